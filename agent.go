@@ -3,7 +3,12 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"simpleagent/claude"
 	"simpleagent/tools"
@@ -18,15 +23,54 @@ type Agent struct {
 
 	// Session state
 	sessionID           string
+	title               string
+	workspace           Workspace
+	parent              string // session this one was forked from
+	forkedAt            int
 	messages            []claude.MessageParam
+	messageModels       map[int]string // message index -> model that produced it
 	todos               *[]tools.Todo
 	planMode            bool
+	planRequested       bool // /plan <task>: the turn must end in ExitPlanMode
 	permissionsMode     string
 	turnsSinceTodoWrite int
 
 	// Config
-	systemPrompt string
-	model        string
+	config           *Config
+	systemPrompt     string
+	watcher          *promptWatcher // memory, rule and skill edits to reload
+	profile          string
+	model            string
+	maxTokens        int
+	turnMaxTokens    int // raised output limit for the current turn (max_tokens retries)
+	maxOutputTokens  int // ceiling for raised limits (0 = unknown)
+	maxContinuations int
+	thinkingBudget   int    // default budget (0 = server default)
+	turnBudget       int    // raised budget for the current turn (/think, keywords)
+	thinkingDisplay  string // show, collapse or hide
+	sampling         claude.Sampling
+	contextWindow    int
+	contextTokens    int // last known request size (estimate or server usage)
+	pricing          *Pricing
+	cost             float64 // USD spent since the agent started, by pricing
+
+	// Persistence: what the transcript already holds, and usage not yet written
+	saved        savedState
+	pendingUsage []claude.Usage
+	lock         *sessionLock // held while this process writes the session
+	readOnly     bool         // open elsewhere: nothing is saved
+}
+
+// savedState mirrors the state last appended to the session transcript
+type savedState struct {
+	started         bool
+	messages        int
+	todos           []tools.Todo
+	planMode        bool
+	permissionsMode string
+	model           string
+	profile         string
+	title           string
 }
 
 // NewAgent creates an agent from session (resume or new)
@@ -41,31 +85,98 @@ func NewAgent(
 	todos *[]tools.Todo,
 ) (*Agent, error) {
 	agent := &Agent{
-		client:          client,
-		mcpClients:      mcpClients,
-		reader:          reader,
-		sessionID:       sessionID,
-		todos:           todos,
-		systemPrompt:    systemPrompt,
-		model:           model,
-		permissionsMode: "prompt",
+		client:           client,
+		mcpClients:       mcpClients,
+		reader:           reader,
+		sessionID:        sessionID,
+		todos:            todos,
+		systemPrompt:     systemPrompt,
+		model:            model,
+		maxTokens:        DefaultMaxTokens,
+		maxContinuations: DefaultMaxContinuations,
+		maxOutputTokens:  knownMaxOutputTokens(model),
+		contextWindow:    DefaultContextWindow,
+		thinkingDisplay:  thinkingShow,
+		permissionsMode:  "prompt",
 	}
-
-	// Resume from existing session
-	if sess != nil {
-		agent.messages = sess.Messages
-		*agent.todos = sess.Todos
-		agent.planMode = sess.PlanMode
-		if sess.PermissionsMode != "" {
-			agent.permissionsMode = sess.PermissionsMode
-		}
+	if sess == nil {
+		agent.workspace = currentWorkspace()
+	} else {
+		agent.restore(sess)
 	}
 
 	return agent, nil
 }
 
+// restore loads a saved session's state, as already written to its transcript
+func (a *Agent) restore(sess *SessionFile) {
+	a.messages = sess.Messages
+	a.messageModels = sess.MessageModels
+	a.profile = sess.Meta.Profile
+	a.title = sess.Meta.Title
+	a.workspace = sess.Meta.Workspace
+	a.parent, a.forkedAt = sess.Meta.Parent, sess.Meta.ForkedAt
+	*a.todos = sess.Todos
+	a.planMode = sess.PlanMode
+	if sess.PermissionsMode != "" {
+		a.permissionsMode = sess.PermissionsMode
+	}
+	a.saved = savedState{
+		started:         true,
+		messages:        len(sess.Messages),
+		todos:           slices.Clone(sess.Todos),
+		planMode:        sess.PlanMode,
+		permissionsMode: a.permissionsMode,
+		model:           sess.Meta.Model,
+		profile:         sess.Meta.Profile,
+		title:           sess.Meta.Title,
+	}
+}
+
+// ApplyProfile points the agent at a profile's client, model and limits
+func (a *Agent) ApplyProfile(p *ResolvedProfile, client *claude.Client) {
+	a.client = client
+	a.profile = p.Name
+	a.model = p.Model
+	a.maxTokens = p.MaxTokens
+	a.maxContinuations = max(p.MaxContinuations, 0)
+	a.maxOutputTokens = p.MaxOutputTokens
+	a.thinkingBudget = p.ThinkingBudget
+	a.contextWindow = p.ContextWindow
+	a.sampling = p.Sampling
+	a.pricing = p.Pricing
+}
+
+// SwitchProfile resolves a named profile from config and switches to it
+// (subagents follow unless subagent_profile pins them)
+func (a *Agent) SwitchProfile(name string) error {
+	p, err := a.config.ResolveProfile(name)
+	if err != nil {
+		return err
+	}
+	client, err := p.NewClient()
+	if err != nil {
+		return err
+	}
+	a.ApplyProfile(p, client)
+	if a.config == nil || a.config.SubagentProfile == "" {
+		tools.SetSubagentConfig(client, p.Model, a.systemPrompt)
+		tools.SetSubagentSampling(p.Sampling)
+		tools.SetSubagentLimits(requestLimits(p.MaxTokens, p.ThinkingBudget))
+	}
+	return nil
+}
+
+// recordModel notes which model produced the message at index
+func (a *Agent) recordModel(index int, model string) {
+	if a.messageModels == nil {
+		a.messageModels = make(map[int]string)
+	}
+	a.messageModels[index] = model
+}
+
 // HandleInput processes user input, returns (shouldInfer bool, error)
-// Returns false for commands that don't need inference (/plan, !, !!)
+// Returns false for commands that don't need inference (/plan, /model, /think, /thinking, /config, /memory, #, !, !!)
 func (a *Agent) HandleInput(input string) (bool, error) {
 	// /plan - toggle plan mode
 	if input == "/plan" {
@@ -74,6 +185,88 @@ func (a *Agent) HandleInput(input string) (bool, error) {
 		return false, nil
 	}
 
+	// /plan <task> - enter plan mode and ask for a plan presented via ExitPlanMode
+	if after, ok := strings.CutPrefix(input, "/plan "); ok {
+		if strings.TrimSpace(after) == "" {
+			return false, fmt.Errorf("usage: /plan [task]")
+		}
+		a.planMode = true
+		a.planRequested = true
+		a.messages = append(a.messages, claude.MessageParam{Role: "user", Content: strings.TrimSpace(after)})
+		return true, nil
+	}
+
+	// /model [profile] - switch profile (no argument: caller lists profiles)
+	if after, ok := strings.CutPrefix(input, "/model"); ok {
+		if name := strings.TrimSpace(after); name != "" {
+			return false, a.SwitchProfile(name)
+		}
+		return false, nil
+	}
+
+	// /thinking [show|collapse|hide] - set or cycle thinking display
+	if after, ok := strings.CutPrefix(input, "/thinking"); ok {
+		mode := strings.TrimSpace(after)
+		if mode == "" {
+			mode = nextThinkingDisplay(a.thinkingDisplay)
+		}
+		if !validThinkingDisplay(mode) {
+			return false, fmt.Errorf("usage: /thinking [show|collapse|hide]")
+		}
+		a.thinkingDisplay = mode
+		return false, nil
+	}
+
+	// /think [level|tokens] - raise thinking budget for the next turn
+	if input == "/think" || strings.HasPrefix(input, "/think ") {
+		budget, err := parseThinkCommand(strings.TrimPrefix(input, "/think"))
+		if err != nil {
+			return false, err
+		}
+		a.turnBudget = budget
+		return false, nil
+	}
+
+	// /fork [n] - continue in a copy of the session, keeping its first n messages
+	if after, ok := strings.CutPrefix(input, "/fork"); ok {
+		upTo := -1
+		if arg := strings.TrimSpace(after); arg != "" {
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 0 {
+				return false, fmt.Errorf("usage: /fork [message count]")
+			}
+			upTo = n
+		}
+		return false, a.Fork(upTo)
+	}
+
+	// /search <words> - caller lists matching sessions
+	if after, ok := strings.CutPrefix(input, "/search"); ok {
+		if strings.TrimSpace(after) == "" {
+			return false, fmt.Errorf("usage: /search <words>")
+		}
+		return false, nil
+	}
+
+	// /memory - pick a memory file to open in $EDITOR, then reload it
+	if strings.TrimSpace(input) == "/memory" {
+		return false, a.editMemory()
+	}
+
+	// # <note> - append a note to a memory file and reload it
+	if after, ok := strings.CutPrefix(input, "#"); ok {
+		note := strings.TrimSpace(after)
+		if note == "" {
+			return false, fmt.Errorf("usage: # <note to remember>")
+		}
+		return false, a.rememberNote(note)
+	}
+
+	// /config [key] - caller shows merged settings and where they came from
+	if strings.HasPrefix(input, "/config") {
+		return false, nil
+	}
+
 	// !! - run bash and add to context
 	if after, ok := strings.CutPrefix(input, "!!"); ok {
 		cmd := after
@@ -93,46 +286,163 @@ func (a *Agent) HandleInput(input string) (bool, error) {
 		return false, nil
 	}
 
-	// Normal input - append to messages
+	// Normal input - keywords like "think hard" raise the budget for this turn
+	if budget := keywordThinkingBudget(input); budget > a.turnBudget {
+		a.turnBudget = budget
+	}
 	a.messages = append(a.messages, claude.MessageParam{Role: "user", Content: input})
 	return true, nil
 }
 
 // Save persists current session state
 func (a *Agent) Save() error {
-	if a.sessionID == "" {
+	if err := a.flush(); err != nil {
+		return err
+	}
+	a.turnsSinceTodoWrite++
+	return nil
+}
+
+// flush appends unsaved state to the transcript
+func (a *Agent) flush() error {
+	if a.sessionID == "" || a.readOnly {
 		return nil
 	}
-	if err := saveSession(a.sessionID, a.messages, *a.todos, a.planMode, a.permissionsMode); err != nil {
+	if err := appendRecords(a.sessionID, a.unsavedRecords(time.Now())); err != nil {
 		return err
 	}
-	a.turnsSinceTodoWrite++
+	a.markSaved()
 	return nil
 }
 
+// Fork saves the session, clones its first upTo messages (all when upTo < 0)
+// into a new session and continues there. The original is left as it was.
+// Forking a read-only session keeps the turns taken since opening it.
+func (a *Agent) Fork(upTo int) error {
+	if a.sessionID == "" {
+		return fmt.Errorf("no session to fork")
+	}
+	if err := a.flush(); err != nil {
+		return err
+	}
+	records, err := readSessionRecords(a.sessionID)
+	if err != nil {
+		return err
+	}
+	// what this agent saved or loaded, then its unsaved turns (read-only)
+	records = append(recordsBefore(records, a.saved.messages), a.unsavedRecords(time.Now())...)
+	fork, err := forkSession(a.sessionID, records, upTo, time.Now())
+	if err != nil {
+		return err
+	}
+	lock, err := lockSession(fork.Meta.ID)
+	if err != nil {
+		return err
+	}
+	path, _ := sessionPath(fork.Meta.ID) // a new id, checked by lockSession
+	records = sessionRecords(fork)
+	if err := writeTranscriptAtomic(path, records); err != nil {
+		lock.Release()
+		return fmt.Errorf("writing fork: %w", err)
+	}
+	updateSearchIndex(fork.Meta.ID, 0, records)
+	a.lock.Release()
+	a.lock, a.readOnly = lock, false
+	a.sessionID = fork.Meta.ID
+	a.restore(fork)
+	return nil
+}
+
+// unsavedRecords returns transcript records for state changed since the last save
+func (a *Agent) unsavedRecords(now time.Time) []Record {
+	var records []Record
+	if !a.saved.started || a.model != a.saved.model || a.profile != a.saved.profile || a.title != a.saved.title {
+		meta := SessionMeta{ID: a.sessionID, CreatedAt: now, UpdatedAt: now, Model: a.model, Profile: a.profile, Title: a.title, Parent: a.parent, ForkedAt: a.forkedAt, Workspace: a.workspace}
+		records = append(records, Record{Type: recordMeta, Time: now, Meta: &meta})
+	}
+	for i := a.saved.messages; i < len(a.messages); i++ {
+		records = append(records, messageRecord(a.messages[i], a.messageModels[i], now))
+	}
+	if !slices.Equal(*a.todos, a.saved.todos) {
+		todos := slices.Clone(*a.todos)
+		records = append(records, Record{Type: recordTodos, Time: now, Todos: &todos})
+	}
+	if a.planMode != a.saved.planMode || a.permissionsMode != a.saved.permissionsMode {
+		plan := a.planMode
+		records = append(records, Record{Type: recordMode, Time: now, PlanMode: &plan, PermissionsMode: a.permissionsMode})
+	}
+	for _, u := range a.pendingUsage {
+		records = append(records, Record{Type: recordUsage, Time: now, Usage: &u})
+	}
+	return records
+}
+
+// markSaved records that the transcript now matches the agent's state
+func (a *Agent) markSaved() {
+	a.saved = savedState{
+		started:         true,
+		messages:        len(a.messages),
+		todos:           slices.Clone(*a.todos),
+		planMode:        a.planMode,
+		permissionsMode: a.permissionsMode,
+		model:           a.model,
+		profile:         a.profile,
+		title:           a.title,
+	}
+	a.pendingUsage = nil
+}
+
+// requestParams builds the next request. A forced tool choice turns thinking
+// off for the request, since the API only allows auto/none with thinking.
+func (a *Agent) requestParams(toolSet []claude.Tool, choice *claude.ToolChoice) claude.MessageCreateParams {
+	maxTokens, budget := requestLimits(max(a.maxTokens, a.turnMaxTokens), max(a.thinkingBudget, a.turnBudget))
+	params := claude.MessageCreateParams{
+		Model:      a.model,
+		MaxTokens:  maxTokens,
+		System:     a.systemPrompt,
+		Messages:   a.messages,
+		Tools:      toolSet,
+		ToolChoice: choice,
+		Sampling:   a.sampling,
+	}
+	if choice == nil || choice.Type == claude.ToolChoiceAuto || choice.Type == claude.ToolChoiceNone {
+		params.Thinking = &claude.ThinkingConfig{Type: "enabled", BudgetTokens: budget}
+		if a.client.SendsThinking() {
+			params.Sampling = a.sampling.WithThinking()
+		}
+	}
+	if a.config != nil && a.config.MetadataUserID != "" {
+		params.Metadata = &claude.Metadata{UserID: a.config.MetadataUserID}
+	}
+	return params
+}
+
 // fetchResponse streams a response from Claude, returns message and collected text
-func (a *Agent) fetchResponse(toolSet []claude.Tool) (*claude.Message, string, error) {
-	stream := a.client.Messages.Stream(claude.MessageCreateParams{
-		Model:     a.model,
-		MaxTokens: 4096,
-		System:    a.systemPrompt,
-		Messages:  a.messages,
-		Tools:     toolSet,
-		Thinking:  &claude.ThinkingConfig{Type: "enabled"},
-	})
+func (a *Agent) fetchResponse(toolSet []claude.Tool, choice *claude.ToolChoice) (*claude.Message, string, error) {
+	params := a.requestParams(toolSet, choice)
+	a.preflight(params)
+	stream := a.client.Messages.Stream(params)
 
 	var textBuffer strings.Builder
+	var thinkingChars int
 	stream.OnText(func(s string) {
 		textBuffer.WriteString(s)
 	})
 	stream.OnThinking(func(s string) {
-		fmt.Print(tools.Thinking(s))
+		thinkingChars += len(s)
+		if a.thinkingDisplay == thinkingShow {
+			fmt.Print(tools.Thinking(s))
+		}
 	})
 
 	msg, err := stream.FinalMessage()
 	if err != nil {
 		return nil, "", err
 	}
+	a.recordUsage(msg.Usage)
+	if a.thinkingDisplay == thinkingCollapse && thinkingChars > 0 {
+		fmt.Print(tools.Dim(fmt.Sprintf("(thinking: %d chars, /thinking show to expand)", thinkingChars)))
+	}
 	return msg, textBuffer.String(), nil
 }
 
@@ -144,11 +454,14 @@ func (a *Agent) executeTools(blocks []claude.ContentBlock) []claude.ToolResultBl
 			continue
 		}
 		result := tools.Execute(block.Name, block.Input)
-		result.Render()
+		result.Render(os.Stdout)
 
 		if block.Name == "TodoWrite" {
 			a.turnsSinceTodoWrite = 0
 		}
+		if block.Name == "ExitPlanMode" {
+			a.planRequested = false
+		}
 		if block.Name == "ExitPlanMode" && strings.Contains(result.String(), `"decision":"Accept"`) {
 			a.planMode = false
 			fmt.Println("\n" + tools.Status("plan mode off") + " " + tools.Dim("full access"))
@@ -163,37 +476,90 @@ func (a *Agent) executeTools(blocks []claude.ContentBlock) []claude.ToolResultBl
 	return results
 }
 
+// printAgentText prints a response's text to w under the agent badge, as markdown
+func printAgentText(w io.Writer, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(w, "%s\n", tools.Agent())
+	if mdRenderer != nil {
+		if rendered, err := mdRenderer.Render(text); err == nil {
+			fmt.Fprint(w, strings.TrimSpace(rendered))
+			return
+		}
+	}
+	fmt.Fprint(w, text)
+}
+
 // RunInferenceTurn executes one agentic loop iteration
 func (a *Agent) RunInferenceTurn() error {
+	defer func() { a.turnBudget, a.turnMaxTokens = 0, 0 }() // raised limits last one turn
+	var choice *claude.ToolChoice
+	continuations := 0
 	for {
 		toolSet := tools.All()
 		if a.planMode {
 			toolSet = tools.ReadOnly()
 		}
 
-		msg, text, err := a.fetchResponse(toolSet)
+		msg, text, err := a.fetchResponse(toolSet, choice)
+		choice = nil
 		if err != nil {
 			fmt.Printf("\n%s\n", tools.Error(err.Error()))
 			return err
 		}
 		fmt.Println("")
 
-		if text != "" {
-			fmt.Printf("%s\n", tools.Agent())
-			if mdRenderer != nil {
-				if rendered, err := mdRenderer.Render(text); err == nil {
-					fmt.Print(strings.TrimSpace(rendered))
-				} else {
-					fmt.Print(text)
+		printAgentText(os.Stdout, text)
+
+		// max_tokens: drop cut-off blocks, then retry or ask for the rest
+		continueText := false
+		if msg.StopReason == "max_tokens" {
+			var dropped int
+			msg.Content, dropped = trimTruncated(msg.Content)
+			switch {
+			case continuations >= a.maxContinuations:
+				fmt.Println("\n" + tools.Warning(fmt.Sprintf("response cut off at max_tokens after %d continuation(s)", continuations)))
+			case hasToolUse(msg.Content):
+				// complete tool calls run as usual; the loop carries on from their results
+				if dropped > 0 {
+					fmt.Println("\n" + tools.Warning(fmt.Sprintf("discarded %d tool call(s) cut off at max_tokens", dropped)))
 				}
-			} else {
-				fmt.Print(text)
+			case dropped > 0 && a.raisedMaxTokens() <= max(a.maxTokens, a.turnMaxTokens):
+				fmt.Println("\n" + tools.Warning(fmt.Sprintf("discarded %d tool call(s) cut off at the output limit of %d tokens", dropped, max(a.maxTokens, a.turnMaxTokens))))
+			case dropped > 0:
+				continuations++
+				a.turnMaxTokens = a.raisedMaxTokens()
+				fmt.Println("\n" + tools.Status("max_tokens") + " " + tools.Dim(fmt.Sprintf("tool call cut off, retrying with max_tokens %d (%d/%d)", a.turnMaxTokens, continuations, a.maxContinuations)))
+				continue
+			default:
+				continuations++
+				continueText = true
+				fmt.Println("\n" + tools.Status("max_tokens") + " " + tools.Dim(fmt.Sprintf("continuing response (%d/%d)", continuations, a.maxContinuations)))
 			}
 		}
 
-		a.messages = append(a.messages, claude.MessageParam{Role: "assistant", Content: msg.Content})
+		if len(msg.Content) > 0 {
+			a.messages = append(a.messages, claude.MessageParam{Role: "assistant", Content: msg.Content})
+			if msg.Model != "" {
+				a.recordModel(len(a.messages)-1, msg.Model)
+			} else {
+				a.recordModel(len(a.messages)-1, a.model)
+			}
+		}
+		if continueText {
+			a.messages = append(a.messages, claude.MessageParam{Role: "user", Content: maxTokensReminder})
+			continue
+		}
 
 		toolResults := a.executeTools(msg.Content)
+		if len(toolResults) == 0 && a.planMode && a.planRequested {
+			// A plan was requested but the turn ended in prose: have it presented for approval
+			a.planRequested = false
+			a.messages = append(a.messages, claude.MessageParam{Role: "user", Content: planRequestReminder})
+			choice = claude.ForceTool("ExitPlanMode")
+			continue
+		}
 		if len(toolResults) == 0 {
 			fmt.Println()
 			return nil
@@ -210,6 +576,7 @@ func (a *Agent) RunInferenceTurn() error {
 			PlanMode:            a.planMode,
 			TurnsSinceTodoWrite: a.turnsSinceTodoWrite,
 			HasPendingTodos:     hasPending,
+			ContextPercent:      a.contextPercent(),
 		}
 		if reminders := GetReminders(state); reminders != "" {
 			last := &toolResults[len(toolResults)-1]
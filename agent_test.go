@@ -2,10 +2,15 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"simpleagent/claude"
+	"simpleagent/claude/claudetest"
 	"simpleagent/tools"
 )
 
@@ -259,3 +264,413 @@ func TestAgent_Save_EmptySessionID(t *testing.T) {
 		t.Fatalf("expected no error, got: %v", err)
 	}
 }
+
+func TestAgent_HandleInput_ThinkCommand(t *testing.T) {
+	// given
+	agent := &Agent{messages: []claude.MessageParam{}}
+
+	// when
+	shouldInfer, err := agent.HandleInput("/think harder")
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if shouldInfer {
+		t.Error("expected false (no inference), got true")
+	}
+	if agent.turnBudget != budgetUltra {
+		t.Errorf("expected turnBudget %d, got %d", budgetUltra, agent.turnBudget)
+	}
+}
+
+func TestAgent_HandleInput_ThinkInvalid(t *testing.T) {
+	// given
+	agent := &Agent{messages: []claude.MessageParam{}}
+
+	// when
+	_, err := agent.HandleInput("/think 10")
+
+	// then
+	if err == nil {
+		t.Error("expected error for budget below 1024")
+	}
+}
+
+func TestAgent_HandleInput_ThinkNeedsWholeWord(t *testing.T) {
+	// given
+	agent := &Agent{messages: []claude.MessageParam{}}
+
+	// when
+	shouldInfer, err := agent.HandleInput("/thinker please review this")
+
+	// then
+	if err != nil || !shouldInfer {
+		t.Errorf("expected a normal message, got %v / %v", shouldInfer, err)
+	}
+	if agent.turnBudget != 0 {
+		t.Errorf("expected no turn budget, got %d", agent.turnBudget)
+	}
+}
+
+func TestAgent_HandleInput_ThinkKeyword(t *testing.T) {
+	// given
+	agent := &Agent{messages: []claude.MessageParam{}}
+
+	// when
+	shouldInfer, _ := agent.HandleInput("Think hard about the parser design")
+
+	// then
+	if !shouldInfer {
+		t.Error("expected true (should infer), got false")
+	}
+	if agent.turnBudget != budgetThinkHard {
+		t.Errorf("expected turnBudget %d, got %d", budgetThinkHard, agent.turnBudget)
+	}
+}
+
+func TestAgent_HandleInput_ThinkingDisplay(t *testing.T) {
+	// given
+	agent := &Agent{thinkingDisplay: thinkingShow}
+
+	// when - cycle, then set explicitly
+	agent.HandleInput("/thinking")
+	cycled := agent.thinkingDisplay
+	agent.HandleInput("/thinking hide")
+
+	// then
+	if cycled != thinkingCollapse {
+		t.Errorf("expected %q after cycle, got %q", thinkingCollapse, cycled)
+	}
+	if agent.thinkingDisplay != thinkingHide {
+		t.Errorf("expected %q, got %q", thinkingHide, agent.thinkingDisplay)
+	}
+}
+
+func TestRequestLimits_RaisesMaxTokens(t *testing.T) {
+	// given/when
+	maxTokens, budget := requestLimits(4096, budgetThinkHard)
+
+	// then
+	if budget != budgetThinkHard || maxTokens <= budget {
+		t.Errorf("expected max_tokens > budget, got max=%d budget=%d", maxTokens, budget)
+	}
+}
+
+func TestRequestLimits_RaisesSmallBudget(t *testing.T) {
+	// given/when
+	maxTokens, budget := requestLimits(8192, 500)
+
+	// then
+	if budget != minThinkingBudget || maxTokens != 8192 {
+		t.Errorf("expected budget raised to %d, got max=%d budget=%d", minThinkingBudget, maxTokens, budget)
+	}
+}
+
+func TestAgent_Preflight_EstimatesContext(t *testing.T) {
+	// given - tiny context window so a short prompt is near the limit
+	agent := &Agent{contextWindow: 1000}
+	params := claude.MessageCreateParams{
+		MaxTokens: 100,
+		Messages:  []claude.MessageParam{{Role: "user", Content: strings.Repeat("word ", 200)}},
+	}
+
+	// when
+	agent.preflight(params)
+
+	// then
+	if agent.contextTokens == 0 {
+		t.Fatal("expected context tokens estimated")
+	}
+	if agent.contextPercent() == 0 {
+		t.Error("expected non-zero context percent")
+	}
+}
+
+func TestGetReminders_ContextNearlyFull(t *testing.T) {
+	// given
+	state := &AgentState{ContextPercent: 85}
+
+	// when
+	r := GetReminders(state)
+
+	// then
+	if !strings.Contains(r, "85% full") {
+		t.Errorf("expected context reminder, got %q", r)
+	}
+}
+
+var updateGolden = flag.Bool("update", false, "rewrite testdata golden files")
+
+// assertGolden compares got against testdata/<name>, rewriting it with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.MkdirAll("testdata", 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (run with -update to create): %v", err)
+	}
+	if string(want) != string(got) {
+		t.Errorf("%s mismatch (run with -update to accept)\n--- want\n%s\n--- got\n%s", path, want, got)
+	}
+}
+
+func TestAgent_RunInferenceTurn_ToolRoundTrip(t *testing.T) {
+	// given - a scripted session: think, write todos, then answer
+	srv := claudetest.NewServer(t,
+		claudetest.Reply(
+			claudetest.Thinking("The user wants a plan, I'll track it with todos."),
+			claudetest.Text("Creating a todo list."),
+			claudetest.ToolUse("toolu_1", "TodoWrite", map[string]any{
+				"todos": []map[string]string{
+					{"content": "Write tests", "active_form": "Writing tests", "status": "in_progress"},
+					{"content": "Ship it", "active_form": "Shipping it", "status": "pending"},
+				},
+			}),
+		),
+		claudetest.Reply(claudetest.Text("Done planning: 2 todos.")),
+	)
+	var todos []tools.Todo
+	tools.Init(tools.Config{Todos: &todos})
+	agent, _ := NewAgent("golden", nil, srv.Client(), bufio.NewReader(strings.NewReader("")), "system", "test-model", nil, &todos)
+	agent.thinkingDisplay = thinkingHide
+	agent.HandleInput("plan the release")
+
+	// when
+	err := agent.RunInferenceTurn()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if srv.Remaining() != 0 {
+		t.Errorf("expected script consumed, %d turns left", srv.Remaining())
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 || !reqs[0].Stream || len(reqs[1].Messages) != 3 {
+		t.Fatalf("unexpected requests: %d", len(reqs))
+	}
+	if len(todos) != 2 || todos[0].Status != "in_progress" {
+		t.Errorf("expected todos written by tool, got %+v", todos)
+	}
+	transcript, _ := json.MarshalIndent(agent.messages, "", "  ")
+	assertGolden(t, "tool_round_trip.golden.json", append(transcript, '\n'))
+}
+
+func TestAgent_RequestParams_SamplingWithThinking(t *testing.T) {
+	// given
+	temp, topP := 0.2, 0.9
+	agent, _ := NewAgent("", nil, &claude.Client{}, nil, "", "m", nil, &[]tools.Todo{})
+	agent.sampling = claude.Sampling{Temperature: &temp, TopP: &topP, StopSequences: []string{"END"}}
+	agent.config = &Config{MetadataUserID: "user-1"}
+
+	// when
+	normal := agent.requestParams(nil, nil)
+	forced := agent.requestParams(nil, claude.ForceTool("ExitPlanMode"))
+
+	// then - thinking requests drop incompatible sampling, forced tools drop thinking
+	if normal.Thinking == nil || normal.Temperature != nil || normal.TopP != nil || len(normal.StopSequences) != 1 {
+		t.Errorf("unexpected thinking request: %+v", normal)
+	}
+	if forced.Thinking != nil || forced.Temperature == nil || *forced.Temperature != temp {
+		t.Errorf("unexpected forced request: %+v", forced)
+	}
+	if normal.Metadata == nil || normal.Metadata.UserID != "user-1" {
+		t.Errorf("expected metadata user id, got %+v", normal.Metadata)
+	}
+
+	// when - a provider that ignores thinking
+	agent.client = claude.NewClient(claude.WithProvider(claude.OpenAIProvider{}))
+	openai := agent.requestParams(nil, nil)
+
+	// then - sampling is kept
+	if openai.Temperature == nil || *openai.Temperature != temp || openai.TopP == nil {
+		t.Errorf("expected sampling sent to openai provider, got %+v", openai.Sampling)
+	}
+}
+
+func TestAgent_HandleInput_EmptyPlanTask(t *testing.T) {
+	// given
+	agent := &Agent{}
+
+	// when
+	shouldInfer, err := agent.HandleInput("/plan   ")
+
+	// then
+	if err == nil || shouldInfer || agent.planMode || len(agent.messages) != 0 {
+		t.Errorf("expected a usage error and no message, got %v %v %+v", shouldInfer, err, agent.messages)
+	}
+}
+
+func TestAgent_PlanRequest_ForcesExitPlanMode(t *testing.T) {
+	// given - the model answers in prose, then is made to present the plan
+	srv := claudetest.NewServer(t,
+		claudetest.Reply(claudetest.Text("I would refactor the parser.")),
+		claudetest.Reply(claudetest.ToolUse("toolu_1", "ExitPlanMode", map[string]string{"plan": "1. Refactor the parser"})),
+		claudetest.Reply(claudetest.Text("Continuing to explore.")),
+	)
+	stdin, w, _ := os.Pipe()
+	w.WriteString("3\n\n")
+	w.Close()
+	oldStdin := os.Stdin
+	os.Stdin = stdin
+	defer func() { os.Stdin = oldStdin }()
+
+	var todos []tools.Todo
+	tools.Init(tools.Config{Todos: &todos})
+	agent, _ := NewAgent("", nil, srv.Client(), nil, "", "m", nil, &todos)
+	agent.thinkingDisplay = thinkingHide
+	shouldInfer, _ := agent.HandleInput("/plan refactor the parser")
+
+	// when
+	err := agent.RunInferenceTurn()
+
+	// then
+	if err != nil || !shouldInfer || !agent.planMode {
+		t.Fatalf("unexpected state: err=%v infer=%v plan=%v", err, shouldInfer, agent.planMode)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(reqs))
+	}
+	if reqs[0].ToolChoice != nil || reqs[2].ToolChoice != nil {
+		t.Error("expected tool choice only on the follow-up request")
+	}
+	if tc := reqs[1].ToolChoice; tc == nil || tc.Type != claude.ToolChoiceTool || tc.Name != "ExitPlanMode" || reqs[1].Thinking != nil {
+		t.Errorf("expected forced ExitPlanMode without thinking, got %+v", reqs[1])
+	}
+	if agent.planRequested {
+		t.Error("expected plan request cleared")
+	}
+}
+
+func TestTrimTruncated_DropsCutOffBlocks(t *testing.T) {
+	// given
+	blocks := []claude.ContentBlock{
+		{Type: "text", Text: "Reading both files."},
+		{Type: "tool_use", ID: "a", Name: "ReadFile", Input: json.RawMessage(`{"path":"go.mod"}`)},
+		{Type: "tool_use", ID: "b", Name: "ReadFile", Input: json.RawMessage(`{"path":"ma`)},
+	}
+
+	// when
+	kept, dropped := trimTruncated(blocks)
+
+	// then
+	if dropped != 1 || len(kept) != 2 || kept[1].ID != "a" {
+		t.Errorf("expected only the cut-off call dropped, got %d dropped: %+v", dropped, kept)
+	}
+}
+
+func TestAgent_RunInferenceTurn_ContinuesTruncatedText(t *testing.T) {
+	// given
+	srv := claudetest.NewServer(t,
+		claudetest.Turn{Blocks: []claude.ContentBlock{claudetest.Text("The first half")}, StopReason: "max_tokens"},
+		claudetest.Reply(claudetest.Text(" and the rest.")),
+	)
+	agent, _ := NewAgent("", nil, srv.Client(), nil, "", "m", nil, &[]tools.Todo{})
+	agent.HandleInput("explain")
+
+	// when
+	err := agent.RunInferenceTurn()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(agent.messages) != 4 || agent.messages[2].Content != maxTokensReminder {
+		t.Fatalf("expected partial answer, continuation request and rest, got %+v", agent.messages)
+	}
+}
+
+func TestAgent_RunInferenceTurn_RetriesCutOffToolCall(t *testing.T) {
+	// given - the only tool call is cut off mid-input
+	srv := claudetest.NewServer(t,
+		claudetest.Turn{Blocks: []claude.ContentBlock{
+			claudetest.Text("Reading."),
+			{Type: "tool_use", ID: "toolu_1", Name: "ReadFile", Input: json.RawMessage(`{"path":"go.`)},
+		}, StopReason: "max_tokens"},
+		claudetest.Reply(claudetest.Text("Never mind.")),
+	)
+	agent, _ := NewAgent("", nil, srv.Client(), nil, "", "m", nil, &[]tools.Todo{})
+	agent.HandleInput("read go.mod")
+
+	// when
+	err := agent.RunInferenceTurn()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 || reqs[1].MaxTokens != 2*reqs[0].MaxTokens {
+		t.Fatalf("expected retry with doubled max_tokens, got %d requests", len(reqs))
+	}
+	if len(agent.messages) != 2 || agent.turnMaxTokens != 0 {
+		t.Errorf("expected truncated response discarded and limit reset, got %d messages", len(agent.messages))
+	}
+}
+
+func TestAgent_RunInferenceTurn_RetriesUpToOutputLimit(t *testing.T) {
+	// given - a model whose output limit is below twice max_tokens
+	cutOff := claudetest.Turn{Blocks: []claude.ContentBlock{
+		{Type: "tool_use", ID: "toolu_1", Name: "ReadFile", Input: json.RawMessage(`{"path":"go.`)},
+	}, StopReason: "max_tokens"}
+	srv := claudetest.NewServer(t, cutOff, cutOff)
+	agent, _ := NewAgent("", nil, srv.Client(), nil, "", "m", nil, &[]tools.Todo{})
+	agent.maxOutputTokens = agent.maxTokens + 1000
+	agent.HandleInput("read go.mod")
+
+	// when
+	err := agent.RunInferenceTurn()
+
+	// then - one retry at the limit, then no more
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 || reqs[1].MaxTokens != agent.maxOutputTokens {
+		t.Fatalf("expected one retry at max_tokens %d, got %d requests", agent.maxOutputTokens, len(reqs))
+	}
+}
+
+func TestModelConfigFor_MaxOutputTokens(t *testing.T) {
+	// given
+	config := &Config{Models: map[string]ModelConfig{"claude-sonnet-4-5": {MaxOutputTokens: 20000}}}
+
+	// when / then - configured, known and unknown models
+	if got := config.ModelConfigFor("claude-sonnet-4-5").MaxOutputTokens; got != 20000 {
+		t.Errorf("expected configured 20000, got %d", got)
+	}
+	if got := config.ModelConfigFor("claude-opus-4-1").MaxOutputTokens; got != 32000 {
+		t.Errorf("expected known 32000, got %d", got)
+	}
+	if got := config.ModelConfigFor("qwen3-coder").MaxOutputTokens; got != 0 {
+		t.Errorf("expected no limit for unknown models, got %d", got)
+	}
+}
+
+func TestAgent_RunInferenceTurn_StopsAfterMaxContinuations(t *testing.T) {
+	// given - continuations disabled
+	srv := claudetest.NewServer(t,
+		claudetest.Turn{Blocks: []claude.ContentBlock{claudetest.Text("cut")}, StopReason: "max_tokens"},
+	)
+	agent, _ := NewAgent("", nil, srv.Client(), nil, "", "m", nil, &[]tools.Todo{})
+	agent.maxContinuations = 0
+	agent.HandleInput("explain")
+
+	// when
+	err := agent.RunInferenceTurn()
+
+	// then
+	if err != nil || len(srv.Requests()) != 1 || len(agent.messages) != 2 {
+		t.Errorf("expected the partial answer kept without retry, got err=%v requests=%d", err, len(srv.Requests()))
+	}
+}
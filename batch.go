@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// batchManifestName is written to the output dir so an interrupted run can be
+// collected later with -id
+const batchManifestName = "batch.json"
+
+// batchJob fans one prompt template out over a set of files
+type batchJob struct {
+	Prompt    *template.Template // executed with batchInput per file
+	Glob      string
+	OutDir    string
+	Model     string
+	MaxTokens int
+	Poll      time.Duration
+}
+
+// batchInput is the template data for one file
+type batchInput struct {
+	Path    string
+	Content string
+}
+
+// batchManifest maps request custom_ids back to input files
+type batchManifest struct {
+	ID    string            `json:"id"`
+	Files map[string]string `json:"files"` // custom_id -> path
+}
+
+// RunBatch implements `agent batch`: submit a prompt per matching file as a
+// Message Batch, wait for it to end, and write each answer under -out
+func RunBatch(args []string) error {
+	fs := flag.NewFlagSet("batch", flag.ContinueOnError)
+	prompt := fs.String("prompt", "", "Prompt template ({{.Path}} and {{.Content}} expand per file)")
+	promptFile := fs.String("prompt-file", "", "Read the prompt template from a file")
+	glob := fs.String("glob", "", "Files to process (doublestar pattern, e.g. 'pkg/**/*.go')")
+	outDir := fs.String("out", "batch-out", "Directory for results")
+	profileFlag := fs.String("profile", "", "Model profile from config")
+	poll := fs.Duration("poll", 30*time.Second, "Status polling interval")
+	idFlag := fs.String("id", "", "Collect an existing batch recorded in -out instead of submitting")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	config, _, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	profile, err := config.ResolveProfile(*profileFlag)
+	if err != nil {
+		return err
+	}
+	client, err := profile.NewClient()
+	if err != nil {
+		return err
+	}
+
+	if *idFlag != "" {
+		manifest, err := readBatchManifest(*outDir)
+		if err != nil {
+			return err
+		}
+		if manifest.ID != *idFlag {
+			return fmt.Errorf("%s records batch %s, not %s", filepath.Join(*outDir, batchManifestName), manifest.ID, *idFlag)
+		}
+		return collectBatch(client, *outDir, manifest, *poll)
+	}
+
+	text := *prompt
+	if *promptFile != "" {
+		data, err := os.ReadFile(*promptFile)
+		if err != nil {
+			return err
+		}
+		text = string(data)
+	}
+	if text == "" || *glob == "" {
+		return errors.New("usage: agent batch -prompt <template> -glob <pattern> [-out dir] [-profile name]")
+	}
+	tmpl, err := template.New("prompt").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return fmt.Errorf("parsing prompt template: %w", err)
+	}
+
+	return runBatchJob(client, batchJob{
+		Prompt:    tmpl,
+		Glob:      *glob,
+		OutDir:    *outDir,
+		Model:     profile.Model,
+		MaxTokens: profile.MaxTokens,
+		Poll:      *poll,
+	})
+}
+
+// runBatchJob submits one request per matching file, then collects results
+func runBatchJob(client *claude.Client, job batchJob) error {
+	paths, err := doublestar.FilepathGlob(job.Glob, doublestar.WithFilesOnly())
+	if err != nil {
+		return fmt.Errorf("glob %q: %w", job.Glob, err)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no files match %q", job.Glob)
+	}
+
+	manifest := &batchManifest{Files: make(map[string]string)}
+	var requests []claude.BatchRequest
+	for i, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var prompt strings.Builder
+		if err := job.Prompt.Execute(&prompt, batchInput{Path: path, Content: string(content)}); err != nil {
+			return fmt.Errorf("prompt for %s: %w", path, err)
+		}
+		id := fmt.Sprintf("file-%04d", i)
+		manifest.Files[id] = path
+		requests = append(requests, claude.BatchRequest{
+			CustomID: id,
+			Params: claude.MessageCreateParams{
+				Model:     job.Model,
+				MaxTokens: job.MaxTokens,
+				Messages:  []claude.MessageParam{{Role: "user", Content: prompt.String()}},
+			},
+		})
+	}
+
+	batch, err := client.Batches.Create(requests)
+	if err != nil {
+		return fmt.Errorf("creating batch: %w", err)
+	}
+	manifest.ID = batch.ID
+	fmt.Println(tools.Status("batch") + " " + tools.Dim(fmt.Sprintf("%s · %d request(s)", batch.ID, len(requests))))
+
+	if err := writeBatchManifest(job.OutDir, manifest); err != nil {
+		return err
+	}
+	return collectBatch(client, job.OutDir, manifest, job.Poll)
+}
+
+// collectBatch waits for the batch to end and writes <out>/<path>.md per
+// succeeded request
+func collectBatch(client *claude.Client, outDir string, manifest *batchManifest, poll time.Duration) error {
+	batch, err := client.Batches.Wait(context.Background(), manifest.ID, poll, func(b *claude.MessageBatch) {
+		if b.ProcessingStatus != claude.BatchEnded {
+			fmt.Println(tools.Dim(fmt.Sprintf("%s: %d processing", b.ProcessingStatus, b.RequestCounts.Processing)))
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("waiting for batch: %w", err)
+	}
+
+	err = client.Batches.Results(batch.ID, func(r claude.BatchResult) error {
+		path, ok := manifest.Files[r.CustomID]
+		if !ok {
+			path = r.CustomID
+		}
+		if err := r.Err(); err != nil {
+			fmt.Println(tools.Error(fmt.Sprintf("%s: %v", path, err)))
+			return nil
+		}
+		var text []string
+		for _, b := range r.Result.Message.Content {
+			if b.Type == "text" {
+				text = append(text, b.Text)
+			}
+		}
+		dest := batchOutputPath(outDir, path)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		return os.WriteFile(dest, []byte(strings.Join(text, "\n")+"\n"), 0644)
+	})
+	if err != nil {
+		return fmt.Errorf("reading results: %w", err)
+	}
+
+	c := batch.RequestCounts
+	fmt.Println(tools.Status("batch done") + " " + tools.Dim(fmt.Sprintf("%d succeeded, %d errored, %d canceled, %d expired → %s",
+		c.Succeeded, c.Errored, c.Canceled, c.Expired, outDir)))
+	return nil
+}
+
+// batchOutputPath mirrors an input path under outDir, never escaping it
+func batchOutputPath(outDir, path string) string {
+	rel := strings.TrimLeft(filepath.ToSlash(filepath.Clean("/"+path)), "/")
+	return filepath.Join(outDir, filepath.FromSlash(rel)) + ".md"
+}
+
+func writeBatchManifest(outDir string, m *batchManifest) error {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outDir, batchManifestName), data, 0644)
+}
+
+func readBatchManifest(outDir string) (*batchManifest, error) {
+	data, err := os.ReadFile(filepath.Join(outDir, batchManifestName))
+	if err != nil {
+		return nil, fmt.Errorf("reading batch manifest: %w", err)
+	}
+	var m batchManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing batch manifest: %w", err)
+	}
+	return &m, nil
+}
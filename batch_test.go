@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+
+	"simpleagent/claude/claudetest"
+)
+
+func TestRunBatchJob_WritesResultPerFile(t *testing.T) {
+	// given - two files and a scripted answer for each
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644)
+	os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "sub", "b.go"), []byte("package b"), 0644)
+	srv := claudetest.NewServer(t,
+		claudetest.Reply(claudetest.Text("doc for a")),
+		claudetest.Reply(claudetest.Text("doc for b")),
+	)
+	outDir := filepath.Join(dir, "out")
+	tmpl := template.Must(template.New("prompt").Parse("Document {{.Path}}:\n{{.Content}}"))
+
+	// when
+	err := runBatchJob(srv.Client(), batchJob{
+		Prompt:    tmpl,
+		Glob:      filepath.Join(dir, "**", "*.go"),
+		OutDir:    outDir,
+		Model:     "m",
+		MaxTokens: 100,
+	})
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	reqs := srv.Requests()
+	if len(reqs) != 2 || !strings.Contains(reqs[0].Messages[0].Content.(string), "package a") {
+		t.Fatalf("expected templated prompts, got %+v", reqs)
+	}
+	got, err := os.ReadFile(batchOutputPath(outDir, filepath.Join(dir, "sub", "b.go")))
+	if err != nil || strings.TrimSpace(string(got)) != "doc for b" {
+		t.Errorf("expected result for b.go, got %q (%v)", got, err)
+	}
+	manifest, err := readBatchManifest(outDir)
+	if err != nil || manifest.ID == "" || len(manifest.Files) != 2 {
+		t.Errorf("expected manifest with 2 files, got %+v (%v)", manifest, err)
+	}
+}
+
+func TestBatchOutputPath_StaysInOutDir(t *testing.T) {
+	// given
+	out := "/tmp/out"
+
+	// when
+	got := batchOutputPath(out, "../../etc/passwd")
+
+	// then
+	if got != "/tmp/out/etc/passwd.md" {
+		t.Errorf("expected path under out dir, got %s", got)
+	}
+}
@@ -0,0 +1,241 @@
+package claude
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Batch processing statuses
+const (
+	BatchInProgress = "in_progress"
+	BatchCanceling  = "canceling"
+	BatchEnded      = "ended"
+)
+
+// BatchRequest is one message request in a batch. CustomID must match
+// ^[a-zA-Z0-9_-]{1,64}$ and be unique within the batch.
+type BatchRequest struct {
+	CustomID string              `json:"custom_id"`
+	Params   MessageCreateParams `json:"params"`
+}
+
+// MessageBatch is the server-side state of a batch
+type MessageBatch struct {
+	ID                string        `json:"id"`
+	Type              string        `json:"type"`
+	ProcessingStatus  string        `json:"processing_status"`
+	RequestCounts     RequestCounts `json:"request_counts"`
+	CreatedAt         time.Time     `json:"created_at"`
+	ExpiresAt         time.Time     `json:"expires_at"`
+	EndedAt           *time.Time    `json:"ended_at"`
+	CancelInitiatedAt *time.Time    `json:"cancel_initiated_at"`
+	ResultsURL        string        `json:"results_url"`
+}
+
+// RequestCounts tallies batch requests by outcome
+type RequestCounts struct {
+	Processing int `json:"processing"`
+	Succeeded  int `json:"succeeded"`
+	Errored    int `json:"errored"`
+	Canceled   int `json:"canceled"`
+	Expired    int `json:"expired"`
+}
+
+// BatchResult is one line of a batch results file
+type BatchResult struct {
+	CustomID string `json:"custom_id"`
+	Result   struct {
+		Type    string      `json:"type"` // succeeded, errored, canceled, expired
+		Message *Message    `json:"message,omitempty"`
+		Error   *BatchError `json:"error,omitempty"`
+	} `json:"result"`
+}
+
+// BatchError wraps the API error of an errored batch request
+type BatchError struct {
+	Type  string      `json:"type"`
+	Error StreamError `json:"error"`
+}
+
+// Err returns the failure of a non-succeeded result
+func (r *BatchResult) Err() error {
+	switch r.Result.Type {
+	case "succeeded":
+		return nil
+	case "errored":
+		if r.Result.Error != nil {
+			return &r.Result.Error.Error
+		}
+	}
+	return fmt.Errorf("request %s %s", r.CustomID, r.Result.Type)
+}
+
+// BatchListParams pages through batches (newest first)
+type BatchListParams struct {
+	Limit    int
+	AfterID  string
+	BeforeID string
+}
+
+// BatchPage is one page of List results
+type BatchPage struct {
+	Data    []MessageBatch `json:"data"`
+	HasMore bool           `json:"has_more"`
+	FirstID string         `json:"first_id"`
+	LastID  string         `json:"last_id"`
+}
+
+// BatchesService handles the Message Batches API (Anthropic provider only)
+type BatchesService struct {
+	client *Client
+}
+
+// Create submits requests as a batch
+func (s *BatchesService) Create(requests []BatchRequest) (*MessageBatch, error) {
+	for i := range requests {
+		requests[i].Params.Stream = false
+	}
+	var batch MessageBatch
+	err := s.call("POST", "/v1/messages/batches", struct {
+		Requests []BatchRequest `json:"requests"`
+	}{requests}, &batch)
+	return &batch, err
+}
+
+// Retrieve fetches the current state of a batch
+func (s *BatchesService) Retrieve(id string) (*MessageBatch, error) {
+	var batch MessageBatch
+	err := s.call("GET", "/v1/messages/batches/"+url.PathEscape(id), nil, &batch)
+	return &batch, err
+}
+
+// List returns one page of batches
+func (s *BatchesService) List(params BatchListParams) (*BatchPage, error) {
+	q := url.Values{}
+	if params.Limit > 0 {
+		q.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.AfterID != "" {
+		q.Set("after_id", params.AfterID)
+	}
+	if params.BeforeID != "" {
+		q.Set("before_id", params.BeforeID)
+	}
+	path := "/v1/messages/batches"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	var page BatchPage
+	err := s.call("GET", path, nil, &page)
+	return &page, err
+}
+
+// Cancel asks the server to stop processing a batch
+func (s *BatchesService) Cancel(id string) (*MessageBatch, error) {
+	var batch MessageBatch
+	err := s.call("POST", "/v1/messages/batches/"+url.PathEscape(id)+"/cancel", nil, &batch)
+	return &batch, err
+}
+
+// Results streams the JSONL results of an ended batch, calling fn per line
+func (s *BatchesService) Results(id string, fn func(BatchResult) error) error {
+	resp, err := s.send("GET", "/v1/messages/batches/"+url.PathEscape(id)+"/results", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	br := bufio.NewReader(resp.Body)
+	for {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			var result BatchResult
+			if jerr := json.Unmarshal(line, &result); jerr != nil {
+				return fmt.Errorf("parsing batch result: %w", jerr)
+			}
+			if ferr := fn(result); ferr != nil {
+				return ferr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Wait polls until the batch ends, calling progress (if set) after each poll
+func (s *BatchesService) Wait(ctx context.Context, id string, interval time.Duration, progress func(*MessageBatch)) (*MessageBatch, error) {
+	for {
+		batch, err := s.Retrieve(id)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(batch)
+		}
+		if batch.ProcessingStatus == BatchEnded {
+			return batch, nil
+		}
+		select {
+		case <-ctx.Done():
+			return batch, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// call sends body as JSON and decodes the JSON response into out
+func (s *BatchesService) call(method, path string, body, out any) error {
+	resp, err := s.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send issues an Anthropic API request, returning an APIError on non-200
+func (s *BatchesService) send(method, path string, body any) (*http.Response, error) {
+	c := s.client
+	if _, ok := c.provider().(AnthropicProvider); !ok {
+		return nil, fmt.Errorf("message batches are not supported by the %s provider", c.provider().Name())
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), method, joinURL(c.BaseURL, path), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{Status: resp.StatusCode, Message: string(respBody)}
+	}
+	return resp, nil
+}
@@ -0,0 +1,85 @@
+package claude_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+	"simpleagent/claude/claudetest"
+)
+
+func TestBatches_CreateWaitResults(t *testing.T) {
+	// given
+	srv := claudetest.NewServer(t,
+		claudetest.Reply(claudetest.Text("first")),
+		claudetest.Reply(claudetest.Text("second")),
+	)
+	client := srv.Client()
+	params := claude.MessageCreateParams{Model: "m", MaxTokens: 10, Messages: []claude.MessageParam{{Role: "user", Content: "hi"}}}
+
+	// when
+	batch, err := client.Batches.Create([]claude.BatchRequest{{CustomID: "a", Params: params}, {CustomID: "b", Params: params}})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	ended, err := client.Batches.Wait(context.Background(), batch.ID, time.Millisecond, nil)
+	if err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	texts := map[string]string{}
+	err = client.Batches.Results(batch.ID, func(r claude.BatchResult) error {
+		if r.Err() != nil {
+			t.Errorf("unexpected result error: %v", r.Err())
+			return nil
+		}
+		texts[r.CustomID] = r.Result.Message.Content[0].Text
+		return nil
+	})
+
+	// then
+	if err != nil {
+		t.Fatalf("results: %v", err)
+	}
+	if batch.ProcessingStatus != claude.BatchInProgress || ended.ProcessingStatus != claude.BatchEnded || ended.RequestCounts.Succeeded != 2 {
+		t.Errorf("unexpected batch states: %+v -> %+v", batch, ended)
+	}
+	if texts["a"] != "first" || texts["b"] != "second" {
+		t.Errorf("unexpected results: %v", texts)
+	}
+}
+
+func TestBatches_CancelAndList(t *testing.T) {
+	// given
+	srv := claudetest.NewServer(t, claudetest.Reply(claudetest.Text("never read")))
+	client := srv.Client()
+	batch, _ := client.Batches.Create([]claude.BatchRequest{{CustomID: "a", Params: claude.MessageCreateParams{Model: "m"}}})
+
+	// when
+	canceled, err := client.Batches.Cancel(batch.ID)
+	page, listErr := client.Batches.List(claude.BatchListParams{Limit: 10})
+
+	// then
+	if err != nil || listErr != nil {
+		t.Fatalf("unexpected errors: %v, %v", err, listErr)
+	}
+	if canceled.ProcessingStatus != claude.BatchCanceling || canceled.CancelInitiatedAt == nil {
+		t.Errorf("expected canceling batch, got %+v", canceled)
+	}
+	if len(page.Data) != 1 || page.Data[0].ID != batch.ID {
+		t.Errorf("expected batch in list, got %+v", page)
+	}
+}
+
+func TestBatches_UnsupportedProvider(t *testing.T) {
+	// given
+	client := claude.NewClient(claude.WithProvider(claude.OpenAIProvider{}))
+
+	// when
+	_, err := client.Batches.List(claude.BatchListParams{})
+
+	// then
+	if err == nil {
+		t.Fatal("expected error for non-Anthropic provider")
+	}
+}
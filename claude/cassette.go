@@ -0,0 +1,214 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Cassette is a recorded sequence of HTTP interactions (SSE bodies kept verbatim)
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one recorded request/response pair
+type Interaction struct {
+	Request struct {
+		Method string `json:"method"`
+		Path   string `json:"path"`
+		Body   string `json:"body"`
+	} `json:"request"`
+	Response struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers,omitempty"`
+		Body    string            `json:"body"`
+	} `json:"response"`
+}
+
+// headers worth keeping in cassettes (never auth headers)
+var recordedHeaders = []string{"Content-Type", "Request-Id"}
+
+// Recorder is a RoundTripper that forwards to Next and appends every
+// interaction to a cassette file
+type Recorder struct {
+	Path string
+	Next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records through next (nil = http.DefaultTransport) into path
+func NewRecorder(path string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{Path: path, Next: next}
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.Next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	var in Interaction
+	in.Request.Method = req.Method
+	in.Request.Path = req.URL.Path
+	in.Request.Body = string(reqBody)
+	in.Response.Status = resp.StatusCode
+	in.Response.Body = string(respBody)
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			if in.Response.Headers == nil {
+				in.Response.Headers = make(map[string]string)
+			}
+			in.Response.Headers[h] = v
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, in)
+	if err := writeCassette(r.Path, &r.cassette); err != nil {
+		return nil, fmt.Errorf("recording cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// Replayer is a RoundTripper that serves interactions from a cassette in order
+type Replayer struct {
+	// CheckBodies also requires each request body to match the recorded one
+	// (as JSON, ignoring whitespace), catching prompts that drifted
+	CheckBodies bool
+
+	mu       sync.Mutex
+	cassette Cassette
+	next     int
+}
+
+// NewReplayer loads a cassette for replay
+func NewReplayer(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c Cassette
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("parsing cassette %s: %w", path, err)
+	}
+	return &Replayer{cassette: c}, nil
+}
+
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		reqBody, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.next >= len(r.cassette.Interactions) {
+		return nil, fmt.Errorf("cassette exhausted: no interaction for %s %s", req.Method, req.URL.Path)
+	}
+	in := r.cassette.Interactions[r.next]
+	if in.Request.Method != req.Method || in.Request.Path != req.URL.Path {
+		return nil, fmt.Errorf("cassette mismatch at interaction %d: want %s %s, got %s %s",
+			r.next, in.Request.Method, in.Request.Path, req.Method, req.URL.Path)
+	}
+	if r.CheckBodies && !sameBody([]byte(in.Request.Body), reqBody) {
+		return nil, fmt.Errorf("cassette mismatch at interaction %d: request body differs from the recording\nwant %s\ngot  %s",
+			r.next, in.Request.Body, reqBody)
+	}
+	r.next++
+
+	header := make(http.Header)
+	for k, v := range in.Response.Headers {
+		header.Set(k, v)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.Status, http.StatusText(in.Response.Status)),
+		StatusCode:    in.Response.Status,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+	}, nil
+}
+
+// sameBody compares request bodies as compacted JSON, falling back to bytes
+func sameBody(want, got []byte) bool {
+	var w, g bytes.Buffer
+	if json.Compact(&w, want) != nil || json.Compact(&g, got) != nil {
+		return bytes.Equal(want, got)
+	}
+	return bytes.Equal(w.Bytes(), g.Bytes())
+}
+
+// Remaining returns how many recorded interactions have not been replayed
+func (r *Replayer) Remaining() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.cassette.Interactions) - r.next
+}
+
+func writeCassette(path string, c *Cassette) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// WithCassette replays path if it exists, otherwise records live traffic to
+// it. A cassette that exists but cannot be loaded fails every request rather
+// than being recorded over.
+func WithCassette(path string) ClientOption {
+	return func(c *Client) {
+		replayer, err := NewReplayer(path)
+		switch {
+		case err == nil:
+			c.Transport = replayer
+		case os.IsNotExist(err):
+			c.Transport = NewRecorder(path, c.Transport)
+		default:
+			c.Transport = failingTransport{fmt.Errorf("loading cassette: %w", err)}
+		}
+	}
+}
+
+// failingTransport fails every request with err
+type failingTransport struct{ err error }
+
+func (t failingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, t.err
+}
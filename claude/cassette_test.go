@@ -0,0 +1,134 @@
+package claude_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simpleagent/claude"
+	"simpleagent/claude/claudetest"
+)
+
+func TestCassette_RecordThenReplay(t *testing.T) {
+	// given - a recording made against the fake server
+	path := filepath.Join(t.TempDir(), "session.json")
+	srv := claudetest.NewServer(t, claudetest.Reply(
+		claudetest.Text("hello"),
+		claudetest.ToolUse("toolu_1", "Read", map[string]string{"path": "go.mod"}),
+	))
+	params := claude.MessageCreateParams{Model: "m", MaxTokens: 10, Messages: []claude.MessageParam{{Role: "user", Content: "hi"}}}
+	recorded, err := srv.Client(claude.WithCassette(path)).Messages.Stream(params).FinalMessage()
+	if err != nil {
+		t.Fatalf("recording: %v", err)
+	}
+	srv.Close()
+
+	// when - replaying with the server gone
+	replayer, err := claude.NewReplayer(path)
+	if err != nil {
+		t.Fatalf("loading cassette: %v", err)
+	}
+	client := claude.NewClient(claude.WithBaseURL(srv.URL), claude.WithHTTPTransport(replayer))
+	replayed, err := client.Messages.Stream(params).FinalMessage()
+
+	// then
+	if err != nil {
+		t.Fatalf("replaying: %v", err)
+	}
+	if replayed.ID != recorded.ID || len(replayed.Content) != 2 || replayed.Content[0].Text != "hello" ||
+		string(replayed.Content[1].Input) != `{"path":"go.mod"}` || replayed.StopReason != "tool_use" {
+		t.Errorf("replayed message differs: %+v", replayed)
+	}
+	if replayer.Remaining() != 0 {
+		t.Errorf("expected cassette consumed, %d left", replayer.Remaining())
+	}
+}
+
+func TestCassette_ReplayExhausted(t *testing.T) {
+	// given - an empty cassette
+	path := filepath.Join(t.TempDir(), "empty.json")
+	srv := claudetest.NewServer(t)
+	srv.Client(claude.WithCassette(path)).Messages.Create(claude.MessageCreateParams{Model: "m"})
+	replayer, _ := claude.NewReplayer(path)
+	client := claude.NewClient(claude.WithBaseURL(srv.URL), claude.WithHTTPTransport(replayer))
+	client.Messages.Create(claude.MessageCreateParams{Model: "m"})
+
+	// when
+	_, err := client.Messages.Create(claude.MessageCreateParams{Model: "m"})
+
+	// then
+	if err == nil {
+		t.Fatal("expected error once the cassette is exhausted")
+	}
+}
+
+func TestCassette_CheckBodies(t *testing.T) {
+	// given - a recording of one prompt
+	path := filepath.Join(t.TempDir(), "session.json")
+	srv := claudetest.NewServer(t, claudetest.Reply(claudetest.Text("hello")), claudetest.Reply(claudetest.Text("hello")))
+	params := func(prompt string) claude.MessageCreateParams {
+		return claude.MessageCreateParams{Model: "m", MaxTokens: 10, Messages: []claude.MessageParam{{Role: "user", Content: prompt}}}
+	}
+	client := srv.Client(claude.WithCassette(path))
+	client.Messages.Create(params("hi"))
+	client.Messages.Create(params("hi"))
+
+	replay := func(prompt string) error {
+		replayer, err := claude.NewReplayer(path)
+		if err != nil {
+			t.Fatalf("loading cassette: %v", err)
+		}
+		replayer.CheckBodies = true
+		_, err = claude.NewClient(claude.WithBaseURL(srv.URL), claude.WithHTTPTransport(replayer)).Messages.Create(params(prompt))
+		return err
+	}
+
+	// when
+	same := replay("hi")
+	drifted := replay("hello there")
+
+	// then
+	if same != nil {
+		t.Errorf("expected the recorded prompt to replay, got %v", same)
+	}
+	if drifted == nil || !strings.Contains(drifted.Error(), "request body differs") {
+		t.Errorf("expected a body mismatch, got %v", drifted)
+	}
+}
+
+func TestWithCassette_UnreadableCassette(t *testing.T) {
+	// given - a cassette that exists but is not valid JSON
+	path := filepath.Join(t.TempDir(), "broken.json")
+	os.WriteFile(path, []byte("{not json"), 0644)
+	srv := claudetest.NewServer(t, claudetest.Reply(claudetest.Text("hello")))
+
+	// when
+	_, err := srv.Client(claude.WithCassette(path)).Messages.Create(claude.MessageCreateParams{Model: "m"})
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "parsing cassette") {
+		t.Errorf("expected the load error, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Errorf("expected the cassette left alone, got %s", data)
+	}
+	if n := len(srv.Requests()); n != 0 {
+		t.Errorf("expected no live requests, got %d", n)
+	}
+}
+
+func TestFakeServer_OverloadedMidStream(t *testing.T) {
+	// given
+	srv := claudetest.NewServer(t, claudetest.Overloaded())
+
+	// when
+	_, err := srv.Client().Messages.Stream(claude.MessageCreateParams{Model: "m"}).FinalMessage()
+
+	// then
+	var streamErr *claude.StreamError
+	if !errors.As(err, &streamErr) || !streamErr.Overloaded() {
+		t.Fatalf("expected overloaded stream error, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package claudetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"simpleagent/claude"
+)
+
+// fakeBatch answers every request from the script at creation time; the
+// batch reports in_progress until its first retrieval
+type fakeBatch struct {
+	batch   claude.MessageBatch
+	results []claude.BatchResult
+}
+
+// Batches returns the batches created so far
+func (s *Server) Batches() []claude.MessageBatch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []claude.MessageBatch
+	for _, b := range s.batches {
+		out = append(out, b.batch)
+	}
+	return out
+}
+
+func (s *Server) handleBatchCreate(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Requests []claude.BatchRequest `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || len(body.Requests) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "requests: expected a non-empty list")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fb := &fakeBatch{batch: claude.MessageBatch{
+		ID:               fmt.Sprintf("msgbatch_%d", len(s.batches)+1),
+		Type:             "message_batch",
+		ProcessingStatus: claude.BatchInProgress,
+		RequestCounts:    claude.RequestCounts{Processing: len(body.Requests)},
+		CreatedAt:        time.Now().UTC(),
+		ExpiresAt:        time.Now().UTC().Add(24 * time.Hour),
+	}}
+	for _, req := range body.Requests {
+		var result claude.BatchResult
+		result.CustomID = req.CustomID
+		turn, msg, ok := s.next(req.Params)
+		switch {
+		case !ok || turn.Err != nil:
+			result.Result.Type = "errored"
+			result.Result.Error = &claude.BatchError{Type: "error", Error: claude.StreamError{Type: "api_error", Message: "claudetest: script exhausted"}}
+			if ok {
+				result.Result.Error.Error = *turn.Err
+			}
+		default:
+			result.Result.Type = "succeeded"
+			result.Result.Message = msg
+		}
+		fb.results = append(fb.results, result)
+	}
+	s.batches = append(s.batches, fb)
+	writeJSON(w, fb.batch)
+}
+
+func (s *Server) handleBatchRetrieve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fb := s.batch(r.PathValue("id"))
+	if fb == nil {
+		writeError(w, http.StatusNotFound, "not_found_error", "batch not found")
+		return
+	}
+	fb.end()
+	writeJSON(w, fb.batch)
+}
+
+func (s *Server) handleBatchCancel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fb := s.batch(r.PathValue("id"))
+	if fb == nil {
+		writeError(w, http.StatusNotFound, "not_found_error", "batch not found")
+		return
+	}
+	if fb.batch.ProcessingStatus == claude.BatchInProgress {
+		now := time.Now().UTC()
+		fb.batch.ProcessingStatus = claude.BatchCanceling
+		fb.batch.CancelInitiatedAt = &now
+		for i := range fb.results {
+			fb.results[i].Result.Type = "canceled"
+			fb.results[i].Result.Message = nil
+			fb.results[i].Result.Error = nil
+		}
+	}
+	writeJSON(w, fb.batch)
+}
+
+func (s *Server) handleBatchList(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var page claude.BatchPage
+	page.Data = []claude.MessageBatch{}
+	for _, fb := range slices.Backward(s.batches) {
+		page.Data = append(page.Data, fb.batch)
+	}
+	if len(page.Data) > 0 {
+		page.FirstID, page.LastID = page.Data[0].ID, page.Data[len(page.Data)-1].ID
+	}
+	writeJSON(w, page)
+}
+
+func (s *Server) handleBatchResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fb := s.batch(r.PathValue("id"))
+	if fb == nil {
+		writeError(w, http.StatusNotFound, "not_found_error", "batch not found")
+		return
+	}
+	if fb.batch.ProcessingStatus != claude.BatchEnded {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "batch has not ended")
+		return
+	}
+	w.Header().Set("Content-Type", "application/binary")
+	enc := json.NewEncoder(w)
+	for _, result := range fb.results {
+		enc.Encode(result)
+	}
+}
+
+// batch finds a batch by id; callers hold s.mu
+func (s *Server) batch(id string) *fakeBatch {
+	for _, fb := range s.batches {
+		if fb.batch.ID == id {
+			return fb
+		}
+	}
+	return nil
+}
+
+// end finalizes the batch counts on first retrieval
+func (fb *fakeBatch) end() {
+	if fb.batch.ProcessingStatus == claude.BatchEnded {
+		return
+	}
+	now := time.Now().UTC()
+	fb.batch.ProcessingStatus = claude.BatchEnded
+	fb.batch.EndedAt = &now
+	fb.batch.ResultsURL = "/v1/messages/batches/" + fb.batch.ID + "/results"
+	counts := claude.RequestCounts{}
+	for _, result := range fb.results {
+		switch result.Result.Type {
+		case "succeeded":
+			counts.Succeeded++
+		case "errored":
+			counts.Errored++
+		case "canceled":
+			counts.Canceled++
+		}
+	}
+	fb.batch.RequestCounts = counts
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,249 @@
+// Package claudetest provides a scriptable fake Anthropic Messages API for tests
+package claudetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"simpleagent/claude"
+)
+
+// Turn is one scripted assistant response
+type Turn struct {
+	Blocks     []claude.ContentBlock
+	StopReason string              // default: tool_use when a tool is called, else end_turn
+	Err        *claude.StreamError // sent as an error event after message_start
+}
+
+// Text is a text block
+func Text(s string) claude.ContentBlock {
+	return claude.ContentBlock{Type: "text", Text: s}
+}
+
+// Thinking is a thinking block
+func Thinking(s string) claude.ContentBlock {
+	return claude.ContentBlock{Type: "thinking", Thinking: s, Signature: "sig"}
+}
+
+// ToolUse is a tool_use block; input is marshaled to JSON
+func ToolUse(id, name string, input any) claude.ContentBlock {
+	data, err := json.Marshal(input)
+	if err != nil {
+		panic(fmt.Sprintf("claudetest: marshal tool input: %v", err))
+	}
+	return claude.ContentBlock{Type: "tool_use", ID: id, Name: name, Input: data}
+}
+
+// Reply builds a turn from blocks
+func Reply(blocks ...claude.ContentBlock) Turn {
+	return Turn{Blocks: blocks}
+}
+
+// Overloaded is a turn that fails mid-stream with overloaded_error
+func Overloaded() Turn {
+	return Turn{Err: &claude.StreamError{Type: "overloaded_error", Message: "Overloaded"}}
+}
+
+// Server replays scripted turns in order, one per /v1/messages request
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	turns    []Turn
+	requests []claude.MessageCreateParams
+	batches  []*fakeBatch
+}
+
+// NewServer starts a fake API serving turns; it is closed on test cleanup
+func NewServer(t testing.TB, turns ...Turn) *Server {
+	s := &Server{turns: turns}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/messages", s.handleMessages)
+	mux.HandleFunc("POST /v1/messages/count_tokens", s.handleCountTokens)
+	mux.HandleFunc("POST /v1/messages/batches", s.handleBatchCreate)
+	mux.HandleFunc("GET /v1/messages/batches", s.handleBatchList)
+	mux.HandleFunc("GET /v1/messages/batches/{id}", s.handleBatchRetrieve)
+	mux.HandleFunc("POST /v1/messages/batches/{id}/cancel", s.handleBatchCancel)
+	mux.HandleFunc("GET /v1/messages/batches/{id}/results", s.handleBatchResults)
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Client returns a client pointed at the server
+func (s *Server) Client(opts ...claude.ClientOption) *claude.Client {
+	return claude.NewClient(append([]claude.ClientOption{claude.WithBaseURL(s.URL), claude.WithAPIKey("test")}, opts...)...)
+}
+
+// Requests returns the message requests received so far
+func (s *Server) Requests() []claude.MessageCreateParams {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]claude.MessageCreateParams(nil), s.requests...)
+}
+
+// Remaining returns how many scripted turns have not been served
+func (s *Server) Remaining() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.turns)
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	var params claude.MessageCreateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	turn, msg, ok := s.next(params)
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "api_error", "claudetest: script exhausted")
+		return
+	}
+	if turn.Err != nil && !params.Stream {
+		writeError(w, 529, turn.Err.Type, turn.Err.Message)
+		return
+	}
+	if !params.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(msg)
+		return
+	}
+	writeStream(w, msg, turn.Err)
+}
+
+// next records params and pops the next turn; callers hold s.mu
+func (s *Server) next(params claude.MessageCreateParams) (Turn, *claude.Message, bool) {
+	s.requests = append(s.requests, params)
+	if len(s.turns) == 0 {
+		return Turn{}, nil, false
+	}
+	turn := s.turns[0]
+	s.turns = s.turns[1:]
+
+	msg := turn.message(fmt.Sprintf("msg_%d", len(s.requests)), params.Model)
+	msg.Usage = &claude.Usage{
+		InputTokens:  claude.EstimateTokens(params),
+		OutputTokens: claude.EstimateTokens(claude.MessageCreateParams{Messages: []claude.MessageParam{{Role: "assistant", Content: msg.Content}}}),
+	}
+	return turn, msg, true
+}
+
+func (s *Server) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	var params claude.MessageCreateParams
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(claude.TokenCount{InputTokens: claude.EstimateTokens(params)})
+}
+
+func (t Turn) message(id, model string) *claude.Message {
+	stop := t.StopReason
+	if stop == "" {
+		stop = "end_turn"
+		for _, b := range t.Blocks {
+			if b.Type == "tool_use" {
+				stop = "tool_use"
+			}
+		}
+	}
+	return &claude.Message{ID: id, Type: "message", Role: "assistant", Content: t.Blocks, Model: model, StopReason: stop}
+}
+
+// writeStream emits msg as Anthropic SSE events, splitting deltas into chunks.
+// A non-nil streamErr replaces the content with an error event.
+func writeStream(w http.ResponseWriter, msg *claude.Message, streamErr *claude.StreamError) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	send := func(event string, data any) {
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id": msg.ID, "type": "message", "role": "assistant", "model": msg.Model, "content": []any{},
+			"usage": map[string]int{"input_tokens": msg.Usage.InputTokens, "output_tokens": 0},
+		},
+	})
+	send("ping", map[string]any{"type": "ping"})
+	if streamErr != nil {
+		send("error", map[string]any{"type": "error", "error": streamErr})
+		return
+	}
+	for i, b := range msg.Content {
+		start := map[string]any{"type": b.Type}
+		switch b.Type {
+		case "text":
+			start["text"] = ""
+		case "thinking":
+			start["thinking"] = ""
+		case "tool_use":
+			start["id"], start["name"], start["input"] = b.ID, b.Name, map[string]any{}
+		}
+		send("content_block_start", map[string]any{"type": "content_block_start", "index": i, "content_block": start})
+
+		delta := func(d map[string]any) {
+			send("content_block_delta", map[string]any{"type": "content_block_delta", "index": i, "delta": d})
+		}
+		switch b.Type {
+		case "text":
+			for _, c := range chunks(b.Text) {
+				delta(map[string]any{"type": "text_delta", "text": c})
+			}
+		case "thinking":
+			for _, c := range chunks(b.Thinking) {
+				delta(map[string]any{"type": "thinking_delta", "thinking": c})
+			}
+			delta(map[string]any{"type": "signature_delta", "signature": b.Signature})
+		case "tool_use":
+			for _, c := range chunks(string(b.Input)) {
+				delta(map[string]any{"type": "input_json_delta", "partial_json": c})
+			}
+		}
+		send("content_block_stop", map[string]any{"type": "content_block_stop", "index": i})
+	}
+	send("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": msg.StopReason},
+		"usage": map[string]int{"output_tokens": msg.Usage.OutputTokens},
+	})
+	send("message_stop", map[string]any{"type": "message_stop"})
+}
+
+// chunks splits s into small pieces so clients see incremental deltas
+func chunks(s string) []string {
+	const size = 16
+	var out []string
+	runes := []rune(s)
+	for len(runes) > size {
+		out = append(out, string(runes[:size]))
+		runes = runes[size:]
+	}
+	if len(runes) > 0 {
+		out = append(out, string(runes))
+	}
+	return out
+}
+
+func writeError(w http.ResponseWriter, status int, kind, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":  "error",
+		"error": map[string]string{"type": kind, "message": message},
+	})
+}
@@ -2,21 +2,22 @@ package claude
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 )
 
 // Client configuration
 type Client struct {
-	APIKey   string
-	BaseURL  string
-	Timeout  time.Duration
-	Messages *MessagesService
+	APIKey    string
+	BaseURL   string
+	Timeout   time.Duration
+	Provider  Provider          // wire format adapter (default: Anthropic)
+	Transport http.RoundTripper // nil = http.DefaultTransport (see WithCassette)
+	Messages  *MessagesService
+	Batches   *BatchesService
 }
 
 type ClientOption func(*Client)
@@ -33,23 +34,72 @@ func WithTimeout(d time.Duration) ClientOption {
 	return func(c *Client) { c.Timeout = d }
 }
 
+func WithProvider(p Provider) ClientOption {
+	return func(c *Client) { c.Provider = p }
+}
+
+func WithHTTPTransport(rt http.RoundTripper) ClientOption {
+	return func(c *Client) { c.Transport = rt }
+}
+
 // NewClient creates a new Anthropic API client
 func NewClient(opts ...ClientOption) *Client {
 	c := &Client{
-		APIKey:  os.Getenv("ANTHROPIC_API_KEY"),
-		BaseURL: "https://api.anthropic.com",
-		Timeout: 10 * time.Minute,
+		APIKey:   os.Getenv("ANTHROPIC_API_KEY"),
+		BaseURL:  "https://api.anthropic.com",
+		Timeout:  10 * time.Minute,
+		Provider: AnthropicProvider{},
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
 	c.Messages = &MessagesService{client: c}
+	c.Batches = &BatchesService{client: c}
 	return c
 }
 
+// provider returns the configured adapter (zero-value Clients use Anthropic)
+func (c *Client) provider() Provider {
+	if c.Provider == nil {
+		return AnthropicProvider{}
+	}
+	return c.Provider
+}
+
+// SendsThinking reports whether the provider passes the thinking config on
+// to the server; the OpenAI-compatible adapters drop it
+func (c *Client) SendsThinking() bool {
+	_, ok := c.provider().(AnthropicProvider)
+	return ok
+}
+
+// httpClient returns the HTTP client used for all API calls
+func (c *Client) httpClient() *http.Client {
+	return &http.Client{Timeout: c.Timeout, Transport: c.Transport}
+}
+
+// do sends a request built by the provider, returning an APIError on non-200
+func (c *Client) do(ctx context.Context, params MessageCreateParams) (*http.Response, error) {
+	req, err := c.provider().NewRequest(ctx, c, params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != 200 {
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, &APIError{Status: resp.StatusCode, Message: string(respBody)}
+	}
+	return resp, nil
+}
+
 // MessagesService handles message operations
 type MessagesService struct {
-	client *Client
+	client           *Client
+	countUnsupported bool // server rejected count_tokens, use estimates
 }
 
 // Create sends a message and returns the response
@@ -64,28 +114,21 @@ func (s *MessagesService) Create(params MessageCreateParams) (*Message, error) {
 // CreateWithResponse returns both message and raw HTTP response
 func (s *MessagesService) CreateWithResponse(params MessageCreateParams) (*MessageResponse, error) {
 	params.Stream = false
-	body, _ := json.Marshal(params)
-
-	req, _ := http.NewRequest("POST", s.client.BaseURL+"/v1/messages", strings.NewReader(string(body)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", s.client.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	httpClient := &http.Client{Timeout: s.client.Timeout}
-	resp, err := httpClient.Do(req)
+	resp, err := s.client.do(context.Background(), params)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	respBody, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode != 200 {
-		return nil, &APIError{Status: resp.StatusCode, Message: string(respBody)}
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
 	}
-
-	var msg Message
-	json.Unmarshal(respBody, &msg)
-	return &MessageResponse{Message: &msg, Response: resp}, nil
+	msg, err := s.client.provider().DecodeMessage(respBody)
+	if err != nil {
+		return nil, err
+	}
+	return &MessageResponse{Message: msg, Response: resp}, nil
 }
 
 // Stream returns a MessageStream for streaming responses
@@ -0,0 +1,384 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAIProvider speaks the OpenAI Chat Completions wire format used by
+// llama.cpp, vLLM, Ollama and most OpenAI-compatible servers
+type OpenAIProvider struct{}
+
+func (OpenAIProvider) Name() string { return ProviderOpenAI }
+
+// Chat Completions request/response shapes (internal)
+type oaiMessage struct {
+	Role             string        `json:"role"`
+	Content          *string       `json:"content"`
+	ReasoningContent string        `json:"reasoning_content,omitempty"`
+	Reasoning        string        `json:"reasoning,omitempty"` // Ollama, some vLLM builds
+	ToolCalls        []oaiToolCall `json:"tool_calls,omitempty"`
+	ToolCallID       string        `json:"tool_call_id,omitempty"`
+}
+
+type oaiToolCall struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Function struct {
+		Name      string `json:"name,omitempty"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type oaiTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string      `json:"name"`
+		Description string      `json:"description,omitempty"`
+		Parameters  InputSchema `json:"parameters"`
+	} `json:"function"`
+}
+
+type oaiRequest struct {
+	Model         string       `json:"model"`
+	Messages      []oaiMessage `json:"messages"`
+	Tools         []oaiTool    `json:"tools,omitempty"`
+	MaxTokens     int          `json:"max_tokens,omitempty"`
+	Stream        bool         `json:"stream,omitempty"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options,omitempty"`
+	ToolChoice        any      `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool    `json:"parallel_tool_calls,omitempty"`
+	Stop              []string `json:"stop,omitempty"`
+	Temperature       *float64 `json:"temperature,omitempty"`
+	TopP              *float64 `json:"top_p,omitempty"`
+	TopK              *int     `json:"top_k,omitempty"` // llama.cpp / vLLM extension
+	User              string   `json:"user,omitempty"`
+}
+
+type oaiUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+type oaiResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Message      oaiMessage `json:"message"`
+		Delta        oaiMessage `json:"delta"`
+		FinishReason string     `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *oaiUsage    `json:"usage"`
+	Error *StreamError `json:"error"` // mid-stream failure (vLLM, llama.cpp)
+}
+
+func (OpenAIProvider) NewRequest(ctx context.Context, c *Client, params MessageCreateParams) (*http.Request, error) {
+	body, err := json.Marshal(toOpenAIRequest(params))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", joinURL(c.BaseURL, "/v1/chat/completions"), strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	return req, nil
+}
+
+func toOpenAIRequest(params MessageCreateParams) oaiRequest {
+	req := oaiRequest{
+		Model:       params.Model,
+		MaxTokens:   params.MaxTokens,
+		Stream:      params.Stream,
+		Stop:        params.StopSequences,
+		Temperature: params.Temperature,
+		TopP:        params.TopP,
+		TopK:        params.TopK,
+	}
+	if params.Metadata != nil {
+		req.User = params.Metadata.UserID
+	}
+	if tc := params.ToolChoice; tc != nil {
+		req.ToolChoice = openAIToolChoice(tc, false)
+		if tc.DisableParallelToolUse {
+			req.ParallelToolCalls = new(bool)
+		}
+	}
+	if params.Stream {
+		req.StreamOptions = &struct {
+			IncludeUsage bool `json:"include_usage"`
+		}{IncludeUsage: true}
+	}
+	if params.System != "" {
+		req.Messages = append(req.Messages, oaiMessage{Role: "system", Content: strPtr(params.System)})
+	}
+	for _, m := range params.Messages {
+		req.Messages = append(req.Messages, toOpenAIMessages(m)...)
+	}
+	for _, t := range params.Tools {
+		var tool oaiTool
+		tool.Type = "function"
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.InputSchema
+		req.Tools = append(req.Tools, tool)
+	}
+	return req
+}
+
+// openAIToolChoice translates tool_choice; the Responses API names forced
+// functions at the top level ("flat"), Chat Completions nests them
+func openAIToolChoice(tc *ToolChoice, flat bool) any {
+	switch tc.Type {
+	case ToolChoiceAny:
+		return "required"
+	case ToolChoiceNone:
+		return "none"
+	case ToolChoiceTool:
+		if flat {
+			return map[string]string{"type": "function", "name": tc.Name}
+		}
+		return map[string]any{"type": "function", "function": map[string]string{"name": tc.Name}}
+	}
+	return "auto"
+}
+
+// toOpenAIMessages splits one Anthropic message into Chat Completions messages
+// (tool results become separate "tool" role messages)
+func toOpenAIMessages(m MessageParam) []oaiMessage {
+	var out []oaiMessage
+	var texts []string
+	var calls []oaiToolCall
+	for _, b := range ContentBlocks(m.Content) {
+		switch b.Type {
+		case "text":
+			texts = append(texts, b.Text)
+		case "tool_use":
+			var call oaiToolCall
+			call.ID = b.ID
+			call.Type = "function"
+			call.Function.Name = b.Name
+			call.Function.Arguments = b.inputJSON()
+			calls = append(calls, call)
+		case "tool_result":
+			out = append(out, oaiMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: strPtr(b.ResultText())})
+		}
+	}
+	if len(texts) > 0 || len(calls) > 0 {
+		msg := oaiMessage{Role: m.Role, ToolCalls: calls}
+		if len(texts) > 0 || m.Role != "assistant" {
+			msg.Content = strPtr(strings.Join(texts, "\n"))
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+func (OpenAIProvider) DecodeMessage(body []byte) (*Message, error) {
+	var resp oaiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	msg := &Message{ID: resp.ID, Type: "message", Role: "assistant", Model: resp.Model, Content: []ContentBlock{}}
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if r := choice.Message.reasoning(); r != "" {
+			msg.Content = append(msg.Content, ContentBlock{Type: "thinking", Thinking: r})
+		}
+		if choice.Message.Content != nil && *choice.Message.Content != "" {
+			msg.Content = append(msg.Content, ContentBlock{Type: "text", Text: *choice.Message.Content})
+		}
+		for _, call := range choice.Message.ToolCalls {
+			msg.Content = append(msg.Content, ContentBlock{
+				Type:  "tool_use",
+				ID:    call.ID,
+				Name:  call.Function.Name,
+				Input: toolArguments(call.Function.Arguments),
+			})
+		}
+		msg.StopReason = openAIStopReason(choice.FinishReason)
+	}
+	if resp.Usage != nil {
+		msg.Usage = &Usage{InputTokens: resp.Usage.PromptTokens, OutputTokens: resp.Usage.CompletionTokens}
+	}
+	return msg, nil
+}
+
+func (OpenAIProvider) DecodeStream(body io.Reader, emit func(StreamEvent) error) error {
+	t := &streamTranslator{emit: emit}
+	err := readSSE(body, func(_, data string) error {
+		if data == "[DONE]" {
+			return nil
+		}
+		var chunk oaiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil
+		}
+		if chunk.Error != nil {
+			return chunk.Error
+		}
+		if err := t.start(chunk.ID, chunk.Model); err != nil {
+			return err
+		}
+		if chunk.Usage != nil {
+			t.usage = &Usage{InputTokens: chunk.Usage.PromptTokens, OutputTokens: chunk.Usage.CompletionTokens}
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		choice := chunk.Choices[0]
+		if r := choice.Delta.reasoning(); r != "" {
+			if err := t.thinking(r); err != nil {
+				return err
+			}
+		}
+		if choice.Delta.Content != nil && *choice.Delta.Content != "" {
+			if err := t.text(*choice.Delta.Content); err != nil {
+				return err
+			}
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			if err := t.toolCall(call.Index, call.ID, call.Function.Name, call.Function.Arguments); err != nil {
+				return err
+			}
+		}
+		if choice.FinishReason != "" {
+			t.stopReason = openAIStopReason(choice.FinishReason)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.finish()
+}
+
+func (m oaiMessage) reasoning() string {
+	if m.ReasoningContent != "" {
+		return m.ReasoningContent
+	}
+	return m.Reasoning
+}
+
+func openAIStopReason(reason string) string {
+	switch reason {
+	case "length":
+		return "max_tokens"
+	case "tool_calls", "function_call":
+		return "tool_use"
+	case "":
+		return ""
+	}
+	return "end_turn"
+}
+
+// toolArguments converts an OpenAI arguments string to tool input JSON
+func toolArguments(args string) json.RawMessage {
+	if strings.TrimSpace(args) == "" {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(args)
+}
+
+func strPtr(s string) *string { return &s }
+
+// streamTranslator turns provider deltas into Anthropic content block events
+type streamTranslator struct {
+	emit       func(StreamEvent) error
+	started    bool
+	open       string // type of the open block ("" = none)
+	index      int    // index of the open block
+	next       int    // index for the next block
+	toolIndex  int    // provider-side index of the open tool call
+	toolID     string // id of the open tool call
+	stopReason string
+	usage      *Usage // reported in the final chunk, if at all
+}
+
+func (t *streamTranslator) start(id, model string) error {
+	if t.started {
+		return nil
+	}
+	t.started = true
+	return t.emit(StreamEvent{Type: "message_start", Message: &StreamMessage{ID: id, Model: model, Role: "assistant"}})
+}
+
+// openBlock closes any open block and starts a new one
+func (t *streamTranslator) openBlock(block ContentBlock) error {
+	if err := t.closeBlock(); err != nil {
+		return err
+	}
+	t.open = block.Type
+	t.index = t.next
+	t.next++
+	return t.emit(StreamEvent{Type: "content_block_start", Index: t.index, ContentBlock: &block})
+}
+
+func (t *streamTranslator) closeBlock() error {
+	if t.open == "" {
+		return nil
+	}
+	t.open = ""
+	return t.emit(StreamEvent{Type: "content_block_stop", Index: t.index})
+}
+
+func (t *streamTranslator) delta(d StreamDelta) error {
+	return t.emit(StreamEvent{Type: "content_block_delta", Index: t.index, Delta: d})
+}
+
+func (t *streamTranslator) thinking(s string) error {
+	if t.open != "thinking" {
+		if err := t.openBlock(ContentBlock{Type: "thinking"}); err != nil {
+			return err
+		}
+	}
+	return t.delta(StreamDelta{Type: "thinking_delta", Thinking: s})
+}
+
+func (t *streamTranslator) text(s string) error {
+	if t.open != "text" {
+		if err := t.openBlock(ContentBlock{Type: "text"}); err != nil {
+			return err
+		}
+	}
+	return t.delta(StreamDelta{Type: "text_delta", Text: s})
+}
+
+func (t *streamTranslator) toolCall(index int, id, name, args string) error {
+	if t.open != "tool_use" || index != t.toolIndex || (id != "" && id != t.toolID) {
+		if err := t.openBlock(ContentBlock{Type: "tool_use", ID: id, Name: name}); err != nil {
+			return err
+		}
+		t.toolIndex = index
+		t.toolID = id
+	}
+	if args == "" {
+		return nil
+	}
+	return t.delta(StreamDelta{Type: "input_json_delta", PartialJSON: args})
+}
+
+func (t *streamTranslator) finish() error {
+	if err := t.start("", ""); err != nil {
+		return err
+	}
+	if err := t.closeBlock(); err != nil {
+		return err
+	}
+	if t.stopReason == "" {
+		t.stopReason = "end_turn"
+	}
+	if err := t.emit(StreamEvent{Type: "message_delta", Delta: StreamDelta{StopReason: t.stopReason}, Usage: t.usage}); err != nil {
+		return err
+	}
+	return t.emit(StreamEvent{Type: "message_stop"})
+}
@@ -0,0 +1,248 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAIResponsesProvider speaks the OpenAI Responses API wire format
+type OpenAIResponsesProvider struct{}
+
+func (OpenAIResponsesProvider) Name() string { return ProviderOpenAIResponses }
+
+// Responses API shapes (internal)
+type respInputItem struct {
+	Type    string `json:"type,omitempty"`
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+	CallID  string `json:"call_id,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Args    string `json:"arguments,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+type respTool struct {
+	Type        string      `json:"type"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  InputSchema `json:"parameters"`
+}
+
+type respRequest struct {
+	Model             string          `json:"model"`
+	Instructions      string          `json:"instructions,omitempty"`
+	Input             []respInputItem `json:"input"`
+	Tools             []respTool      `json:"tools,omitempty"`
+	MaxOutputTokens   int             `json:"max_output_tokens,omitempty"`
+	Stream            bool            `json:"stream,omitempty"`
+	ToolChoice        any             `json:"tool_choice,omitempty"`
+	ParallelToolCalls *bool           `json:"parallel_tool_calls,omitempty"`
+	Temperature       *float64        `json:"temperature,omitempty"`
+	TopP              *float64        `json:"top_p,omitempty"`
+	User              string          `json:"user,omitempty"`
+}
+
+type respOutputItem struct {
+	Type    string `json:"type"`
+	CallID  string `json:"call_id"`
+	Name    string `json:"name"`
+	Args    string `json:"arguments"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Summary []struct {
+		Text string `json:"text"`
+	} `json:"summary"`
+}
+
+type respResponse struct {
+	ID                string           `json:"id"`
+	Model             string           `json:"model"`
+	Status            string           `json:"status"`
+	Output            []respOutputItem `json:"output"`
+	IncompleteDetails *struct {
+		Reason string `json:"reason"`
+	} `json:"incomplete_details"`
+	Usage *struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// respStreamEvent is one Responses API stream event
+type respStreamEvent struct {
+	Type     string          `json:"type"`
+	Delta    string          `json:"delta"`
+	Item     *respOutputItem `json:"item"`
+	Response *respResponse   `json:"response"`
+	Code     string          `json:"code"`    // error events
+	Message  string          `json:"message"` // error events
+}
+
+func (OpenAIResponsesProvider) NewRequest(ctx context.Context, c *Client, params MessageCreateParams) (*http.Request, error) {
+	body, err := json.Marshal(toResponsesRequest(params))
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", joinURL(c.BaseURL, "/v1/responses"), strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	return req, nil
+}
+
+func toResponsesRequest(params MessageCreateParams) respRequest {
+	req := respRequest{
+		Model:           params.Model,
+		Instructions:    params.System,
+		MaxOutputTokens: params.MaxTokens,
+		Stream:          params.Stream,
+		Input:           []respInputItem{},
+		Temperature:     params.Temperature,
+		TopP:            params.TopP,
+	}
+	if params.Metadata != nil {
+		req.User = params.Metadata.UserID
+	}
+	if tc := params.ToolChoice; tc != nil {
+		req.ToolChoice = openAIToolChoice(tc, true)
+		if tc.DisableParallelToolUse {
+			req.ParallelToolCalls = new(bool)
+		}
+	}
+	for _, m := range params.Messages {
+		var texts []string
+		for _, b := range ContentBlocks(m.Content) {
+			switch b.Type {
+			case "text":
+				texts = append(texts, b.Text)
+			case "tool_use":
+				req.Input = append(req.Input, respInputItem{Type: "function_call", CallID: b.ID, Name: b.Name, Args: b.inputJSON()})
+			case "tool_result":
+				req.Input = append(req.Input, respInputItem{Type: "function_call_output", CallID: b.ToolUseID, Output: b.ResultText()})
+			}
+		}
+		if len(texts) > 0 {
+			req.Input = append(req.Input, respInputItem{Role: m.Role, Content: strings.Join(texts, "\n")})
+		}
+	}
+	for _, t := range params.Tools {
+		req.Tools = append(req.Tools, respTool{Type: "function", Name: t.Name, Description: t.Description, Parameters: t.InputSchema})
+	}
+	return req
+}
+
+func (OpenAIResponsesProvider) DecodeMessage(body []byte) (*Message, error) {
+	var resp respResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	msg := &Message{ID: resp.ID, Type: "message", Role: "assistant", Model: resp.Model, Content: []ContentBlock{}}
+	for _, item := range resp.Output {
+		switch item.Type {
+		case "reasoning":
+			var parts []string
+			for _, s := range item.Summary {
+				parts = append(parts, s.Text)
+			}
+			if len(parts) > 0 {
+				msg.Content = append(msg.Content, ContentBlock{Type: "thinking", Thinking: strings.Join(parts, "\n")})
+			}
+		case "message":
+			for _, c := range item.Content {
+				if c.Type == "output_text" {
+					msg.Content = append(msg.Content, ContentBlock{Type: "text", Text: c.Text})
+				}
+			}
+		case "function_call":
+			msg.Content = append(msg.Content, ContentBlock{Type: "tool_use", ID: item.CallID, Name: item.Name, Input: toolArguments(item.Args)})
+		}
+	}
+	msg.StopReason = responsesStopReason(&resp, hasToolUse(msg.Content))
+	if resp.Usage != nil {
+		msg.Usage = &Usage{InputTokens: resp.Usage.InputTokens, OutputTokens: resp.Usage.OutputTokens}
+	}
+	return msg, nil
+}
+
+func (OpenAIResponsesProvider) DecodeStream(body io.Reader, emit func(StreamEvent) error) error {
+	t := &streamTranslator{emit: emit}
+	var sawToolCall bool
+	err := readSSE(body, func(_, data string) error {
+		var ev respStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return nil
+		}
+		switch ev.Type {
+		case "response.created":
+			if ev.Response != nil {
+				return t.start(ev.Response.ID, ev.Response.Model)
+			}
+		case "response.reasoning_summary_text.delta", "response.reasoning_text.delta":
+			return t.thinking(ev.Delta)
+		case "response.output_text.delta":
+			return t.text(ev.Delta)
+		case "response.output_item.added":
+			if ev.Item != nil && ev.Item.Type == "function_call" {
+				sawToolCall = true
+				return t.openBlock(ContentBlock{Type: "tool_use", ID: ev.Item.CallID, Name: ev.Item.Name})
+			}
+		case "response.function_call_arguments.delta":
+			return t.delta(StreamDelta{Type: "input_json_delta", PartialJSON: ev.Delta})
+		case "response.output_item.done":
+			if ev.Item != nil && ev.Item.Type == "function_call" {
+				return t.closeBlock()
+			}
+		case "response.completed", "response.incomplete":
+			if ev.Response != nil {
+				t.stopReason = responsesStopReason(ev.Response, sawToolCall)
+				if u := ev.Response.Usage; u != nil {
+					t.usage = &Usage{InputTokens: u.InputTokens, OutputTokens: u.OutputTokens}
+				}
+			}
+		case "error":
+			return &StreamError{Type: ev.Code, Message: ev.Message}
+		case "response.failed":
+			if ev.Response != nil && ev.Response.Error != nil {
+				return &StreamError{Type: ev.Response.Error.Code, Message: ev.Response.Error.Message}
+			}
+			return &StreamError{Type: "response_failed", Message: "response failed"}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return t.finish()
+}
+
+func responsesStopReason(resp *respResponse, toolUse bool) string {
+	if resp.IncompleteDetails != nil && resp.IncompleteDetails.Reason == "max_output_tokens" {
+		return "max_tokens"
+	}
+	if toolUse {
+		return "tool_use"
+	}
+	return "end_turn"
+}
+
+func hasToolUse(blocks []ContentBlock) bool {
+	for _, b := range blocks {
+		if b.Type == "tool_use" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Provider adapts the Messages API to a backend wire format. Adapters decode
+// streams into Anthropic-shaped events so MessageStream handles every backend
+// with the same accumulation logic.
+type Provider interface {
+	// Name identifies the adapter in config ("anthropic", "openai", ...)
+	Name() string
+	// NewRequest builds the HTTP request for params (params.Stream selects streaming)
+	NewRequest(ctx context.Context, c *Client, params MessageCreateParams) (*http.Request, error)
+	// DecodeMessage parses a non-streaming response body
+	DecodeMessage(body []byte) (*Message, error)
+	// DecodeStream reads a streaming response body and calls emit per event
+	DecodeStream(body io.Reader, emit func(StreamEvent) error) error
+}
+
+// Provider names accepted by NewProvider
+const (
+	ProviderAnthropic       = "anthropic"
+	ProviderOpenAI          = "openai"           // Chat Completions (llama.cpp, vLLM, Ollama)
+	ProviderOpenAIResponses = "openai-responses" // Responses API
+)
+
+// NewProvider returns the adapter for name ("" = anthropic)
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case "", ProviderAnthropic:
+		return AnthropicProvider{}, nil
+	case ProviderOpenAI:
+		return OpenAIProvider{}, nil
+	case ProviderOpenAIResponses:
+		return OpenAIResponsesProvider{}, nil
+	}
+	return nil, fmt.Errorf("unknown provider %q (want %s, %s or %s)", name, ProviderAnthropic, ProviderOpenAI, ProviderOpenAIResponses)
+}
+
+// AnthropicProvider speaks the native Messages wire format
+type AnthropicProvider struct{}
+
+func (AnthropicProvider) Name() string { return ProviderAnthropic }
+
+func (AnthropicProvider) NewRequest(ctx context.Context, c *Client, params MessageCreateParams) (*http.Request, error) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", joinURL(c.BaseURL, "/v1/messages"), strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, nil
+}
+
+func (AnthropicProvider) DecodeMessage(body []byte) (*Message, error) {
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (AnthropicProvider) DecodeStream(body io.Reader, emit func(StreamEvent) error) error {
+	return readSSE(body, func(name, data string) error {
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil // skip malformed events
+		}
+		if event.Type == "" {
+			event.Type = name
+		}
+		if event.Type == "error" {
+			if event.Error == nil {
+				return &StreamError{Type: "api_error", Message: data}
+			}
+			return event.Error
+		}
+		return emit(event)
+	})
+}
+
+// joinURL appends path to base, avoiding a doubled /v1 when base already ends in it
+func joinURL(base, path string) string {
+	base = strings.TrimRight(base, "/")
+	if strings.HasSuffix(base, "/v1") {
+		path = strings.TrimPrefix(path, "/v1")
+	}
+	return base + path
+}
+
+// WireBlock is a content block in any of the shapes MessageParam.Content can
+// hold (typed blocks, tool results, or JSON decoded from a session file)
+type WireBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	Thinking  string          `json:"thinking,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+}
+
+// ContentBlocks normalizes MessageParam.Content into a flat block list
+func ContentBlocks(content any) []WireBlock {
+	if s, ok := content.(string); ok {
+		return []WireBlock{{Type: "text", Text: s}}
+	}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		return []WireBlock{{Type: "text", Text: text}}
+	}
+	var blocks []WireBlock
+	json.Unmarshal(data, &blocks)
+	return blocks
+}
+
+// ResultText flattens tool_result content (string or text blocks) to a string
+func (b WireBlock) ResultText() string {
+	var s string
+	if json.Unmarshal(b.Content, &s) == nil {
+		return s
+	}
+	var parts []WireBlock
+	json.Unmarshal(b.Content, &parts)
+	var texts []string
+	for _, p := range parts {
+		if p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// inputJSON returns tool input as a JSON object string (OpenAI "arguments")
+func (b WireBlock) inputJSON() string {
+	if len(b.Input) == 0 {
+		return "{}"
+	}
+	return string(b.Input)
+}
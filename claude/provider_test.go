@@ -0,0 +1,212 @@
+package claude
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// sseServer replies to every request with the given SSE lines, recording the last body
+func sseServer(t *testing.T, path string, lines []string, lastBody *[]byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			t.Errorf("expected path %s, got %s", path, r.URL.Path)
+		}
+		if lastBody != nil {
+			*lastBody, _ = io.ReadAll(r.Body)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, l := range lines {
+			fmt.Fprintf(w, "%s\n\n", l)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAnthropicProvider_Stream(t *testing.T) {
+	// given
+	srv := sseServer(t, "/v1/messages", []string{
+		`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_1","model":"m","role":"assistant"}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"}}`,
+		`data: {"type":"message_stop"}`,
+	}, nil)
+	client := NewClient(WithBaseURL(srv.URL))
+
+	// when
+	msg, err := client.Messages.Stream(MessageCreateParams{Model: "m"}).FinalMessage()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.ID != "msg_1" || len(msg.Content) != 1 || msg.Content[0].Text != "hi" || msg.StopReason != "end_turn" {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestOpenAIProvider_StreamTextReasoningAndTools(t *testing.T) {
+	// given - llama.cpp style chunks with reasoning, text and a split tool call
+	srv := sseServer(t, "/v1/chat/completions", []string{
+		`data: {"id":"c1","model":"local","choices":[{"delta":{"reasoning_content":"hmm"}}]}`,
+		`data: {"id":"c1","model":"local","choices":[{"delta":{"content":"Let me look."}}]}`,
+		`data: {"id":"c1","model":"local","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"ReadFile","arguments":"{\"pa"}}]}}]}`,
+		`data: {"id":"c1","model":"local","choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"th\":\"a.go\"}"}}]}}]}`,
+		`data: {"id":"c1","model":"local","choices":[{"delta":{},"finish_reason":"tool_calls"}]}`,
+		`data: [DONE]`,
+	}, nil)
+	client := NewClient(WithBaseURL(srv.URL), WithProvider(OpenAIProvider{}))
+
+	// when
+	var thinking string
+	stream := client.Messages.Stream(MessageCreateParams{Model: "local"})
+	stream.OnThinking(func(s string) { thinking += s })
+	msg, err := stream.FinalMessage()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if thinking != "hmm" {
+		t.Errorf("expected thinking 'hmm', got %q", thinking)
+	}
+	if len(msg.Content) != 3 {
+		t.Fatalf("expected 3 blocks, got %+v", msg.Content)
+	}
+	tool := msg.Content[2]
+	if tool.Type != "tool_use" || tool.ID != "call_1" || tool.Name != "ReadFile" || string(tool.Input) != `{"path":"a.go"}` {
+		t.Errorf("unexpected tool block: %+v (input %s)", tool, tool.Input)
+	}
+	if msg.StopReason != "tool_use" {
+		t.Errorf("expected stop_reason tool_use, got %q", msg.StopReason)
+	}
+}
+
+func TestOpenAIProvider_RequestTranslation(t *testing.T) {
+	// given - a tool round trip in Anthropic shape
+	params := MessageCreateParams{
+		Model:  "local",
+		System: "be brief",
+		Messages: []MessageParam{
+			{Role: "user", Content: "read a.go"},
+			{Role: "assistant", Content: []ContentBlock{{Type: "tool_use", ID: "call_1", Name: "ReadFile", Input: json.RawMessage(`{"path":"a.go"}`)}}},
+			{Role: "user", Content: []ToolResultBlock{{Type: "tool_result", ToolUseID: "call_1", Content: "package a"}}},
+		},
+		Tools: []Tool{{Name: "ReadFile", InputSchema: InputSchema{Type: "object"}}},
+	}
+
+	// when
+	req := toOpenAIRequest(params)
+
+	// then
+	roles := []string{}
+	for _, m := range req.Messages {
+		roles = append(roles, m.Role)
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,tool" {
+		t.Fatalf("unexpected roles: %v", roles)
+	}
+	if call := req.Messages[2].ToolCalls; len(call) != 1 || call[0].Function.Arguments != `{"path":"a.go"}` {
+		t.Errorf("unexpected tool call: %+v", call)
+	}
+	if req.Messages[3].ToolCallID != "call_1" || *req.Messages[3].Content != "package a" {
+		t.Errorf("unexpected tool message: %+v", req.Messages[3])
+	}
+	if len(req.Tools) != 1 || req.Tools[0].Function.Name != "ReadFile" {
+		t.Errorf("unexpected tools: %+v", req.Tools)
+	}
+}
+
+func TestOpenAIResponsesProvider_Stream(t *testing.T) {
+	// given
+	srv := sseServer(t, "/v1/responses", []string{
+		`event: response.created` + "\n" + `data: {"type":"response.created","response":{"id":"r1","model":"gpt"}}`,
+		`data: {"type":"response.output_text.delta","delta":"ok"}`,
+		`data: {"type":"response.output_item.added","item":{"type":"function_call","call_id":"fc_1","name":"Ls"}}`,
+		`data: {"type":"response.function_call_arguments.delta","delta":"{\"path\":\".\"}"}`,
+		`data: {"type":"response.output_item.done","item":{"type":"function_call"}}`,
+		`data: {"type":"response.completed","response":{"status":"completed"}}`,
+	}, nil)
+	client := NewClient(WithBaseURL(srv.URL+"/v1"), WithProvider(OpenAIResponsesProvider{}))
+
+	// when
+	msg, err := client.Messages.Stream(MessageCreateParams{Model: "gpt"}).FinalMessage()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msg.Content) != 2 || msg.Content[0].Text != "ok" || msg.Content[1].Name != "Ls" {
+		t.Fatalf("unexpected content: %+v", msg.Content)
+	}
+	if msg.StopReason != "tool_use" {
+		t.Errorf("expected tool_use, got %q", msg.StopReason)
+	}
+}
+
+func TestNewProvider_Unknown(t *testing.T) {
+	// given/when
+	_, err := NewProvider("bogus")
+
+	// then
+	if err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestMessageCreateParams_ToolChoiceAndSamplingJSON(t *testing.T) {
+	// given
+	params := MessageCreateParams{
+		Model:      "m",
+		ToolChoice: &ToolChoice{Type: ToolChoiceAny, DisableParallelToolUse: true},
+		Metadata:   &Metadata{UserID: "u1"},
+		Sampling:   Sampling{Temperature: Float(0.5), TopK: Int(40), StopSequences: []string{"###"}},
+	}
+
+	// when
+	data, _ := json.Marshal(params)
+
+	// then
+	for _, want := range []string{
+		`"tool_choice":{"type":"any","disable_parallel_tool_use":true}`,
+		`"metadata":{"user_id":"u1"}`,
+		`"temperature":0.5`, `"top_k":40`, `"stop_sequences":["###"]`,
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected %s in %s", want, data)
+		}
+	}
+	if strings.Contains(string(data), "top_p") {
+		t.Errorf("expected unset top_p omitted: %s", data)
+	}
+}
+
+func TestOpenAIProvider_ToolChoiceTranslation(t *testing.T) {
+	// given
+	params := MessageCreateParams{
+		Model:      "m",
+		ToolChoice: &ToolChoice{Type: ToolChoiceTool, Name: "Done", DisableParallelToolUse: true},
+		Sampling:   Sampling{StopSequences: []string{"END"}},
+	}
+
+	// when
+	chat, _ := json.Marshal(toOpenAIRequest(params))
+	responses, _ := json.Marshal(toResponsesRequest(params))
+
+	// then
+	if !strings.Contains(string(chat), `"tool_choice":{"function":{"name":"Done"},"type":"function"}`) ||
+		!strings.Contains(string(chat), `"parallel_tool_calls":false`) || !strings.Contains(string(chat), `"stop":["END"]`) {
+		t.Errorf("unexpected chat request: %s", chat)
+	}
+	if !strings.Contains(string(responses), `"tool_choice":{"name":"Done","type":"function"}`) {
+		t.Errorf("unexpected responses request: %s", responses)
+	}
+}
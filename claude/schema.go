@@ -0,0 +1,228 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// InputSchema is the root JSON Schema of a tool's input (always type "object")
+type InputSchema = Property
+
+// Property is a JSON Schema node. Field names follow the JSON Schema spec so
+// schemas from MCP servers round-trip without loss.
+type Property struct {
+	Type        string   `json:"type,omitempty"`
+	Types       []string `json:"-"` // union type, e.g. ["string", "null"] (takes precedence over Type)
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Ref         string   `json:"$ref,omitempty"`
+
+	// Value constraints
+	Enum    []any `json:"enum,omitempty"`
+	Const   any   `json:"const,omitempty"`
+	Default any   `json:"default,omitempty"`
+
+	// Numbers
+	Minimum          *float64 `json:"minimum,omitempty"`
+	Maximum          *float64 `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64 `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64 `json:"exclusiveMaximum,omitempty"`
+	MultipleOf       *float64 `json:"multipleOf,omitempty"`
+
+	// Strings
+	MinLength *int   `json:"minLength,omitempty"`
+	MaxLength *int   `json:"maxLength,omitempty"`
+	Pattern   string `json:"pattern,omitempty"`
+	Format    string `json:"format,omitempty"`
+
+	// Arrays
+	Items       *Property  `json:"items,omitempty"`
+	TupleItems  []Property `json:"-"` // array form of items: one schema per position
+	MinItems    *int       `json:"minItems,omitempty"`
+	MaxItems    *int       `json:"maxItems,omitempty"`
+	UniqueItems bool       `json:"uniqueItems,omitempty"`
+
+	// Objects
+	Properties           map[string]Property `json:"properties,omitempty"`
+	Required             []string            `json:"required,omitempty"`
+	AdditionalProperties any                 `json:"additionalProperties,omitempty"` // bool or *Property
+
+	// Composition
+	AnyOf []Property `json:"anyOf,omitempty"`
+	OneOf []Property `json:"oneOf,omitempty"`
+	AllOf []Property `json:"allOf,omitempty"`
+	Not   *Property  `json:"not,omitempty"`
+
+	Defs map[string]Property `json:"$defs,omitempty"`
+
+	// Boolean is set for the boolean schemas: true allows any value, false none
+	Boolean *bool `json:"-"`
+
+	// Extra keeps keywords with no field (patternProperties, prefixItems,
+	// if/then/else, examples, $schema, ...) to be emitted unchanged
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// schemaKeywords are the keywords Property has a field for
+var schemaKeywords = func() map[string]bool {
+	keywords := make(map[string]bool)
+	typ := reflect.TypeFor[Property]()
+	for i := range typ.NumField() {
+		if name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ","); name != "" && name != "-" {
+			keywords[name] = true
+		}
+	}
+	return keywords
+}()
+
+// Float returns a pointer to f (for Minimum/Maximum literals)
+func Float(f float64) *float64 { return &f }
+
+// Int returns a pointer to n (for MinLength/MaxItems literals)
+func Int(n int) *int { return &n }
+
+// TypeNames returns the declared type(s) of the schema
+func (p Property) TypeNames() []string {
+	if len(p.Types) > 0 {
+		return p.Types
+	}
+	if p.Type != "" {
+		return []string{p.Type}
+	}
+	return nil
+}
+
+// AdditionalSchema returns the schema for additional properties and whether
+// additional properties are allowed at all
+func (p Property) AdditionalSchema() (*Property, bool) {
+	switch v := p.AdditionalProperties.(type) {
+	case nil:
+		return nil, true
+	case bool:
+		return nil, v
+	case *Property:
+		return v, true
+	case Property:
+		return &v, true
+	}
+	return nil, true
+}
+
+// MarshalJSON emits "type" as a string or array depending on Types, tuple
+// items as an array, boolean schemas as true or false, and Extra as it was
+func (p Property) MarshalJSON() ([]byte, error) {
+	if p.Boolean != nil {
+		return json.Marshal(*p.Boolean)
+	}
+	type alias Property
+	aux := struct {
+		alias
+		Type  any `json:"type,omitempty"`
+		Items any `json:"items,omitempty"`
+	}{alias: alias(p)}
+	if len(p.Types) > 0 {
+		aux.Type = p.Types
+	} else if p.Type != "" {
+		aux.Type = p.Type
+	}
+	if p.TupleItems != nil {
+		aux.Items = p.TupleItems
+	} else if p.Items != nil {
+		aux.Items = p.Items
+	}
+	data, err := json.Marshal(aux)
+	if err != nil || len(p.Extra) == 0 {
+		return data, err
+	}
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return nil, err
+	}
+	for k, v := range p.Extra {
+		if _, ok := keywords[k]; !ok {
+			keywords[k] = v
+		}
+	}
+	return json.Marshal(keywords)
+}
+
+// UnmarshalJSON accepts union types, boolean schemas, legacy "definitions",
+// the array form of items, and schema-valued additionalProperties. Other
+// keywords are kept in Extra.
+func (p *Property) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+	if bytes.Equal(trimmed, []byte("true")) || bytes.Equal(trimmed, []byte("false")) {
+		allowed := trimmed[0] == 't'
+		*p = Property{Boolean: &allowed}
+		return nil
+	}
+
+	type alias Property
+	aux := struct {
+		*alias
+		Type                 json.RawMessage     `json:"type,omitempty"`
+		Items                json.RawMessage     `json:"items,omitempty"`
+		AdditionalProperties json.RawMessage     `json:"additionalProperties,omitempty"`
+		Definitions          map[string]Property `json:"definitions,omitempty"`
+	}{alias: (*alias)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var keywords map[string]json.RawMessage
+	if err := json.Unmarshal(data, &keywords); err != nil {
+		return err
+	}
+
+	if len(aux.Type) > 0 {
+		if aux.Type[0] == '[' {
+			if err := json.Unmarshal(aux.Type, &p.Types); err != nil {
+				return err
+			}
+		} else if err := json.Unmarshal(aux.Type, &p.Type); err != nil {
+			return err
+		}
+	}
+
+	if len(aux.Items) > 0 {
+		if aux.Items[0] == '[' {
+			if err := json.Unmarshal(aux.Items, &p.TupleItems); err != nil {
+				return err
+			}
+		} else {
+			var item Property
+			if err := json.Unmarshal(aux.Items, &item); err != nil {
+				return err
+			}
+			p.Items = &item
+		}
+	}
+
+	if len(aux.AdditionalProperties) > 0 {
+		var allowed bool
+		if err := json.Unmarshal(aux.AdditionalProperties, &allowed); err == nil {
+			p.AdditionalProperties = allowed
+		} else {
+			var schema Property
+			if err := json.Unmarshal(aux.AdditionalProperties, &schema); err != nil {
+				return err
+			}
+			p.AdditionalProperties = &schema
+		}
+	}
+
+	if p.Defs == nil && aux.Definitions != nil {
+		p.Defs = aux.Definitions
+		delete(keywords, "definitions")
+	}
+	for k, v := range keywords {
+		if !schemaKeywords[k] {
+			if p.Extra == nil {
+				p.Extra = make(map[string]json.RawMessage)
+			}
+			p.Extra[k] = v
+		}
+	}
+	return nil
+}
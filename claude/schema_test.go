@@ -0,0 +1,111 @@
+package claude
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProperty_RoundTrip(t *testing.T) {
+	// given - schema using keywords beyond type/description
+	raw := `{"type":"object","properties":{"mode":{"type":"string","enum":["a","b"],"default":"a"},"count":{"type":["integer","null"],"minimum":0},"tags":{"type":"array","items":{"$ref":"#/$defs/tag"}},"opt":{"anyOf":[{"type":"string"},{"type":"number"}]}},"required":["mode"],"additionalProperties":false,"$defs":{"tag":{"type":"string"}}}`
+
+	// when
+	var p Property
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	// then - semantically identical
+	var want, got any
+	json.Unmarshal([]byte(raw), &want)
+	json.Unmarshal(out, &got)
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("round trip mismatch:\nwant %s\ngot  %s", wantJSON, gotJSON)
+	}
+}
+
+func TestProperty_UnionType(t *testing.T) {
+	// given
+	var p Property
+
+	// when
+	err := json.Unmarshal([]byte(`{"type":["string","null"]}`), &p)
+
+	// then
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(p.TypeNames()) != 2 || p.TypeNames()[1] != "null" {
+		t.Errorf("expected [string null], got %v", p.TypeNames())
+	}
+}
+
+func TestProperty_AdditionalPropertiesSchema(t *testing.T) {
+	// given
+	var p Property
+
+	// when
+	err := json.Unmarshal([]byte(`{"type":"object","additionalProperties":{"type":"integer"}}`), &p)
+
+	// then
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	schema, allowed := p.AdditionalSchema()
+	if !allowed || schema == nil || schema.Type != "integer" {
+		t.Errorf("expected integer schema, got %v (allowed=%v)", schema, allowed)
+	}
+}
+
+func TestProperty_LegacyDefinitions(t *testing.T) {
+	// given
+	var p Property
+
+	// when
+	err := json.Unmarshal([]byte(`{"definitions":{"x":{"type":"string"}}}`), &p)
+
+	// then
+	if err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if p.Defs["x"].Type != "string" {
+		t.Errorf("expected definitions mapped to $defs, got %v", p.Defs)
+	}
+}
+
+func TestProperty_RoundTripKeepsUnknownKeywords(t *testing.T) {
+	// given - keywords Property has no field for, tuple items and boolean schemas
+	raw := `{"$schema":"https://json-schema.org/draft/2020-12/schema","type":"object","properties":{"point":{"type":"array","items":[{"type":"number"},{"type":"number"}],"additionalItems":false},"meta":{"type":"object","patternProperties":{"^x-":{"type":"string"}}},"mode":{"if":{"const":"a"},"then":{"minLength":1},"else":false,"examples":["a"]},"never":false,"any":true,"pair":{"prefixItems":[{"type":"string"}]}}}`
+
+	// when
+	var p Property
+	if err := json.Unmarshal([]byte(raw), &p); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	// then - semantically identical
+	var want, got any
+	json.Unmarshal([]byte(raw), &want)
+	json.Unmarshal(out, &got)
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("round trip mismatch:\nwant %s\ngot  %s", wantJSON, gotJSON)
+	}
+	if point := p.Properties["point"]; len(point.TupleItems) != 2 || point.Items != nil {
+		t.Errorf("expected tuple items, got %+v", point)
+	}
+	if never := p.Properties["never"]; never.Boolean == nil || *never.Boolean {
+		t.Errorf("expected the false schema kept, got %+v", never)
+	}
+}
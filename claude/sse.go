@@ -0,0 +1,83 @@
+package claude
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamError is an error event received mid-stream (after a 200 response),
+// e.g. overloaded_error when the API is under load
+type StreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+	Err     error  `json:"-"` // cause, e.g. io.ErrUnexpectedEOF for a cut-off stream
+}
+
+func (e *StreamError) Error() string {
+	return fmt.Sprintf("stream error: %s: %s", e.Type, e.Message)
+}
+
+func (e *StreamError) Unwrap() error { return e.Err }
+
+// Overloaded reports whether the server shed load and the request can be retried
+func (e *StreamError) Overloaded() bool {
+	return e.Type == "overloaded_error"
+}
+
+// Retryable reports whether sending the request again may succeed: the
+// server was overloaded or the connection dropped before message_stop
+func (e *StreamError) Retryable() bool {
+	return e.Overloaded() || errors.Is(e.Err, io.ErrUnexpectedEOF)
+}
+
+// readSSE parses a text/event-stream body, calling fn with the event name and
+// data of each dispatched event. Multi-line data fields are joined with "\n",
+// comment lines are skipped, and lines are not limited in length.
+func readSSE(r io.Reader, fn func(event, data string) error) error {
+	br := bufio.NewReaderSize(r, 64*1024)
+	var event string
+	var data []string
+	dispatch := func() error {
+		if data == nil {
+			event = ""
+			return nil
+		}
+		name, payload := event, strings.Join(data, "\n")
+		event, data = "", nil
+		return fn(name, payload)
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		eof := err != nil
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				return err
+			}
+		case line[0] == ':':
+			// comment / keep-alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				data = append(data, value)
+			}
+		}
+
+		if eof {
+			return dispatch()
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package claude
+
+import (
+	"strings"
+	"testing"
+)
+
+type sseRecord struct{ event, data string }
+
+func collectSSE(t *testing.T, body string) []sseRecord {
+	t.Helper()
+	var got []sseRecord
+	if err := readSSE(strings.NewReader(body), func(event, data string) error {
+		got = append(got, sseRecord{event, data})
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return got
+}
+
+func TestReadSSE_EventsAndMultiLineData(t *testing.T) {
+	// given
+	body := "event: ping\ndata: {}\n\n" +
+		": keep-alive comment\n\n" +
+		"event: message\r\ndata: line one\r\ndata:line two\r\n\r\n" +
+		"data: trailing without blank line"
+
+	// when
+	got := collectSSE(t, body)
+
+	// then
+	want := []sseRecord{
+		{"ping", "{}"},
+		{"message", "line one\nline two"},
+		{"", "trailing without blank line"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d events, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestReadSSE_LargeLine(t *testing.T) {
+	// given - a data line far beyond bufio.Scanner's 64KB default
+	big := strings.Repeat("x", 1<<20)
+
+	// when
+	got := collectSSE(t, "data: "+big+"\n\n")
+
+	// then
+	if len(got) != 1 || len(got[0].data) != len(big) {
+		t.Fatalf("expected one %d byte event, got %d events", len(big), len(got))
+	}
+}
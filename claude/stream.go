@@ -1,7 +1,6 @@
 package claude
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -146,66 +145,33 @@ func (ms *MessageStream) signalDone(err error) {
 // Event represents a stream event
 type Event struct {
 	Type string
-	Data sseEvent
+	Data StreamEvent
 }
 
-// Events returns a channel of raw SSE events (Go equivalent of async iterable)
+// Events returns a channel of raw SSE events (Go equivalent of async iterable).
+// Handlers still fire and the message is accumulated, so FinalText and
+// OnMessage work alongside the channel. The stream error arrives on Done().
 func (ms *MessageStream) Events() (<-chan Event, error) {
 	ch := make(chan Event, 100)
 
-	body, _ := json.Marshal(ms.params)
-	req, _ := http.NewRequestWithContext(ms.ctx, "POST", ms.service.client.BaseURL+"/v1/messages", strings.NewReader(string(body)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", ms.service.client.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	httpClient := &http.Client{Timeout: ms.service.client.Timeout}
-	resp, err := httpClient.Do(req)
+	resp, err := ms.service.client.do(ms.ctx, ms.params)
 	if err != nil {
 		close(ch)
+		ms.emit("error", err)
+		ms.signalDone(err)
 		return ch, err
 	}
 
-	if resp.StatusCode != 200 {
-		respBody, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		close(ch)
-		return ch, &APIError{Status: resp.StatusCode, Message: string(respBody)}
-	}
-
-	ms.response = resp
-
 	go func() {
 		defer close(ch)
-		defer resp.Body.Close()
-
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
+		ms.consume(resp, func(ev StreamEvent) error {
 			select {
 			case <-ms.ctx.Done():
-				ms.signalDone(ms.ctx.Err())
-				return
-			default:
+				return ms.ctx.Err()
+			case ch <- Event{Type: ev.Type, Data: ev}:
+				return nil
 			}
-
-			line := scanner.Text()
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
-			data := strings.TrimPrefix(line, "data: ")
-
-			var sse sseEvent
-			if err := json.Unmarshal([]byte(data), &sse); err != nil {
-				continue
-			}
-
-			ch <- Event{Type: sse.Type, Data: sse}
-		}
-		if err := scanner.Err(); err != nil {
-			ms.signalDone(err)
-			return
-		}
-		ms.signalDone(nil)
+		})
 	}()
 
 	return ch, nil
@@ -213,114 +179,140 @@ func (ms *MessageStream) Events() (<-chan Event, error) {
 
 // FinalMessage starts streaming and returns the complete message
 func (ms *MessageStream) FinalMessage() (*Message, error) {
-	body, _ := json.Marshal(ms.params)
-
-	req, _ := http.NewRequestWithContext(ms.ctx, "POST", ms.service.client.BaseURL+"/v1/messages", strings.NewReader(string(body)))
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", ms.service.client.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	httpClient := &http.Client{Timeout: ms.service.client.Timeout}
-	resp, err := httpClient.Do(req)
+	resp, err := ms.service.client.do(ms.ctx, ms.params)
 	if err != nil {
 		ms.emit("error", err)
 		ms.signalDone(err)
 		return nil, err
 	}
+	return ms.consume(resp, nil)
+}
+
+// consume decodes resp into ms.message, firing handlers and passing every
+// event to forward (when set). Both Events and FinalMessage end here.
+func (ms *MessageStream) consume(resp *http.Response, forward func(StreamEvent) error) (*Message, error) {
 	defer resp.Body.Close()
 	ms.response = resp
+	ms.message = &Message{Content: []ContentBlock{}}
+	acc := &accumulator{ms: ms, open: make(map[int]*openBlock)}
+
+	err := ms.service.client.provider().DecodeStream(resp.Body, func(event StreamEvent) error {
+		if err := ms.ctx.Err(); err != nil {
+			return err
+		}
+		acc.apply(event)
+		if forward != nil {
+			return forward(event)
+		}
+		return nil
+	})
 
-	if resp.StatusCode != 200 {
-		respBody, _ := io.ReadAll(resp.Body)
-		err := &APIError{Status: resp.StatusCode, Message: string(respBody)}
+	if ms.ctx.Err() != nil {
+		ms.signalDone(ms.ctx.Err())
+		return ms.message, ms.ctx.Err()
+	}
+	if err == nil && !acc.stopped {
+		err = &StreamError{Type: "incomplete_stream", Message: "stream ended before message_stop", Err: io.ErrUnexpectedEOF}
+	}
+	if err != nil {
 		ms.emit("error", err)
 		ms.signalDone(err)
 		return nil, err
 	}
 
-	ms.message = &Message{Content: []ContentBlock{}}
-	var currentBlock *ContentBlock
-	var toolInputJSON string
-
-	scanner := bufio.NewScanner(resp.Body)
-	for scanner.Scan() {
-		select {
-		case <-ms.ctx.Done():
-			ms.signalDone(ms.ctx.Err())
-			return ms.message, ms.ctx.Err()
-		default:
-		}
+	ms.emit("end", nil)
+	ms.signalDone(nil)
+	return ms.message, nil
+}
 
-		line := scanner.Text()
-		if !strings.HasPrefix(line, "data: ") {
-			continue
-		}
-		data := strings.TrimPrefix(line, "data: ")
+// openBlock is a content block still receiving deltas
+type openBlock struct {
+	block     ContentBlock
+	inputJSON strings.Builder
+}
 
-		var event sseEvent
-		if err := json.Unmarshal([]byte(data), &event); err != nil {
-			continue
-		}
+// accumulator builds a Message from stream events
+type accumulator struct {
+	ms      *MessageStream
+	open    map[int]*openBlock // by event index
+	stopped bool               // message_stop seen
+}
 
-		switch event.Type {
-		case "message_start":
-			if event.Message != nil {
-				ms.message.ID = event.Message.ID
-				ms.message.Model = event.Message.Model
-				ms.message.Role = event.Message.Role
-			}
-		case "content_block_start":
-			currentBlock = event.ContentBlock
-			toolInputJSON = ""
-			if currentBlock != nil {
-				ms.emit("contentBlockStart", *currentBlock)
-			}
-		case "content_block_delta":
-			if currentBlock != nil {
-				if event.Delta.Type == "text_delta" {
-					currentBlock.Text += event.Delta.Text
-					ms.finalText += event.Delta.Text
-					ms.emit("text", event.Delta.Text)
-				} else if event.Delta.Type == "input_json_delta" {
-					toolInputJSON += event.Delta.PartialJSON
-					ms.emit("inputJson", event.Delta.PartialJSON)
-				} else if event.Delta.Type == "thinking_delta" {
-					currentBlock.Thinking += event.Delta.Thinking
-					ms.emit("thinking", event.Delta.Thinking)
-				}
+func (a *accumulator) apply(event StreamEvent) {
+	ms, msg := a.ms, a.ms.message
+	switch event.Type {
+	case "message_start":
+		if event.Message != nil {
+			msg.ID = event.Message.ID
+			msg.Model = event.Message.Model
+			msg.Role = event.Message.Role
+			if event.Message.Usage != nil {
+				usage := *event.Message.Usage
+				msg.Usage = &usage
 			}
-		case "content_block_stop":
-			if currentBlock != nil {
-				if currentBlock.Type == "tool_use" && toolInputJSON != "" {
-					currentBlock.Input = json.RawMessage(toolInputJSON)
-				}
-				ms.emit("contentBlockStop", *currentBlock)
-				ms.emit("contentBlock", *currentBlock)
-				ms.message.Content = append(ms.message.Content, *currentBlock)
-				currentBlock = nil
+		}
+	case "content_block_start":
+		if event.ContentBlock == nil {
+			return
+		}
+		a.open[event.Index] = &openBlock{block: *event.ContentBlock}
+		ms.emit("contentBlockStart", *event.ContentBlock)
+	case "content_block_delta":
+		ob := a.open[event.Index]
+		if ob == nil {
+			return
+		}
+		switch d := event.Delta; d.Type {
+		case "text_delta":
+			ob.block.Text += d.Text
+			ms.finalText += d.Text
+			ms.emit("text", d.Text)
+		case "input_json_delta":
+			ob.inputJSON.WriteString(d.PartialJSON)
+			ms.emit("inputJson", d.PartialJSON)
+		case "thinking_delta":
+			ob.block.Thinking += d.Thinking
+			ms.emit("thinking", d.Thinking)
+		case "signature_delta":
+			ob.block.Signature += d.Signature
+		case "citations_delta":
+			if d.Citation != nil {
+				ob.block.Citations = append(ob.block.Citations, *d.Citation)
 			}
-		case "message_delta":
-			if event.Delta.StopReason != "" {
-				ms.message.StopReason = event.Delta.StopReason
+		}
+	case "content_block_stop":
+		ob := a.open[event.Index]
+		if ob == nil {
+			return
+		}
+		delete(a.open, event.Index)
+		if ob.block.Type == "tool_use" && ob.inputJSON.Len() > 0 {
+			ob.block.Input = json.RawMessage(ob.inputJSON.String())
+		}
+		ms.emit("contentBlockStop", ob.block)
+		ms.emit("contentBlock", ob.block)
+		msg.Content = append(msg.Content, ob.block)
+	case "message_delta":
+		if event.Delta.StopReason != "" {
+			msg.StopReason = event.Delta.StopReason
+		}
+		if event.Delta.ReasoningContent != "" {
+			msg.ReasoningContent += event.Delta.ReasoningContent
+			ms.emit("thinking", event.Delta.ReasoningContent)
+		}
+		if u := event.Usage; u != nil {
+			if msg.Usage == nil {
+				msg.Usage = &Usage{}
 			}
-			if event.Delta.ReasoningContent != "" {
-				ms.message.ReasoningContent += event.Delta.ReasoningContent
-				ms.emit("thinking", event.Delta.ReasoningContent)
+			if u.InputTokens > 0 {
+				msg.Usage.InputTokens = u.InputTokens
 			}
-		case "message_stop":
-			ms.emit("message", ms.message)
+			msg.Usage.OutputTokens = u.OutputTokens
 		}
+	case "message_stop":
+		a.stopped = true
+		ms.emit("message", msg)
 	}
-
-	if err := scanner.Err(); err != nil {
-		ms.emit("error", err)
-		ms.signalDone(err)
-		return nil, err
-	}
-
-	ms.emit("end", nil)
-	ms.signalDone(nil)
-	return ms.message, nil
 }
 
 // Response returns the raw HTTP response (call after FinalMessage)
@@ -328,22 +320,34 @@ func (ms *MessageStream) Response() *http.Response {
 	return ms.response
 }
 
-// SSE event types (internal)
-type sseEvent struct {
-	Type    string `json:"type"`
-	Index   int    `json:"index"`
-	Message *struct {
-		ID    string `json:"id"`
-		Model string `json:"model"`
-		Role  string `json:"role"`
-	} `json:"message"`
-	Delta struct {
-		Type             string `json:"type"`
-		Text             string `json:"text"`
-		PartialJSON      string `json:"partial_json"`
-		StopReason       string `json:"stop_reason"`
-		Thinking         string `json:"thinking"`
-		ReasoningContent string `json:"reasoning_content"` // GLM-4.7
-	} `json:"delta"`
-	ContentBlock *ContentBlock `json:"content_block"`
+// StreamEvent is one Anthropic-shaped stream event (other providers are
+// translated into this shape by their adapter)
+type StreamEvent struct {
+	Type         string         `json:"type"`
+	Index        int            `json:"index"`
+	Message      *StreamMessage `json:"message,omitempty"`
+	Delta        StreamDelta    `json:"delta"`
+	ContentBlock *ContentBlock  `json:"content_block,omitempty"`
+	Usage        *Usage         `json:"usage,omitempty"` // message_delta (cumulative)
+	Error        *StreamError   `json:"error,omitempty"` // error events
+}
+
+// StreamMessage is the message header carried by message_start
+type StreamMessage struct {
+	ID    string `json:"id"`
+	Model string `json:"model"`
+	Role  string `json:"role"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// StreamDelta carries content_block_delta and message_delta payloads
+type StreamDelta struct {
+	Type             string    `json:"type,omitempty"`
+	Text             string    `json:"text,omitempty"`
+	PartialJSON      string    `json:"partial_json,omitempty"`
+	StopReason       string    `json:"stop_reason,omitempty"`
+	Thinking         string    `json:"thinking,omitempty"`
+	ReasoningContent string    `json:"reasoning_content,omitempty"` // GLM-4.7
+	Signature        string    `json:"signature,omitempty"`
+	Citation         *Citation `json:"citation,omitempty"`
 }
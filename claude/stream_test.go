@@ -0,0 +1,124 @@
+package claude
+
+import (
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestMessageStream_SignatureCitationsAndUsage(t *testing.T) {
+	// given
+	srv := sseServer(t, "/v1/messages", []string{
+		`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_1","model":"m","role":"assistant","usage":{"input_tokens":120,"output_tokens":1}}}`,
+		`event: ping` + "\n" + `data: {"type":"ping"}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"hmm"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"EqQB"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":""}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"sky is blue","document_index":0,"start_char_index":4,"end_char_index":15}}}`,
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"It is blue."}}`,
+		`data: {"type":"content_block_stop","index":1}`,
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":42}}`,
+		`data: {"type":"message_stop"}`,
+	}, nil)
+	client := NewClient(WithBaseURL(srv.URL))
+
+	// when
+	msg, err := client.Messages.Stream(MessageCreateParams{Model: "m"}).FinalMessage()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(msg.Content) != 2 {
+		t.Fatalf("expected 2 blocks, got %+v", msg.Content)
+	}
+	if msg.Content[0].Thinking != "hmm" || msg.Content[0].Signature != "EqQB" {
+		t.Errorf("expected thinking with signature, got %+v", msg.Content[0])
+	}
+	if c := msg.Content[1].Citations; len(c) != 1 || c[0].CitedText != "sky is blue" || c[0].EndCharIndex != 15 {
+		t.Errorf("expected citation, got %+v", c)
+	}
+	if msg.Usage == nil || msg.Usage.InputTokens != 120 || msg.Usage.OutputTokens != 42 {
+		t.Errorf("expected usage 120/42, got %+v", msg.Usage)
+	}
+}
+
+func TestMessageStream_MidStreamError(t *testing.T) {
+	// given - the server gives up after a 200 response started
+	srv := sseServer(t, "/v1/messages", []string{
+		`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_1","model":"m","role":"assistant"}}`,
+		`event: error` + "\n" + `data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+	}, nil)
+	client := NewClient(WithBaseURL(srv.URL))
+
+	// when
+	_, err := client.Messages.Stream(MessageCreateParams{Model: "m"}).FinalMessage()
+
+	// then
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) {
+		t.Fatalf("expected *StreamError, got %v", err)
+	}
+	if !streamErr.Overloaded() || streamErr.Message != "Overloaded" {
+		t.Errorf("unexpected stream error: %+v", streamErr)
+	}
+}
+
+func TestMessageStream_EOFBeforeMessageStop(t *testing.T) {
+	// given - the connection drops mid-message
+	srv := sseServer(t, "/v1/messages", []string{
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"m","role":"assistant"}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hal"}}`,
+	}, nil)
+	client := NewClient(WithBaseURL(srv.URL))
+
+	// when
+	_, err := client.Messages.Stream(MessageCreateParams{Model: "m"}).FinalMessage()
+
+	// then
+	var streamErr *StreamError
+	if !errors.As(err, &streamErr) || !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected a StreamError wrapping io.ErrUnexpectedEOF, got %v", err)
+	}
+	if !streamErr.Retryable() {
+		t.Error("expected a cut-off stream to be retryable")
+	}
+}
+
+func TestMessageStream_EventsAccumulatesLikeFinalMessage(t *testing.T) {
+	// given
+	srv := sseServer(t, "/v1/messages", []string{
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"m","role":"assistant"}}`,
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text"}}`,
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hi"}}`,
+		`data: {"type":"content_block_stop","index":0}`,
+		`data: {"type":"message_stop"}`,
+	}, nil)
+	stream := NewClient(WithBaseURL(srv.URL)).Messages.Stream(MessageCreateParams{Model: "m"})
+	var final *Message
+	stream.OnMessage(func(m *Message) { final = m })
+
+	// when
+	events, err := stream.Events()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var types []string
+	for ev := range events {
+		types = append(types, ev.Type)
+	}
+
+	// then
+	if err := <-stream.Done(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if len(types) != 5 {
+		t.Errorf("expected 5 events, got %v", types)
+	}
+	if final == nil || len(final.Content) != 1 || stream.FinalText() != "hi" {
+		t.Errorf("expected accumulated message, got %+v", final)
+	}
+}
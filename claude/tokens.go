@@ -0,0 +1,142 @@
+package claude
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// TokenCount is the size of a request's input
+type TokenCount struct {
+	InputTokens int  `json:"input_tokens"`
+	Estimated   bool `json:"-"` // true when computed by EstimateTokens
+}
+
+// TokenCounter is implemented by providers whose servers support
+// /v1/messages/count_tokens
+type TokenCounter interface {
+	CountTokensRequest(ctx context.Context, c *Client, params MessageCreateParams) (*http.Request, error)
+}
+
+// countTokensParams is the count_tokens body (no max_tokens or stream)
+type countTokensParams struct {
+	Model      string          `json:"model"`
+	Messages   []MessageParam  `json:"messages"`
+	System     string          `json:"system,omitempty"`
+	Tools      []Tool          `json:"tools,omitempty"`
+	ToolChoice *ToolChoice     `json:"tool_choice,omitempty"`
+	Thinking   *ThinkingConfig `json:"thinking,omitempty"`
+}
+
+func (AnthropicProvider) CountTokensRequest(ctx context.Context, c *Client, params MessageCreateParams) (*http.Request, error) {
+	body, err := json.Marshal(countTokensParams{
+		Model:      params.Model,
+		Messages:   params.Messages,
+		System:     params.System,
+		Tools:      params.Tools,
+		ToolChoice: params.ToolChoice,
+		Thinking:   params.Thinking,
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", joinURL(c.BaseURL, "/v1/messages/count_tokens"), strings.NewReader(string(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, nil
+}
+
+// CountTokens asks the server for the input size of params, falling back to
+// EstimateTokens when the provider or server does not support counting.
+// Servers without the endpoint are not asked again.
+func (s *MessagesService) CountTokens(params MessageCreateParams) (*TokenCount, error) {
+	counter, ok := s.client.provider().(TokenCounter)
+	if !ok || s.countUnsupported {
+		return &TokenCount{InputTokens: EstimateTokens(params), Estimated: true}, nil
+	}
+
+	req, err := counter.CountTokensRequest(context.Background(), s.client, params)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.httpClient().Do(req)
+	if err != nil {
+		return &TokenCount{InputTokens: EstimateTokens(params), Estimated: true}, nil
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var count TokenCount
+	if resp.StatusCode != 200 || json.Unmarshal(body, &count) != nil || count.InputTokens == 0 {
+		if countingUnsupported(resp.StatusCode, body) {
+			s.countUnsupported = true
+		}
+		return &TokenCount{InputTokens: EstimateTokens(params), Estimated: true}, nil
+	}
+	return &count, nil
+}
+
+// countingUnsupported reports whether a failed count_tokens response means
+// the server has no such endpoint, rather than that this request was bad
+func countingUnsupported(status int, body []byte) bool {
+	if status == http.StatusNotFound || status == http.StatusMethodNotAllowed {
+		return true
+	}
+	msg := strings.ToLower(string(body))
+	return status == http.StatusBadRequest && (strings.Contains(msg, "not supported") || strings.Contains(msg, "unsupported"))
+}
+
+// Heuristic overheads for EstimateTokens
+const (
+	messageOverheadTokens = 4  // role and framing per message
+	toolOverheadTokens    = 16 // per tool definition wrapper
+)
+
+// EstimateTokens approximates the input tokens of params without a tokenizer:
+// ~4 bytes per token for ASCII text, one token per non-ASCII rune
+func EstimateTokens(params MessageCreateParams) int {
+	n := estimateText(params.System)
+	for _, m := range params.Messages {
+		n += messageOverheadTokens
+		for _, b := range ContentBlocks(m.Content) {
+			n += estimateText(b.Text) + estimateText(b.Thinking) + estimateText(string(b.Input))
+			if b.Type == "tool_result" {
+				n += estimateText(b.ResultText())
+			}
+			if b.Name != "" {
+				n += estimateText(b.Name)
+			}
+		}
+	}
+	for _, t := range params.Tools {
+		schema, _ := json.Marshal(t.InputSchema)
+		n += toolOverheadTokens + estimateText(t.Name) + estimateText(t.Description) + estimateText(string(schema))
+	}
+	return n
+}
+
+// estimateText approximates the token count of s
+func estimateText(s string) int {
+	if s == "" {
+		return 0
+	}
+	ascii := 0
+	other := 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+		i += size
+	}
+	return (ascii+3)/4 + other
+}
@@ -0,0 +1,142 @@
+package claude
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEstimateTokens_GrowsWithContent(t *testing.T) {
+	// given
+	small := MessageCreateParams{Messages: []MessageParam{{Role: "user", Content: "hello"}}}
+	large := MessageCreateParams{
+		System:   strings.Repeat("x", 4000),
+		Messages: []MessageParam{{Role: "user", Content: "hello"}},
+		Tools:    []Tool{{Name: "ReadFile", Description: "Read a file", InputSchema: InputSchema{Type: "object"}}},
+	}
+
+	// when
+	s, l := EstimateTokens(small), EstimateTokens(large)
+
+	// then
+	if s <= 0 || l < s+1000 {
+		t.Errorf("expected estimates to scale with content, got small=%d large=%d", s, l)
+	}
+}
+
+func TestEstimateTokens_ToolResults(t *testing.T) {
+	// given
+	params := MessageCreateParams{Messages: []MessageParam{
+		{Role: "user", Content: []ToolResultBlock{{Type: "tool_result", ToolUseID: "t1", Content: strings.Repeat("a", 400)}}},
+	}}
+
+	// when
+	n := EstimateTokens(params)
+
+	// then
+	if n < 100 {
+		t.Errorf("expected tool result counted (~100 tokens), got %d", n)
+	}
+}
+
+func TestCountTokens_Server(t *testing.T) {
+	// given
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/messages/count_tokens" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"input_tokens":1234}`))
+	}))
+	defer srv.Close()
+	client := NewClient(WithBaseURL(srv.URL))
+
+	// when
+	count, err := client.Messages.CountTokens(MessageCreateParams{Model: "m"})
+
+	// then
+	if err != nil || count.InputTokens != 1234 || count.Estimated {
+		t.Errorf("expected exact 1234, got %+v (%v)", count, err)
+	}
+}
+
+func TestCountTokens_FallbackWhenUnsupported(t *testing.T) {
+	// given - server without count_tokens
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+	client := NewClient(WithBaseURL(srv.URL))
+	params := MessageCreateParams{Messages: []MessageParam{{Role: "user", Content: "hello world"}}}
+
+	// when
+	first, _ := client.Messages.CountTokens(params)
+	second, _ := client.Messages.CountTokens(params)
+
+	// then
+	if !first.Estimated || !second.Estimated || first.InputTokens == 0 {
+		t.Errorf("expected estimates, got %+v %+v", first, second)
+	}
+	if calls != 1 {
+		t.Errorf("expected server asked once, got %d", calls)
+	}
+}
+
+func TestCountTokens_BadRequestKeepsCounting(t *testing.T) {
+	// given - a request the server rejects, then a good one
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, `{"type":"error","error":{"type":"invalid_request_error","message":"messages: roles must alternate"}}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"input_tokens":42}`))
+	}))
+	defer srv.Close()
+	client := NewClient(WithBaseURL(srv.URL))
+	params := MessageCreateParams{Messages: []MessageParam{{Role: "user", Content: "hello world"}}}
+
+	// when
+	first, _ := client.Messages.CountTokens(params)
+	second, _ := client.Messages.CountTokens(params)
+
+	// then
+	if !first.Estimated || second.Estimated || second.InputTokens != 42 {
+		t.Errorf("expected an estimate then an exact count, got %+v %+v", first, second)
+	}
+}
+
+func TestCountingUnsupported(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   bool
+	}{
+		{404, "", true},
+		{405, "", true},
+		{400, `{"error":{"message":"count_tokens is not supported for this model"}}`, true},
+		{400, `{"error":{"message":"messages: roles must alternate"}}`, false},
+		{500, "", false},
+	}
+	for _, tt := range tests {
+		if got := countingUnsupported(tt.status, []byte(tt.body)); got != tt.want {
+			t.Errorf("%d %s: expected %v, got %v", tt.status, tt.body, tt.want, got)
+		}
+	}
+}
+
+func TestCountTokens_OpenAIUsesEstimate(t *testing.T) {
+	// given - provider without count support, unreachable server
+	client := NewClient(WithBaseURL("http://127.0.0.1:1"), WithProvider(OpenAIProvider{}))
+
+	// when
+	count, err := client.Messages.CountTokens(MessageCreateParams{System: "be brief"})
+
+	// then
+	if err != nil || !count.Estimated {
+		t.Errorf("expected local estimate, got %+v (%v)", count, err)
+	}
+}
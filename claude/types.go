@@ -30,6 +30,27 @@ type ContentBlock struct {
 	Input     json.RawMessage `json:"input,omitempty"`
 	Thinking  string          `json:"thinking,omitempty"`
 	Signature string          `json:"signature,omitempty"`
+	Data      string          `json:"data,omitempty"` // redacted_thinking payload
+	Citations []Citation      `json:"citations,omitempty"`
+}
+
+// Citation points text at a source; which location fields are set depends on
+// Type (char_location, page_location, content_block_location,
+// web_search_result_location)
+type Citation struct {
+	Type            string `json:"type"`
+	CitedText       string `json:"cited_text,omitempty"`
+	DocumentIndex   int    `json:"document_index,omitempty"`
+	DocumentTitle   string `json:"document_title,omitempty"`
+	StartCharIndex  int    `json:"start_char_index,omitempty"`
+	EndCharIndex    int    `json:"end_char_index,omitempty"`
+	StartPageNumber int    `json:"start_page_number,omitempty"`
+	EndPageNumber   int    `json:"end_page_number,omitempty"`
+	StartBlockIndex int    `json:"start_block_index,omitempty"`
+	EndBlockIndex   int    `json:"end_block_index,omitempty"`
+	URL             string `json:"url,omitempty"`
+	Title           string `json:"title,omitempty"`
+	EncryptedIndex  string `json:"encrypted_index,omitempty"`
 }
 
 type MessageParam struct {
@@ -50,33 +71,72 @@ type Tool struct {
 	InputSchema InputSchema `json:"input_schema"`
 }
 
-type InputSchema struct {
-	Type       string              `json:"type"`
-	Properties map[string]Property `json:"properties"`
-	Required   []string            `json:"required"`
-}
-
-type Property struct {
-	Type        string              `json:"type"`
-	Description string              `json:"description"`
-	Items       *Property           `json:"items,omitempty"`
-	Properties  map[string]Property `json:"properties,omitempty"`
-}
-
 // ThinkingConfig controls thinking/reasoning mode
 type ThinkingConfig struct {
-	Type string `json:"type"` // "enabled" or "disabled"
+	Type         string `json:"type"`                    // "enabled" or "disabled"
+	BudgetTokens int    `json:"budget_tokens,omitempty"` // must be >= 1024 and < max_tokens
 }
 
 // MessageCreateParams for API request
 type MessageCreateParams struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	Messages  []MessageParam  `json:"messages"`
-	System    string          `json:"system,omitempty"`
-	Tools     []Tool          `json:"tools,omitempty"`
-	Stream    bool            `json:"stream,omitempty"`
-	Thinking  *ThinkingConfig `json:"thinking,omitempty"`
+	Model      string          `json:"model"`
+	MaxTokens  int             `json:"max_tokens"`
+	Messages   []MessageParam  `json:"messages"`
+	System     string          `json:"system,omitempty"`
+	Tools      []Tool          `json:"tools,omitempty"`
+	ToolChoice *ToolChoice     `json:"tool_choice,omitempty"`
+	Stream     bool            `json:"stream,omitempty"`
+	Thinking   *ThinkingConfig `json:"thinking,omitempty"`
+	Metadata   *Metadata       `json:"metadata,omitempty"`
+	Sampling
+}
+
+// Tool choice types
+const (
+	ToolChoiceAuto = "auto" // model decides (default)
+	ToolChoiceAny  = "any"  // must call some tool
+	ToolChoiceTool = "tool" // must call ToolChoice.Name
+	ToolChoiceNone = "none" // must not call tools
+)
+
+// ToolChoice constrains which tools the model may call. Extended thinking
+// only allows auto and none.
+type ToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+// ForceTool returns a tool choice requiring a call to the named tool
+func ForceTool(name string) *ToolChoice {
+	return &ToolChoice{Type: ToolChoiceTool, Name: name}
+}
+
+// Metadata describes the request for abuse detection
+type Metadata struct {
+	UserID string `json:"user_id,omitempty"` // opaque id, never PII
+}
+
+// Sampling holds optional generation settings (nil = server default)
+type Sampling struct {
+	Temperature   *float64 `json:"temperature,omitempty"`
+	TopP          *float64 `json:"top_p,omitempty"`
+	TopK          *int     `json:"top_k,omitempty"`
+	StopSequences []string `json:"stop_sequences,omitempty"`
+}
+
+// minThinkingTopP is the lowest top_p accepted alongside extended thinking
+const minThinkingTopP = 0.95
+
+// WithThinking drops settings the API rejects when thinking is enabled:
+// temperature and top_k, and top_p below 0.95
+func (s Sampling) WithThinking() Sampling {
+	s.Temperature = nil
+	s.TopK = nil
+	if s.TopP != nil && *s.TopP < minThinkingTopP {
+		s.TopP = nil
+	}
+	return s
 }
 
 // MessageResponse wraps Message with raw response access
@@ -3,38 +3,187 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
-	"simpleagent/claude"
 	"simpleagent/tools"
 )
 
+// CLIOptions are the parsed flags for commands that run instead of the agent
+type CLIOptions struct {
+	List   bool    // -sessions
+	All    bool    // -all: every project, not just the current one
+	Delete string  // -delete <id>
+	Export string  // -export <id>
+	Format string  // -format md|html|json, for -export
+	Search string  // -search <query>
+	Prune  bool    // -prune: apply the retention policy
+	DryRun bool    // -dry-run, for -prune
+	Pin    string  // -pin <id>
+	Unpin  string  // -unpin <id>
+	Replay string  // -replay <id>
+	Pace   float64 // -pace, for -replay: 0 steps on enter, 1 is real time
+}
+
 // RunCLI handles CLI flag dispatch (list/delete sessions or continue to agent)
 // Returns (shouldContinue, error)
-func RunCLI(listFlag *bool, deleteFlag, resumeFlag *string) (bool, error) {
+func RunCLI(opts CLIOptions) (bool, error) {
 	switch {
-	case *listFlag:
-		listSessions()
+	case opts.List:
+		listSessions(opts.All)
+		return false, nil
+	case opts.Delete != "":
+		deleteSession(opts.Delete)
 		return false, nil
-	case *deleteFlag != "":
-		deleteSession(*deleteFlag)
+	case opts.Export != "":
+		sess, err := loadSession(opts.Export)
+		if err != nil {
+			return false, fmt.Errorf("loading session: %w", err)
+		}
+		return false, exportSession(os.Stdout, sess, opts.Format)
+	case opts.Search != "":
+		hits, err := searchSessions(opts.Search)
+		if err != nil {
+			return false, err
+		}
+		printSearchResults(os.Stdout, opts.Search, hits, time.Now())
 		return false, nil
+	case opts.Pin != "" || opts.Unpin != "":
+		id, pinned := opts.Pin, true
+		if id == "" {
+			id, pinned = opts.Unpin, false
+		}
+		if err := setPinned(id, pinned); err != nil {
+			return false, err
+		}
+		if pinned {
+			fmt.Println(tools.Success("pinned " + id))
+		} else {
+			fmt.Println(tools.Success("unpinned " + id))
+		}
+		return false, nil
+	case opts.Prune:
+		config, _, err := LoadConfig()
+		if err != nil {
+			return false, fmt.Errorf("loading config: %w", err)
+		}
+		return false, pruneSessions(os.Stdout, config.Retention, opts.DryRun)
+	case opts.Replay != "":
+		sess, err := loadSession(opts.Replay)
+		if err != nil {
+			return false, fmt.Errorf("loading session: %w", err)
+		}
+		display := thinkingShow
+		if config, _, err := LoadConfig(); err == nil && validThinkingDisplay(config.ThinkingDisplay) {
+			display = config.ThinkingDisplay
+		}
+		return false, playReplay(sess, bufio.NewReader(os.Stdin), os.Stdout, opts.Pace, display)
 	}
 	return true, nil
 }
 
-// AgentSession orchestrates agent session (config → MCP → tools → agent loop)
-func AgentSession(resumeFlag *string) error {
-	client := claude.NewClient(claude.WithBaseURL(baseURL))
+// SessionChoice says which session AgentSession opens; the zero value starts
+// a new one
+type SessionChoice struct {
+	ID       string // resume this session
+	Pick     bool   // choose interactively
+	Continue bool   // most recent session of the current project
+	All      bool   // pick from every project
+	Fork     string // continue in a copy of this session
+	ForkAt   int    // messages of Fork to keep (negative: all)
+}
+
+// resolveSession turns a SessionChoice into a session ID ("" for new)
+func resolveSession(choice SessionChoice, reader *bufio.Reader) (string, error) {
+	if choice.ID != "" {
+		return choice.ID, nil
+	}
+	if choice.Fork != "" {
+		fork, err := createFork(choice.Fork, choice.ForkAt)
+		if err != nil {
+			return "", err
+		}
+		fmt.Println(tools.Status("forked") + " " + tools.Dim(fmt.Sprintf("%s at message %d", choice.Fork, fork.Meta.ForkedAt)))
+		return fork.Meta.ID, nil
+	}
+	if !choice.Pick && !choice.Continue {
+		return "", nil
+	}
+	sessions, err := loadSessions()
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("reading sessions: %w", err)
+	}
+	project := currentWorkspace().Project()
+	if !choice.All {
+		sessions = projectSessions(sessions, project)
+	}
+	if choice.Pick {
+		if len(sessions) == 0 && !choice.All {
+			return "", fmt.Errorf("no sessions in %s (use -all to pick from every project)", project)
+		}
+		return pickSession(reader, os.Stdout, sessions, time.Now())
+	}
+	if len(sessions) == 0 {
+		return "", fmt.Errorf("no session to continue in %s", project)
+	}
+	return sessions[0].Meta.ID, nil
+}
+
+// claimSession locks session id for this process. When another process has
+// it open the user picks: fork it, open it read-only, or quit.
+func claimSession(id string, r *bufio.Reader, w io.Writer) (string, *sessionLock, bool, error) {
+	lock, err := lockSession(id)
+	if !errors.Is(err, errSessionLocked) {
+		return id, lock, false, err
+	}
+	fmt.Fprintln(w, tools.Warning(fmt.Sprintf("%s: %v", id, err)))
+	for {
+		fmt.Fprint(w, tools.Dim("[f]ork it, open [r]ead-only or [q]uit: "))
+		line, readErr := r.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "f", "fork":
+			fork, err := createFork(id, -1)
+			if err != nil {
+				return "", nil, false, err
+			}
+			lock, err := lockSession(fork.Meta.ID)
+			if err != nil {
+				return "", nil, false, err
+			}
+			fmt.Fprintln(w, tools.Status("forked")+" "+tools.Dim(fmt.Sprintf("%s → %s", id, fork.Meta.ID)))
+			return fork.Meta.ID, lock, false, nil
+		case "r", "read-only":
+			return id, nil, true, nil
+		case "q", "quit":
+			return "", nil, false, errPickerCanceled
+		}
+		if readErr != nil {
+			return "", nil, false, errPickerCanceled
+		}
+	}
+}
+
+// AgentSession orchestrates agent session (config → profile → MCP → tools → agent loop)
+func AgentSession(choice SessionChoice, profileFlag *string) error {
 	reader := bufio.NewReader(os.Stdin)
-	var sessionID string
 	var sess *SessionFile
 
 	// Load or create session
-	if *resumeFlag != "" {
-		var err error
-		sess, err = loadSession(*resumeFlag)
+	sessionID, err := resolveSession(choice, reader)
+	if err != nil {
+		return err
+	}
+	var lock *sessionLock
+	readOnly := false
+	if sessionID != "" {
+		if sessionID, lock, readOnly, err = claimSession(sessionID, reader, os.Stdout); err != nil {
+			return err
+		}
+		sess, err = loadSession(sessionID)
 		if err != nil {
 			return fmt.Errorf("loading session: %w", err)
 		}
@@ -51,8 +200,14 @@ func AgentSession(resumeFlag *string) error {
 		if permissionsMode == "accept_all" {
 			fmt.Println(tools.Warning("accept-all permissions"))
 		}
+		if readOnly {
+			fmt.Println(tools.Warning("read-only") + " " + tools.Dim("nothing is saved; /fork to keep this conversation"))
+		}
 	} else {
 		sessionID = newSessionID()
+		if lock, err = lockSession(sessionID); err != nil {
+			return fmt.Errorf("locking session: %w", err)
+		}
 		fmt.Println(tools.Status("new session") + " " + tools.Dim(sessionID))
 	}
 
@@ -62,7 +217,38 @@ func AgentSession(resumeFlag *string) error {
 	// Build system prompt and config
 	systemPrompt, loadedFiles, config, err := BuildSystemPrompt()
 	if err != nil {
-		fmt.Println(tools.Error(fmt.Sprintf("loading config: %v", err)))
+		printErrors(err)
+		fmt.Println(tools.Dim("run agent doctor for details"))
+	}
+	for _, problem := range config.Problems() {
+		fmt.Println(tools.Warning(problem))
+	}
+
+	// Resolve profiles (flag, then resumed session's profile, then default)
+	profileName := *profileFlag
+	if profileName == "" && sess != nil {
+		if _, err := config.ResolveProfile(sess.Meta.Profile); err == nil {
+			profileName = sess.Meta.Profile
+		}
+	}
+	profile, err := config.ResolveProfile(profileName)
+	if err != nil {
+		return err
+	}
+	client, err := profile.NewClient()
+	if err != nil {
+		return err
+	}
+	fmt.Println(tools.Status("model") + " " + tools.Dim(profile.Name+" · "+profile.Model))
+
+	subagentProfile, subagentClient := profile, client
+	if config.SubagentProfile != "" {
+		if subagentProfile, err = config.ResolveProfile(config.SubagentProfile); err != nil {
+			return fmt.Errorf("subagent profile: %w", err)
+		}
+		if subagentClient, err = subagentProfile.NewClient(); err != nil {
+			return fmt.Errorf("subagent profile: %w", err)
+		}
 	}
 
 	// Setup MCP clients
@@ -81,38 +267,61 @@ func AgentSession(resumeFlag *string) error {
 	permissionsMode := "prompt"
 	if sess != nil && sess.PermissionsMode != "" {
 		permissionsMode = sess.PermissionsMode
+	} else if sess == nil && config.PermissionsMode == "accept_all" {
+		permissionsMode = config.PermissionsMode
+		fmt.Println(tools.Warning("accept-all permissions"))
 	}
+	subagentMaxTokens, subagentBudget := requestLimits(subagentProfile.MaxTokens, subagentProfile.ThinkingBudget)
 	tools.Init(tools.Config{
 		MCPClients:      mcpClients,
 		PermissionsMode: permissionsMode,
 		RuleMatcher:     GetMatchingRules,
+		MemoryLoader:    GetNestedMemory,
 		SkillLoader:     makeSkillLoader(),
 		Todos:           &sessionTodos,
 		Subagent: &tools.SubagentConfig{
-			Client:       client,
-			Model:        model,
-			SystemPrompt: systemPrompt,
+			Client:         subagentClient,
+			Model:          subagentProfile.Model,
+			SystemPrompt:   systemPrompt,
+			Sampling:       subagentProfile.Sampling,
+			MaxTokens:      subagentMaxTokens,
+			ThinkingBudget: subagentBudget,
 		},
 	})
 
 	// Create agent
-	agent, err := NewAgent(sessionID, sess, client, reader, systemPrompt, model, mcpClients, &sessionTodos)
+	agent, err := NewAgent(sessionID, sess, client, reader, systemPrompt, profile.Model, mcpClients, &sessionTodos)
 	if err != nil {
 		return fmt.Errorf("creating agent: %w", err)
 	}
+	agent.config = config
+	agent.permissionsMode = permissionsMode
+	agent.lock, agent.readOnly = lock, readOnly
+	defer func() { agent.lock.Release() }() // /fork swaps in the fork's lock
+	agent.ApplyProfile(profile, client)
+	agent.watcher = newPromptWatcher()
+	if validThinkingDisplay(config.ThinkingDisplay) {
+		agent.thinkingDisplay = config.ThinkingDisplay
+	}
 
 	// Main loop
 	for {
-		fmt.Print(tools.Prompt())
+		fmt.Print(agent.promptPrefix() + tools.Prompt())
 		input := readMultiLine(reader)
 		if input == "" {
 			continue
 		}
 
+		// Pick up memory, rule and skill edits made since the last turn
+		if err := agent.reloadChanged(); err != nil {
+			printErrors(err)
+		}
+
 		// Handle input and check if we should infer
 		shouldInfer, err := agent.HandleInput(input)
 		if err != nil {
-			return err
+			fmt.Println(tools.Error(err.Error()))
+			continue
 		}
 
 		// Print status for special commands
@@ -122,6 +331,30 @@ func AgentSession(resumeFlag *string) error {
 			} else {
 				fmt.Println(tools.Status("plan mode off") + " " + tools.Dim("full access"))
 			}
+		} else if strings.HasPrefix(input, "/plan ") {
+			fmt.Println(tools.Plan("plan mode") + " " + tools.Dim("plan requested"))
+		} else if strings.HasPrefix(input, "/model") {
+			printProfiles(agent)
+		} else if strings.HasPrefix(input, "/thinking") {
+			fmt.Println(tools.Status("thinking") + " " + tools.Dim(agent.thinkingDisplay))
+		} else if strings.HasPrefix(input, "/fork") {
+			fmt.Println(tools.Status("forked") + " " + tools.Dim(fmt.Sprintf("%s → %s at message %d", agent.parent, agent.sessionID, agent.forkedAt)))
+		} else if after, ok := strings.CutPrefix(input, "/search"); ok {
+			if hits, err := searchSessions(after); err != nil {
+				fmt.Println(tools.Error(err.Error()))
+			} else {
+				printSearchResults(os.Stdout, strings.TrimSpace(after), hits, time.Now())
+			}
+		} else if after, ok := strings.CutPrefix(input, "/config"); ok {
+			lc, err := LoadLayeredConfig()
+			if lc != nil {
+				printConfig(os.Stdout, lc, strings.TrimSpace(after))
+			}
+			if err != nil {
+				printErrors(err)
+			}
+		} else if input == "/think" || strings.HasPrefix(input, "/think ") {
+			fmt.Println(tools.Status("think") + " " + tools.Dim(fmt.Sprintf("budget %d tokens for next turn", agent.turnBudget)))
 		} else if input[0] == '!' {
 			// Bash commands already executed in HandleInput, output already printed
 		}
@@ -137,6 +370,10 @@ func AgentSession(resumeFlag *string) error {
 		if err := agent.RunInferenceTurn(); err != nil {
 			fmt.Printf("\n%s\n", tools.Error(err.Error()))
 		}
+		agent.ensureTitle()
+		if agent.shouldCompact() {
+			fmt.Println(tools.Warning(fmt.Sprintf("context %d%% full, consider starting a new session", agent.contextPercent())))
+		}
 
 		// Save session
 		if err := agent.Save(); err != nil {
@@ -144,3 +381,22 @@ func AgentSession(resumeFlag *string) error {
 		}
 	}
 }
+
+// printErrors prints each line of err as an error
+func printErrors(err error) {
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Println(tools.Error(line))
+	}
+}
+
+// printProfiles shows the active profile and the configured alternatives
+func printProfiles(agent *Agent) {
+	fmt.Println(tools.Status("model") + " " + tools.Dim(agent.profile+" · "+agent.model))
+	for _, name := range agent.config.ProfileNames() {
+		marker := "  "
+		if name == agent.profile {
+			marker = "▸ "
+		}
+		fmt.Println(tools.Dim(marker + name + " (" + agent.config.Profiles[name].Model + ")"))
+	}
+}
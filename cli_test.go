@@ -11,10 +11,9 @@ func TestRunCLI_ListSessions(t *testing.T) {
 	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
 	listFlag := flag.Bool("sessions", true, "")
 	deleteFlag := flag.String("delete", "", "")
-	resumeFlag := flag.String("resume", "", "")
 
 	// when
-	shouldContinue, err := RunCLI(listFlag, deleteFlag, resumeFlag)
+	shouldContinue, err := RunCLI(CLIOptions{List: *listFlag, Delete: *deleteFlag})
 
 	// then
 	if err != nil {
@@ -34,10 +33,9 @@ func TestRunCLI_DeleteSession(t *testing.T) {
 	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
 	listFlag := flag.Bool("sessions", false, "")
 	deleteFlag := flag.String("delete", "test-id", "")
-	resumeFlag := flag.String("resume", "", "")
 
 	// when
-	shouldContinue, err := RunCLI(listFlag, deleteFlag, resumeFlag)
+	shouldContinue, err := RunCLI(CLIOptions{List: *listFlag, Delete: *deleteFlag})
 
 	// then
 	if err != nil {
@@ -57,10 +55,9 @@ func TestRunCLI_Continue(t *testing.T) {
 	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
 	listFlag := flag.Bool("sessions", false, "")
 	deleteFlag := flag.String("delete", "", "")
-	resumeFlag := flag.String("resume", "", "")
 
 	// when
-	shouldContinue, err := RunCLI(listFlag, deleteFlag, resumeFlag)
+	shouldContinue, err := RunCLI(CLIOptions{List: *listFlag, Delete: *deleteFlag})
 
 	// then
 	if err != nil {
@@ -73,3 +70,45 @@ func TestRunCLI_Continue(t *testing.T) {
 	// cleanup
 	flag.CommandLine = oldArgs
 }
+
+func TestOptionalString(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantSet     bool
+		wantVal     string
+		wantProfile string
+		wantErr     bool
+	}{
+		{"absent", nil, false, "", "", false},
+		{"bare", []string{"-resume"}, true, "", "", false},
+		{"with equals", []string{"-resume=abc"}, true, "abc", "", false},
+		{"bare before other flag", []string{"-resume", "-sessions"}, true, "", "", false},
+		{"id then other flags", []string{"-resume", "abc", "-profile", "x", "-sessions"}, true, "abc", "x", false},
+		{"leftover argument", []string{"-resume", "abc", "def"}, true, "abc", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			var resume optionalString
+			fs.Var(&resume, "resume", "")
+			fs.Bool("sessions", false, "")
+			profile := fs.String("profile", "", "")
+
+			// when
+			err := parseFlags(fs, &resume, tt.args)
+
+			// then
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if *profile != tt.wantProfile {
+				t.Errorf("expected profile %q, got %q", tt.wantProfile, *profile)
+			}
+			if resume.set != tt.wantSet || resume.value != tt.wantVal {
+				t.Errorf("expected set=%v value=%q, got set=%v value=%q", tt.wantSet, tt.wantVal, resume.set, resume.value)
+			}
+		})
+	}
+}
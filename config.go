@@ -1,11 +1,13 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"simpleagent/claude"
 	"simpleagent/tools"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -36,8 +38,84 @@ Todo tracking (use todo_write for multi-step tasks):
 </system-instructions>`
 
 type Config struct {
-	MemoryFiles []string               `json:"memory_files"`
-	MCPServers  []tools.MCPServerConfig `json:"mcp_servers"`
+	MemoryFiles     []string                `json:"memory_files"`
+	MCPServers      []tools.MCPServerConfig `json:"mcp_servers"`
+	Models          map[string]ModelConfig  `json:"models,omitempty"`           // keyed by model name
+	ThinkingDisplay string                  `json:"thinking_display,omitempty"` // "show", "collapse" or "hide"
+	Profiles        map[string]Profile      `json:"profiles,omitempty"`
+	DefaultProfile  string                  `json:"default_profile,omitempty"`
+	SubagentProfile string                  `json:"subagent_profile,omitempty"` // "" = same as main agent
+	MetadataUserID  string                  `json:"metadata_user_id,omitempty"` // sent as metadata.user_id
+	Retention       RetentionPolicy         `json:"retention"`                  // what -prune keeps
+	PermissionsMode string                  `json:"permissions_mode,omitempty"` // new sessions: "prompt" (default) or "accept_all"
+
+	ignored []string // settings dropped from a scope that may not set them
+}
+
+// ModelConfig holds per-model request limits, sampling and wire format
+type ModelConfig struct {
+	Provider       string `json:"provider,omitempty"` // "anthropic" (default), "openai" or "openai-responses"
+	MaxTokens      int    `json:"max_tokens,omitempty"`
+	ThinkingBudget int    `json:"thinking_budget,omitempty"` // 0 = let the server decide
+	ContextWindow  int    `json:"context_window,omitempty"`  // input + output token limit
+	// MaxOutputTokens caps max_tokens when retrying cut-off tool calls
+	// (0 = the known limit for the model, if any)
+	MaxOutputTokens int `json:"max_output_tokens,omitempty"`
+	// MaxContinuations caps max_tokens recoveries per turn (0 = default, negative = off)
+	MaxContinuations int `json:"max_continuations,omitempty"`
+	claude.Sampling
+}
+
+// DefaultMaxTokens is used when no per-model max_tokens is configured
+const DefaultMaxTokens = 4096
+
+// DefaultContextWindow is used when no per-model context_window is configured
+const DefaultContextWindow = 200000
+
+// ModelConfigFor returns the configured limits for model, filling defaults
+func (c *Config) ModelConfigFor(model string) ModelConfig {
+	var mc ModelConfig
+	if c != nil {
+		mc = c.Models[model]
+	}
+	if mc.MaxTokens <= 0 {
+		mc.MaxTokens = DefaultMaxTokens
+	}
+	if mc.ContextWindow <= 0 {
+		mc.ContextWindow = DefaultContextWindow
+	}
+	if mc.MaxContinuations == 0 {
+		mc.MaxContinuations = DefaultMaxContinuations
+	}
+	if mc.MaxOutputTokens <= 0 {
+		mc.MaxOutputTokens = knownMaxOutputTokens(model)
+	}
+	return mc
+}
+
+// maxOutputTokens are the output limits of known models, by name prefix,
+// most specific first
+var maxOutputTokens = []struct {
+	prefix string
+	limit  int
+}{
+	{"claude-opus-4-5", 64000},
+	{"claude-opus-4", 32000},
+	{"claude-sonnet-4", 64000},
+	{"claude-haiku-4", 64000},
+	{"claude-3-7-sonnet", 64000},
+	{"claude-3-5", 8192},
+	{"claude-3", 4096},
+}
+
+// knownMaxOutputTokens returns the output limit of model, or 0 when unknown
+func knownMaxOutputTokens(model string) int {
+	for _, m := range maxOutputTokens {
+		if strings.HasPrefix(model, m.prefix) {
+			return m.limit
+		}
+	}
+	return 0
 }
 
 // Rule represents a rule file with YAML frontmatter
@@ -92,80 +170,51 @@ func FindConfig() (string, error) {
 	return path, nil
 }
 
-// LoadConfig loads the configuration from the config file
-// Returns config and the directory containing the config (for path resolution)
+// LoadConfig loads the merged configuration (see LoadLayeredConfig)
+// Returns config and the directory relative memory_files resolve against
 func LoadConfig() (*Config, string, error) {
-	configPath, err := FindConfig()
-	if err != nil {
-		// No config file found, return defaults (resolve from cwd)
-		return &Config{MemoryFiles: DefaultMemoryFiles()}, "", nil
-	}
-	configDir := filepath.Dir(filepath.Dir(configPath)) // go up from .simpleagent/config.json
-
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return nil, "", err
-	}
-
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	lc, err := LoadLayeredConfig()
+	if lc == nil {
 		return nil, "", err
 	}
-
-	// Filter out empty entries
-	var validFiles []string
-	for _, f := range cfg.MemoryFiles {
-		if f != "" {
-			validFiles = append(validFiles, f)
-		}
-	}
-
-	if len(validFiles) == 0 {
-		validFiles = DefaultMemoryFiles()
-	}
-
-	cfg.MemoryFiles = validFiles
-	return &cfg, configDir, nil
+	return lc.Config, lc.MemoryDir, err
 }
 
-// GetMemoryFilesContent reads all configured memory files and concatenates them
-// configDir is the directory containing the config file (for resolving relative paths)
-func GetMemoryFilesContent(config *Config, configDir string) (string, []string) {
-	var contents []string
-	var loaded []string
-
-	for _, memFile := range config.MemoryFiles {
-		// Resolve relative paths from config directory
-		path := memFile
-		if configDir != "" && !filepath.IsAbs(memFile) {
-			path = filepath.Join(configDir, memFile)
-		}
-
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue
-		}
-		content := strings.TrimSpace(string(data))
-		if content != "" {
-			contents = append(contents, wrapXML("memory", memFile, content))
-			loaded = append(loaded, memFile)
-		}
+// GetMemoryFilesContent loads ~/.claude/CLAUDE.md, CLAUDE.md and AGENTS.md
+// from the repository root down to cwd, and the configured memory files
+// (relative to configDir), each with its @imports
+// Returns (content, loaded sources, import problems)
+func GetMemoryFilesContent(config *Config, configDir string) (string, []string, []error) {
+	m := newMemoryLoader()
+	m.config, m.configDir = config, configDir
+	var blocks []memoryBlock
+	for _, path := range m.discover() {
+		blocks = append(blocks, m.read(path, nil)...)
 	}
-
-	return strings.Join(contents, "\n\n"), loaded
+	globalMemory = m
+	content, loaded := formatMemory(blocks)
+	return content, loaded, m.problems
 }
 
 // BuildSystemPrompt loads config, memory files, and returns the full system prompt
+// Config problems and rules that failed to load are returned as the error
+// along with the prompt built from everything else
 func BuildSystemPrompt() (string, []string, *Config, error) {
+	var problems []error
 	config, configDir, err := LoadConfig()
 	if err != nil {
-		return systemInstructions, nil, nil, err
+		problems = append(problems, err)
+	}
+	if config == nil {
+		config, configDir = &Config{MemoryFiles: DefaultMemoryFiles()}, ""
 	}
 
-	memoryContent, loadedFiles := GetMemoryFilesContent(config, configDir)
+	memoryContent, loadedFiles, memoryErrs := GetMemoryFilesContent(config, configDir)
+	problems = append(problems, memoryErrs...)
 
 	// Load rules from .rules directory
-	_, rules := LoadRules()
+	_, rules, ruleErrs := LoadRules()
+	problems = append(problems, ruleErrs...)
 	alwaysRules := formatAlwaysRules(rules)
 
 	var loaded []string
@@ -197,7 +246,7 @@ func BuildSystemPrompt() (string, []string, *Config, error) {
 	// Store rules globally for conditional matching
 	globalRules = rules
 
-	return prompt, loaded, config, nil
+	return prompt, loaded, config, errors.Join(problems...)
 }
 
 // ParseFrontmatter extracts YAML frontmatter and body from file content
@@ -224,13 +273,17 @@ func ParseFrontmatter(content string) (frontmatter, body string) {
 	return
 }
 
+// ruleDirNames are searched for rule files, in cwd and parent dirs
+var ruleDirNames = []string{rulesDirName, ".claude/rules"}
+
 // LoadRules loads all rule files from .rules and .claude/rules directories
-func LoadRules() (string, []ruleFile) {
+// Rules whose frontmatter does not parse are skipped and reported
+func LoadRules() (string, []ruleFile, []error) {
 	var rules []ruleFile
+	var errs []error
 	var primaryDir string
 
-	// Check both .rules and .claude/rules
-	for _, dirName := range []string{rulesDirName, ".claude/rules"} {
+	for _, dirName := range ruleDirNames {
 		rulesDir := findDir(dirName)
 		if rulesDir == "" {
 			continue
@@ -266,7 +319,10 @@ func LoadRules() (string, []ruleFile) {
 
 			var rule Rule
 			if fm != "" {
-				yaml.Unmarshal([]byte(fm), &rule)
+				if err := yaml.Unmarshal([]byte(fm), &rule); err != nil {
+					errs = append(errs, frontmatterError(dirName+"/"+entry.Name(), err))
+					continue
+				}
 			}
 
 			rules = append(rules, ruleFile{
@@ -277,7 +333,28 @@ func LoadRules() (string, []ruleFile) {
 		}
 	}
 
-	return primaryDir, rules
+	return primaryDir, rules, errs
+}
+
+// frontmatterError reports a YAML error in a file's frontmatter as
+// file:line, counting the opening --- as line 1
+func frontmatterError(file string, err error) error {
+	msgs := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+	for i, msg := range msgs {
+		msg = strings.TrimPrefix(msg, "yaml: ")
+		var line int
+		if _, err := fmt.Sscanf(msg, "line %d:", &line); err == nil {
+			_, rest, _ := strings.Cut(msg, ":")
+			msgs[i] = fmt.Sprintf("%s:%d:%s", file, line+1, rest)
+		} else {
+			msgs[i] = file + ": " + msg
+		}
+	}
+	return errors.New(strings.Join(msgs, "\n"))
 }
 
 // findDir searches for a directory in current dir and parent dirs
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"simpleagent/tools"
+)
+
+const configUsage = `usage: agent config <list|get|set|unset> [key] [value] [--scope user|project|local]
+
+Settings merge in this order, later winning:
+  default  built-in
+  user     ~/.config/agent/config.json
+  project  .simpleagent/config.json (shared)
+  local    .simpleagent/config.local.json (git-ignored)
+  env      AGENT_DEFAULT_PROFILE, AGENT_SUBAGENT_PROFILE, AGENT_THINKING_DISPLAY,
+           AGENT_PERMISSIONS_MODE, AGENT_METADATA_USER_ID
+  flag     -c key=value
+
+Keys are dotted paths (models.MiniMax-M2.1.max_tokens). Values are JSON when
+they parse as JSON, strings otherwise. set and unset write the local scope
+unless --scope says otherwise; list and get show merged values without it.`
+
+// RunConfigCommand implements `agent config`
+func RunConfigCommand(args []string, w io.Writer) error {
+	args, scope, err := cutScopeFlag(args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(configUsage)
+	}
+	cmd, args := args[0], args[1:]
+
+	switch cmd {
+	case "list":
+		if len(args) > 1 {
+			return errors.New(configUsage)
+		}
+		prefix := ""
+		if len(args) == 1 {
+			prefix = args[0]
+		}
+		if scope == "" {
+			lc, err := LoadLayeredConfig()
+			if lc == nil {
+				return err
+			}
+			printConfig(w, lc, prefix)
+			return err
+		}
+		path, values, err := readConfigScope(scope)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, tools.Dim(path))
+		flat := flattenConfig(values)
+		for _, key := range sortedConfigKeys(flat) {
+			if matchesConfigPrefix(key, prefix) {
+				fmt.Fprintf(w, "%s = %s\n", key, formatConfigValue(flat[key]))
+			}
+		}
+		return nil
+
+	case "get":
+		if len(args) != 1 {
+			return errors.New("usage: agent config get <key> [--scope user|project|local]")
+		}
+		var values map[string]any
+		source := scope
+		if scope == "" {
+			lc, err := LoadLayeredConfig()
+			if err != nil {
+				return err
+			}
+			values, source = lc.Values, configSource(lc, args[0])
+		} else if _, values, err = readConfigScope(scope); err != nil {
+			return err
+		}
+		v, ok := getConfigPath(values, args[0])
+		if !ok {
+			return fmt.Errorf("%s is not set", args[0])
+		}
+		fmt.Fprintf(w, "%s %s\n", formatConfigValue(v), tools.Dim("("+source+")"))
+		return nil
+
+	case "set", "unset":
+		if cmd == "set" && len(args) != 2 {
+			return errors.New("usage: agent config set <key> <value> [--scope user|project|local]")
+		}
+		if cmd == "unset" && len(args) != 1 {
+			return errors.New("usage: agent config unset <key> [--scope user|project|local]")
+		}
+		if scope == "" {
+			scope = scopeLocal
+		}
+		path, values, err := readConfigScope(scope)
+		if err != nil {
+			return err
+		}
+		if cmd == "set" {
+			if err := setConfigPath(values, args[0], parseConfigValue(args[1])); err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+		} else if !deleteConfigPath(values, args[0]) {
+			return fmt.Errorf("%s is not set in %s", args[0], path)
+		}
+		if err := writeConfigScope(scope, path, values); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, tools.Success(fmt.Sprintf("%s %s in %s", cmd, args[0], path)))
+		return nil
+	}
+	return errors.New(configUsage)
+}
+
+// cutScopeFlag removes --scope (or -scope, with = or a separate value) from
+// anywhere in args
+func cutScopeFlag(args []string) ([]string, string, error) {
+	var rest []string
+	scope := ""
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || name != "scope" {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, "", errors.New("--scope needs a value: user, project or local")
+			}
+			i++
+			value = args[i]
+		}
+		if _, err := configScopePath(value); err != nil {
+			return nil, "", err
+		}
+		scope = value
+	}
+	return rest, scope, nil
+}
+
+// readConfigScope reads the file of a writable scope
+func readConfigScope(scope string) (string, map[string]any, error) {
+	path, err := configScopePath(scope)
+	if err != nil {
+		return "", nil, err
+	}
+	values, err := readConfigFile(path)
+	return path, values, err
+}
+
+// writeConfigScope checks values still decode as a Config and writes them
+func writeConfigScope(scope, path string, values map[string]any) error {
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return err
+	}
+	if issues := checkConfigValues(values); len(issues) > 0 {
+		return &ConfigError{Source: path, Issues: issues}
+	}
+	if keys := unsafeProjectKeys(values); scope == scopeProject && len(keys) > 0 {
+		return fmt.Errorf("%s cannot be set in project config, which is shared with the repo (use --scope user or local)", strings.Join(keys, ", "))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if scope == scopeLocal {
+		if err := ensureIgnored(filepath.Dir(path), localConfigFileName); err != nil {
+			return err
+		}
+	}
+	return writeFileAtomic(path, append(data, '\n'))
+}
+
+// ensureIgnored makes sure dir/.gitignore lists name
+func ensureIgnored(dir, name string) error {
+	path := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if slices.Contains(strings.Fields(string(data)), name) {
+		return nil
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	return writeFileAtomic(path, append(data, name+"\n"...))
+}
+
+// configSource is the scope that set key or the nearest value under it
+func configSource(lc *LayeredConfig, key string) string {
+	if s, ok := lc.Sources[key]; ok {
+		return s
+	}
+	var scopes []string
+	for k, s := range lc.Sources {
+		if matchesConfigPrefix(k, key) && !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	slices.Sort(scopes)
+	return strings.Join(scopes, ", ")
+}
+
+// matchesConfigPrefix reports whether key is prefix or lies under it
+func matchesConfigPrefix(key, prefix string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+".")
+}
+
+// printConfig lists merged values under prefix with the scope each came from
+func printConfig(w io.Writer, lc *LayeredConfig, prefix string) {
+	flat := flattenConfig(lc.Values)
+	width := 0
+	var keys []string
+	for _, key := range sortedConfigKeys(flat) {
+		if matchesConfigPrefix(key, prefix) {
+			keys = append(keys, key)
+			width = max(width, len(key))
+		}
+	}
+	if len(keys) == 0 {
+		fmt.Fprintln(w, tools.Dim("nothing set under "+prefix))
+		return
+	}
+	for _, key := range keys {
+		fmt.Fprintf(w, "%-*s  %s %s\n", width, key, formatConfigValue(flat[key]), tools.Dim("("+lc.Sources[key]+")"))
+	}
+	for _, l := range lc.Layers {
+		if l.Path != "" {
+			fmt.Fprintln(w, tools.Dim(l.Scope+": "+l.Path))
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Config scopes, lowest precedence first. Files are deep-merged: objects
+// merge key by key, anything else (including arrays) replaces.
+const (
+	scopeDefault = "default"
+	scopeUser    = "user"    // ~/.config/agent/config.json
+	scopeProject = "project" // .simpleagent/config.json, shared with the repo
+	scopeLocal   = "local"   // .simpleagent/config.local.json, git-ignored
+	scopeEnv     = "env"     // AGENT_<KEY> for top-level string settings
+	scopeFlag    = "flag"    // -c key=value
+)
+
+const localConfigFileName = "config.local.json"
+
+// envConfigPrefix + the upper-cased key sets a top-level string setting
+const envConfigPrefix = "AGENT_"
+
+// envConfigKeys are the settings the environment can set
+var envConfigKeys = []string{"default_profile", "subagent_profile", "thinking_display", "permissions_mode", "metadata_user_id"}
+
+// unsafeProjectKeys lists the settings in values the project scope may not
+// set. The project config is committed with the repo, so honoring them
+// would let a cloned repository turn off tool confirmations, run shell
+// commands or send the API key to another host. There is no hooks setting
+// yet; when one is added it runs commands, so it belongs here too.
+func unsafeProjectKeys(values map[string]any) []string {
+	var keys []string
+	if _, ok := values["permissions_mode"]; ok {
+		keys = append(keys, "permissions_mode")
+	}
+	profiles, _ := values["profiles"].(map[string]any)
+	for _, name := range slices.Sorted(maps.Keys(profiles)) {
+		profile, _ := profiles[name].(map[string]any)
+		for _, field := range []string{"base_url", "api_key_command"} {
+			if _, ok := profile[field]; ok {
+				keys = append(keys, "profiles."+name+"."+field)
+			}
+		}
+	}
+	return keys
+}
+
+// userConfigDir holds the user-wide config
+var userConfigDir = filepath.Join(os.Getenv("HOME"), ".config", "agent")
+
+// configOverrides are -c key=value flags, applied above every other scope
+var configOverrides []string
+
+// configLayer is one source of settings
+type configLayer struct {
+	Scope   string
+	Path    string // file read ("" for env and flags)
+	Dir     string // base for relative paths in the layer
+	Values  map[string]any
+	Dropped []string // keys this scope may not set, removed from Values
+}
+
+// LayeredConfig is the merged config and where each value came from
+type LayeredConfig struct {
+	*Config
+	Layers    []configLayer
+	Sources   map[string]string // flattened key -> scope that set it
+	Values    map[string]any    // merged settings, as JSON values
+	MemoryDir string            // base for relative memory_files
+}
+
+// projectConfigDir is the .simpleagent directory config files are read
+// from: the nearest one above cwd, else one at the project root
+func projectConfigDir() string {
+	if dir := findInAncestors(configDirName, true); dir != "" {
+		return dir
+	}
+	return filepath.Join(currentWorkspace().Project(), configDirName)
+}
+
+// configScopePath is the file a writable scope reads and writes
+func configScopePath(scope string) (string, error) {
+	switch scope {
+	case scopeUser:
+		return filepath.Join(userConfigDir, configFileName), nil
+	case scopeProject:
+		return filepath.Join(projectConfigDir(), configFileName), nil
+	case scopeLocal:
+		return filepath.Join(projectConfigDir(), localConfigFileName), nil
+	}
+	return "", fmt.Errorf("unknown config scope %q (want user, project or local)", scope)
+}
+
+// readConfigFile reads a config file as JSON values; a missing file is empty
+func readConfigFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]any{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]any{}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return values, nil
+	}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return values, nil
+}
+
+// configDefaults are the built-in settings under every other scope
+func configDefaults() map[string]any {
+	return map[string]any{
+		"memory_files":     DefaultMemoryFiles(),
+		"thinking_display": thinkingShow,
+		"permissions_mode": "prompt",
+	}
+}
+
+// loadConfigLayers reads every scope that has settings, lowest first
+func loadConfigLayers() ([]configLayer, error) {
+	layers := []configLayer{{Scope: scopeDefault, Values: configDefaults()}}
+	for _, scope := range []string{scopeUser, scopeProject, scopeLocal} {
+		path, _ := configScopePath(scope)
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		dir := filepath.Dir(path)
+		if scope != scopeUser {
+			dir = filepath.Dir(dir) // project paths are relative to the project, not .simpleagent
+		}
+		var dropped []string
+		if scope == scopeProject {
+			dropped = unsafeProjectKeys(values)
+			for _, key := range dropped {
+				deleteConfigPath(values, key)
+			}
+		}
+		if len(values) > 0 || len(dropped) > 0 {
+			layers = append(layers, configLayer{Scope: scope, Path: path, Dir: dir, Values: values, Dropped: dropped})
+		}
+	}
+
+	env := map[string]any{}
+	for _, key := range envConfigKeys {
+		if v, ok := os.LookupEnv(envConfigPrefix + strings.ToUpper(key)); ok && v != "" {
+			env[key] = v
+		}
+	}
+	if len(env) > 0 {
+		layers = append(layers, configLayer{Scope: scopeEnv, Values: env})
+	}
+
+	flags := map[string]any{}
+	for _, o := range configOverrides {
+		key, value, ok := strings.Cut(o, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("-c %s: want key=value", o)
+		}
+		if err := setConfigPath(flags, key, parseConfigValue(value)); err != nil {
+			return nil, fmt.Errorf("-c %s: %w", o, err)
+		}
+	}
+	if len(flags) > 0 {
+		layers = append(layers, configLayer{Scope: scopeFlag, Values: flags})
+	}
+	return layers, nil
+}
+
+// LoadLayeredConfig merges user, project, local, env and flag settings,
+// each overriding the ones before it. Unknown keys and mistyped values are
+// reported as *ConfigError alongside the config decoded without them.
+func LoadLayeredConfig() (*LayeredConfig, error) {
+	layers, err := loadConfigLayers()
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, l := range layers[1:] { // built-in defaults are known good
+		if issues := checkConfigValues(l.Values); len(issues) > 0 {
+			errs = append(errs, &ConfigError{Source: layerSource(l), Issues: issues})
+		}
+	}
+	merged := map[string]any{}
+	for _, l := range layers {
+		mergeConfigValues(merged, l.Values)
+	}
+
+	lc := &LayeredConfig{Config: &Config{}, Layers: layers, Values: merged, Sources: map[string]string{}}
+	data, _ := json.Marshal(merged)
+	if err := json.Unmarshal(data, lc.Config); err != nil && len(errs) == 0 {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	for _, l := range layers {
+		for _, key := range l.Dropped {
+			lc.ignored = append(lc.ignored, fmt.Sprintf("%s: %s is ignored in %s config", layerSource(l), key, l.Scope))
+		}
+	}
+	for key := range flattenConfig(merged) {
+		for _, l := range layers {
+			if _, ok := flattenConfig(l.Values)[key]; ok {
+				lc.Sources[key] = l.Scope
+			}
+		}
+	}
+
+	// Filter out empty entries
+	var validFiles []string
+	for _, f := range lc.MemoryFiles {
+		if f != "" {
+			validFiles = append(validFiles, f)
+		}
+	}
+	if len(validFiles) == 0 {
+		validFiles = DefaultMemoryFiles()
+		lc.Sources["memory_files"] = scopeDefault
+	}
+	lc.MemoryFiles = validFiles
+	lc.Values["memory_files"] = validFiles
+
+	// memory_files resolve against the layer that listed them; defaults,
+	// env and flags against the project config, if any
+	from := lc.Sources["memory_files"]
+	for _, l := range layers {
+		if l.Dir == "" || (l.Scope == scopeUser && from != scopeUser) {
+			continue
+		}
+		lc.MemoryDir = l.Dir
+		if l.Scope == from {
+			break
+		}
+	}
+	return lc, errors.Join(errs...)
+}
+
+// mergeConfigValues deep-merges src into dst
+func mergeConfigValues(dst, src map[string]any) {
+	for k, v := range src {
+		if sub, ok := v.(map[string]any); ok {
+			if existing, ok := dst[k].(map[string]any); ok {
+				mergeConfigValues(existing, sub)
+				continue
+			}
+			clone := map[string]any{}
+			mergeConfigValues(clone, sub)
+			dst[k] = clone
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+// flattenConfig maps dotted keys to leaf values (arrays are leaves)
+func flattenConfig(values map[string]any) map[string]any {
+	flat := map[string]any{}
+	var walk func(prefix string, m map[string]any)
+	walk = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if sub, ok := v.(map[string]any); ok && len(sub) > 0 {
+				walk(key, sub)
+				continue
+			}
+			flat[key] = v
+		}
+	}
+	walk("", values)
+	return flat
+}
+
+// configNamedMaps are the settings keyed by a user-chosen name, with the
+// type each name maps to
+var configNamedMaps = map[string]reflect.Type{
+	"models":   reflect.TypeFor[ModelConfig](),
+	"profiles": reflect.TypeFor[Profile](),
+}
+
+// splitConfigPath splits a dotted key against m. Names may contain dots
+// (model names do), so the longest existing key wins at each level, and a
+// new name under models or profiles runs up to the first field of its type.
+func splitConfigPath(m map[string]any, path string) []string {
+	var parts []string
+	for path != "" {
+		next, rest, _ := strings.Cut(path, ".")
+		if typ, ok := configNamedMaps[strings.Join(parts, ".")]; ok {
+			next, rest = cutConfigName(path, slices.Collect(maps.Keys(structFields(typ))))
+		}
+		for k := range m {
+			if len(k) > len(next) && (path == k || strings.HasPrefix(path, k+".")) {
+				next, rest = k, strings.TrimPrefix(strings.TrimPrefix(path, k), ".")
+			}
+		}
+		parts = append(parts, next)
+		sub, _ := m[next].(map[string]any)
+		m, path = sub, rest
+	}
+	return parts
+}
+
+// cutConfigName splits path before the first segment after the name that
+// is one of fields; without one the whole path is the name
+func cutConfigName(path string, fields []string) (string, string) {
+	segments := strings.Split(path, ".")
+	for i := 1; i < len(segments); i++ {
+		if slices.Contains(fields, segments[i]) {
+			return strings.Join(segments[:i], "."), strings.Join(segments[i:], ".")
+		}
+	}
+	return path, ""
+}
+
+// getConfigPath looks up a dotted key
+func getConfigPath(m map[string]any, path string) (any, bool) {
+	var v any = m
+	for _, part := range splitConfigPath(m, path) {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setConfigPath sets a dotted key, creating objects on the way
+func setConfigPath(m map[string]any, path string, value any) error {
+	parts := splitConfigPath(m, path)
+	if len(parts) == 0 {
+		return errors.New("empty key")
+	}
+	for i, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			if _, exists := m[part]; exists {
+				return fmt.Errorf("%s is not an object", strings.Join(parts[:i+1], "."))
+			}
+			next = map[string]any{}
+			m[part] = next
+		}
+		m = next
+	}
+	m[parts[len(parts)-1]] = value
+	return nil
+}
+
+// deleteConfigPath removes a dotted key and any objects it leaves empty,
+// reporting whether it was set
+func deleteConfigPath(m map[string]any, path string) bool {
+	parts := splitConfigPath(m, path)
+	if len(parts) == 0 {
+		return false
+	}
+	parent, ok := getConfigPath(m, strings.Join(parts[:len(parts)-1], "."))
+	obj, isObj := parent.(map[string]any)
+	if len(parts) == 1 {
+		obj, ok, isObj = m, true, true
+	}
+	if !ok || !isObj {
+		return false
+	}
+	if _, ok := obj[parts[len(parts)-1]]; !ok {
+		return false
+	}
+	delete(obj, parts[len(parts)-1])
+	if len(obj) == 0 && len(parts) > 1 {
+		deleteConfigPath(m, strings.Join(parts[:len(parts)-1], "."))
+	}
+	return true
+}
+
+// parseConfigValue reads a command-line value as JSON, falling back to a
+// plain string, so `8192`, `true` and `["a.md"]` keep their types
+func parseConfigValue(s string) any {
+	var v any
+	if json.Unmarshal([]byte(s), &v) == nil {
+		return v
+	}
+	return s
+}
+
+// formatConfigValue shows a value as JSON
+func formatConfigValue(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// sortedConfigKeys returns the flattened keys of values in order
+func sortedConfigKeys(flat map[string]any) []string {
+	return slices.Sorted(maps.Keys(flat))
+}
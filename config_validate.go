@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+
+	"simpleagent/claude"
+)
+
+// ConfigError lists what is wrong with one config source
+type ConfigError struct {
+	Source string // file path, "env" or "-c"
+	Issues []string
+}
+
+func (e *ConfigError) Error() string {
+	return e.Source + ": " + strings.Join(e.Issues, "\n"+e.Source+": ")
+}
+
+// layerSource names a layer in errors
+func layerSource(l configLayer) string {
+	switch {
+	case l.Path != "":
+		return l.Path
+	case l.Scope == scopeFlag:
+		return "-c"
+	}
+	return l.Scope
+}
+
+// checkConfigValues reports unknown keys and values of the wrong type in
+// one config source, so a typo fails loudly instead of being ignored
+func checkConfigValues(values map[string]any) []string {
+	issues := unknownConfigFields(values, reflect.TypeFor[Config](), "")
+	data, err := json.Marshal(values)
+	if err != nil {
+		return append(issues, err.Error())
+	}
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(data, &Config{}); errors.As(err, &typeErr) {
+		issues = append(issues, fmt.Sprintf("%s: expected %s, got %s", typeErr.Field, jsonTypeName(typeErr.Type), typeErr.Value))
+	} else if err != nil {
+		issues = append(issues, err.Error())
+	}
+	return issues
+}
+
+// unknownConfigFields walks value against typ, naming keys typ has no
+// field for along with the closest one it does
+func unknownConfigFields(value any, typ reflect.Type, path string) []string {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	var issues []string
+	switch typ.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return nil // a type error, reported by the decoder
+		}
+		fields := structFields(typ)
+		for _, key := range slices.Sorted(maps.Keys(obj)) {
+			field, ok := fields[key]
+			if !ok {
+				issue := fmt.Sprintf("unknown field %q", joinConfigPath(path, key))
+				if s := suggestName(key, slices.Collect(maps.Keys(fields))); s != "" {
+					issue += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				issues = append(issues, issue)
+				continue
+			}
+			issues = append(issues, unknownConfigFields(obj[key], field, joinConfigPath(path, key))...)
+		}
+	case reflect.Map:
+		if obj, ok := value.(map[string]any); ok {
+			for _, key := range slices.Sorted(maps.Keys(obj)) {
+				issues = append(issues, unknownConfigFields(obj[key], typ.Elem(), joinConfigPath(path, key))...)
+			}
+		}
+	case reflect.Slice:
+		if list, ok := value.([]any); ok {
+			for i, v := range list {
+				issues = append(issues, unknownConfigFields(v, typ.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return issues
+}
+
+// structFields maps typ's JSON field names to their types, embedded
+// structs included
+func structFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range typ.NumField() {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			for k, v := range structFields(f.Type) {
+				fields[k] = v
+			}
+		} else if name != "" && name != "-" && f.IsExported() {
+			fields[name] = f.Type
+		}
+	}
+	return fields
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// jsonTypeName describes a Go type the way it is written in JSON
+func jsonTypeName(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int64, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return typ.String()
+}
+
+// suggestName returns the candidate closest to name, ignoring case, "_"
+// and "-", or "" when none is close
+func suggestName(name string, candidates []string) string {
+	normalize := func(s string) string {
+		return strings.NewReplacer("_", "", "-", "").Replace(strings.ToLower(s))
+	}
+	best, bestDist := "", len(name)/3+1
+	for _, c := range slices.Sorted(slices.Values(candidates)) {
+		if d := editDistance(normalize(name), normalize(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// Problems reports settings that decode but cannot work: unknown modes,
+// profiles or providers, MCP servers whose command is not on PATH, and
+// settings ignored because the project config may not set them
+func (c *Config) Problems() []string {
+	problems := slices.Clone(c.ignored)
+	if c.ThinkingDisplay != "" && !validThinkingDisplay(c.ThinkingDisplay) {
+		problems = append(problems, fmt.Sprintf("thinking_display: %q is not show, collapse or hide", c.ThinkingDisplay))
+	}
+	if c.PermissionsMode != "" && c.PermissionsMode != "prompt" && c.PermissionsMode != "accept_all" {
+		problems = append(problems, fmt.Sprintf("permissions_mode: %q is not prompt or accept_all", c.PermissionsMode))
+	}
+	for _, p := range [][2]string{{"default_profile", c.DefaultProfile}, {"subagent_profile", c.SubagentProfile}} {
+		key, name := p[0], p[1]
+		if _, ok := c.Profiles[name]; name != "" && name != envProfileName && !ok {
+			problems = append(problems, fmt.Sprintf("%s: unknown profile %q (available: %s)", key, name, strings.Join(append(c.ProfileNames(), envProfileName), ", ")))
+		}
+	}
+	for _, name := range c.ProfileNames() {
+		if c.Profiles[name].Model == "" {
+			problems = append(problems, fmt.Sprintf("profiles.%s: no model", name))
+		}
+		if _, err := claude.NewProvider(c.Profiles[name].Provider); err != nil {
+			problems = append(problems, fmt.Sprintf("profiles.%s.provider: %v", name, err))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.Models)) {
+		if _, err := claude.NewProvider(c.Models[name].Provider); err != nil {
+			problems = append(problems, fmt.Sprintf("models.%s.provider: %v", name, err))
+		}
+	}
+	seen := make(map[string]bool)
+	for i, srv := range c.MCPServers {
+		switch {
+		case srv.Name == "":
+			problems = append(problems, fmt.Sprintf("mcp_servers[%d]: no name", i))
+		case seen[srv.Name]:
+			problems = append(problems, fmt.Sprintf("mcp_servers[%d]: duplicate name %q", i, srv.Name))
+		}
+		seen[srv.Name] = true
+		if srv.Cmd == "" {
+			problems = append(problems, fmt.Sprintf("mcp_servers[%d] (%s): no cmd", i, srv.Name))
+		} else if _, err := exec.LookPath(srv.Cmd); err != nil {
+			problems = append(problems, fmt.Sprintf("mcp_servers[%d] (%s): command %q not found on PATH", i, srv.Name, srv.Cmd))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"simpleagent/claude/claudetest"
+	"simpleagent/tools"
+)
+
+func TestCheckConfigValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]any
+		want   []string
+	}{
+		{"valid", map[string]any{"mcp_servers": []any{map[string]any{"name": "fs", "cmd": "fs-server"}}, "models": map[string]any{"m": map[string]any{"max_tokens": 10.0, "temperature": 0.5}}}, nil},
+		{"camel case", map[string]any{"mcpServers": []any{}}, []string{`unknown field "mcpServers" (did you mean "mcp_servers"?)`}},
+		{"nested typo", map[string]any{"models": map[string]any{"MiniMax-M2.1": map[string]any{"max_token": 10.0}}}, []string{`unknown field "models.MiniMax-M2.1.max_token" (did you mean "max_tokens"?)`}},
+		{"list entries", map[string]any{"mcp_servers": []any{map[string]any{"name": "fs", "arg": []any{}}}}, []string{`unknown field "mcp_servers[0].arg" (did you mean "args"?)`}},
+		{"nothing close", map[string]any{"colour": "blue"}, []string{`unknown field "colour"`}},
+		{"wrong type", map[string]any{"retention": map[string]any{"max_sessions": "3"}}, []string{"retention.max_sessions: expected a number, got string"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			got := checkConfigValues(tt.values)
+
+			// then
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadLayeredConfig_ReportsInvalidFile(t *testing.T) {
+	// given
+	project := useTempConfig(t)
+	path := filepath.Join(project, configDirName, configFileName)
+	writeConfigFile(t, path, `{"mcpServers": [], "default_profile": "fast", "profiles": {"fast": {"model": "m"}}}`)
+
+	// when
+	lc, err := LoadLayeredConfig()
+
+	// then - the rest of the file still applies
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) || configErr.Source != path || len(configErr.Issues) != 1 {
+		t.Fatalf("expected one issue in %s, got %v", path, err)
+	}
+	if lc == nil || lc.DefaultProfile != "fast" {
+		t.Errorf("expected valid settings kept, got %+v", lc)
+	}
+}
+
+func TestConfig_Problems(t *testing.T) {
+	// given
+	config := &Config{
+		ThinkingDisplay: "loud",
+		DefaultProfile:  "fast",
+		Profiles:        map[string]Profile{"slow": {Model: "m", ModelConfig: ModelConfig{Provider: "carrier-pigeon"}}},
+		MCPServers: []tools.MCPServerConfig{
+			{Name: "shell", Cmd: "sh"},
+			{Name: "missing", Cmd: "no-such-mcp-server-cmd"},
+			{Name: "shell", Cmd: "sh"},
+		},
+	}
+
+	// when
+	problems := config.Problems()
+
+	// then
+	want := []string{
+		`thinking_display: "loud"`,
+		`default_profile: unknown profile "fast"`,
+		"profiles.slow.provider:",
+		`mcp_servers[1] (missing): command "no-such-mcp-server-cmd" not found on PATH`,
+		`mcp_servers[2]: duplicate name "shell"`,
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("expected %d problems, got %q", len(want), problems)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(problems[i], w) {
+			t.Errorf("expected problem %d to start with %q, got %q", i, w, problems[i])
+		}
+	}
+}
+
+func TestLoadRules_ReportsFrontmatterErrors(t *testing.T) {
+	// given
+	dir := t.TempDir()
+	t.Chdir(dir)
+	writeConfigFile(t, filepath.Join(dir, rulesDirName, "good.md"), "---\npaths: \"*.go\"\n---\nUse gofmt.\n")
+	writeConfigFile(t, filepath.Join(dir, rulesDirName, "bad.md"), "---\ndescription: x\npaths: [*.go\n---\nAlways loaded by mistake.\n")
+
+	// when
+	_, rules, errs := LoadRules()
+
+	// then - the broken rule is skipped rather than loaded unconditionally
+	if len(rules) != 1 || rules[0].Pattern != "*.go" {
+		t.Errorf("expected only the good rule, got %+v", rules)
+	}
+	if len(errs) != 1 || !strings.HasPrefix(errs[0].Error(), rulesDirName+"/bad.md:3:") {
+		t.Errorf("expected an error at %s/bad.md:3, got %v", rulesDirName, errs)
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	// given
+	project := useTempConfig(t)
+	t.Setenv("HOME", t.TempDir()) // no personal skills
+	srv := claudetest.NewServer(t, claudetest.Reply(claudetest.Text("p")), claudetest.Reply(claudetest.Text("p")))
+	writeConfigFile(t, filepath.Join(project, configDirName, localConfigFileName),
+		`{"default_profile": "test", "profiles": {"test": {"model": "m", "base_url": "`+srv.URL+`"}}}`)
+	var out bytes.Buffer
+
+	// when
+	err := RunDoctor(&out)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out.String())
+	}
+	if reqs := srv.Requests(); len(reqs) != 1 || reqs[0].Model != "m" || reqs[0].MaxTokens != 1 {
+		t.Errorf("expected one 1-token request to m, got %+v", reqs)
+	}
+	if !strings.Contains(out.String(), "all checks passed") {
+		t.Errorf("expected success, got:\n%s", out.String())
+	}
+
+	// when - a broken rule fails the run
+	writeConfigFile(t, filepath.Join(project, rulesDirName, "bad.md"), "---\npaths: [\n---\nx\n")
+	out.Reset()
+	err = RunDoctor(&out)
+
+	// then
+	if err == nil || !strings.Contains(out.String(), "bad.md:2") {
+		t.Errorf("expected a failure naming bad.md:2, got %v:\n%s", err, out.String())
+	}
+}
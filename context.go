@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// compactThresholdPercent is the context usage at which the agent is told to
+// wrap up and exact server-side counts replace local estimates
+const compactThresholdPercent = 80
+
+// contextPercent returns how full the context window is (0 when unknown)
+func (a *Agent) contextPercent() int {
+	if a.contextWindow <= 0 || a.contextTokens <= 0 {
+		return 0
+	}
+	return a.contextTokens * 100 / a.contextWindow
+}
+
+// shouldCompact reports whether the conversation is near the context limit
+func (a *Agent) shouldCompact() bool {
+	return a.contextPercent() >= compactThresholdPercent
+}
+
+// preflight sizes the request before sending: a local estimate normally, an
+// exact count_tokens call once the estimate nears the limit. Warns when the
+// request plus max_tokens will not fit.
+func (a *Agent) preflight(params claude.MessageCreateParams) {
+	if a.contextWindow <= 0 {
+		return
+	}
+	tokens := claude.EstimateTokens(params)
+	estimated := true
+	if tokens*100/a.contextWindow >= compactThresholdPercent && a.client != nil && a.client.Messages != nil {
+		if count, err := a.client.Messages.CountTokens(params); err == nil {
+			tokens, estimated = count.InputTokens, count.Estimated
+		}
+	}
+	a.contextTokens = tokens
+
+	if tokens+params.MaxTokens > a.contextWindow {
+		approx := ""
+		if estimated {
+			approx = "~"
+		}
+		fmt.Println(tools.Warning(fmt.Sprintf("request is %s%d tokens + %d max_tokens, over the %d token context window", approx, tokens, params.MaxTokens, a.contextWindow)))
+	}
+}
+
+// recordUsage replaces the estimate with the server-reported size, adds its
+// cost and queues the usage for the session transcript
+func (a *Agent) recordUsage(usage *claude.Usage) {
+	if usage == nil {
+		return
+	}
+	a.pendingUsage = append(a.pendingUsage, *usage)
+	a.cost += a.pricing.Cost(*usage)
+	if usage.InputTokens > 0 {
+		a.contextTokens = usage.InputTokens + usage.OutputTokens
+	}
+}
+
+// promptPrefix shows context usage, the cost so far when the profile has
+// pricing, and whether nothing is being saved, before the input prompt
+func (a *Agent) promptPrefix() string {
+	prefix := ""
+	if a.readOnly {
+		prefix = tools.Dim("read-only") + " "
+	}
+	if pct := a.contextPercent(); pct > 0 {
+		prefix += tools.ContextGauge(pct) + " "
+	}
+	if a.cost > 0 {
+		prefix += tools.Dim(fmt.Sprintf("$%.4f", a.cost)) + " "
+	}
+	return prefix
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// doctorTimeout bounds each API and MCP check
+var doctorTimeout = 15 * time.Second
+
+// doctorReport prints check results and remembers whether any failed
+type doctorReport struct {
+	w      io.Writer
+	failed bool
+}
+
+func (r *doctorReport) section(name string) { fmt.Fprintln(r.w, "\n"+tools.Status(name)) }
+func (r *doctorReport) ok(msg string)       { fmt.Fprintln(r.w, tools.Success(msg)) }
+
+func (r *doctorReport) fail(err error) {
+	r.failed = true
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintln(r.w, tools.Error(line))
+	}
+}
+
+// RunDoctor implements `agent doctor`: check the config, the API behind
+// the configured profiles, MCP servers, skills, memory files and rules
+func RunDoctor(w io.Writer) error {
+	r := &doctorReport{w: w}
+
+	r.section("config")
+	config, memoryDir := &Config{MemoryFiles: DefaultMemoryFiles()}, ""
+	lc, err := LoadLayeredConfig()
+	if err != nil {
+		r.fail(err)
+	}
+	if lc != nil {
+		config, memoryDir = lc.Config, lc.MemoryDir
+		for _, l := range lc.Layers {
+			if l.Path != "" {
+				r.ok(l.Scope + ": " + l.Path)
+			}
+		}
+		if len(lc.Layers) == 1 {
+			r.ok("no config files, using defaults")
+		}
+	}
+	for _, problem := range config.Problems() {
+		r.fail(errors.New(problem))
+	}
+
+	r.section("api")
+	profiles := []string{config.DefaultProfile}
+	if config.SubagentProfile != "" && config.SubagentProfile != config.DefaultProfile {
+		profiles = append(profiles, config.SubagentProfile)
+	}
+	for _, name := range profiles {
+		profile, err := config.ResolveProfile(name)
+		if err != nil {
+			r.fail(err)
+			continue
+		}
+		took, err := checkAPI(profile)
+		label := fmt.Sprintf("%s · %s at %s", profile.Name, profile.Model, profile.BaseURL)
+		if err != nil {
+			r.fail(fmt.Errorf("%s: %w", label, err))
+			continue
+		}
+		r.ok(fmt.Sprintf("%s (%s)", label, took.Round(time.Millisecond)))
+	}
+
+	r.section("mcp")
+	if len(config.MCPServers) == 0 {
+		r.ok("no servers configured")
+	}
+	for _, srv := range config.MCPServers {
+		ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+		n, err := tools.CheckMCPServer(ctx, srv)
+		cancel()
+		if err != nil {
+			r.fail(fmt.Errorf("%s: %w", srv.Name, err))
+			continue
+		}
+		r.ok(fmt.Sprintf("%s: %d tools", srv.Name, n))
+	}
+
+	r.section("skills")
+	skills, _, errs := scanSkills()
+	r.ok(fmt.Sprintf("%d skill(s)", len(skills)))
+	for _, err := range errs {
+		r.fail(err)
+	}
+
+	r.section("memory")
+	_, loaded, errs := GetMemoryFilesContent(config, memoryDir)
+	r.ok(fmt.Sprintf("%d file(s) %v", len(loaded), loaded))
+	for _, err := range errs {
+		r.fail(err)
+	}
+
+	r.section("rules")
+	_, rules, errs := LoadRules()
+	r.ok(fmt.Sprintf("%d rule(s)", len(rules)))
+	for _, err := range errs {
+		r.fail(err)
+	}
+
+	fmt.Fprintln(w)
+	if r.failed {
+		return errors.New("doctor found problems")
+	}
+	r.ok("all checks passed")
+	return nil
+}
+
+// checkAPI sends the smallest possible request with a profile, returning
+// how long the round trip took
+func checkAPI(profile *ResolvedProfile) (time.Duration, error) {
+	client, err := profile.NewClient()
+	if err != nil {
+		return 0, err
+	}
+	client.Timeout = doctorTimeout
+	start := time.Now()
+	_, err = client.Messages.Create(claude.MessageCreateParams{
+		Model:     profile.Model,
+		MaxTokens: 1,
+		Messages:  []claude.MessageParam{{Role: "user", Content: "ping"}},
+	})
+	return time.Since(start), err
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// Export formats
+const (
+	exportMarkdown = "md"
+	exportHTML     = "html"
+	exportJSON     = "json"
+)
+
+// Tool output and inputs longer than this are cut in exports
+const (
+	exportMaxLines = 40
+	exportMaxBytes = 4000
+)
+
+// Export entry kinds, in transcript order
+const (
+	entryUser       = "user"
+	entryAssistant  = "assistant"
+	entryThinking   = "thinking"
+	entryToolCall   = "tool_call"
+	entryToolResult = "tool_result"
+	entryTodos      = "todos" // a TodoWrite call
+)
+
+// exportDocument is a session flattened for reading
+type exportDocument struct {
+	Title   string        `json:"title"`
+	Meta    SessionMeta   `json:"meta"`
+	Usage   claude.Usage  `json:"usage"`
+	Entries []exportEntry `json:"entries"`
+	Todos   []tools.Todo  `json:"todos,omitempty"` // final todo list
+}
+
+// exportEntry is one readable piece of a message
+type exportEntry struct {
+	Kind      string       `json:"kind"`
+	Text      string       `json:"text,omitempty"` // message text, thinking, tool output, or the file a tool call touches
+	Model     string       `json:"model,omitempty"`
+	Tool      string       `json:"tool,omitempty"`
+	ToolID    string       `json:"tool_id,omitempty"`
+	Input     string       `json:"input,omitempty"` // indented JSON
+	Diff      string       `json:"diff,omitempty"`  // "-" and "+" prefixed lines
+	Todos     []tools.Todo `json:"todos,omitempty"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;?]*[A-Za-z]`)
+
+// exportSession writes sess to w in format
+func exportSession(w io.Writer, sess *SessionFile, format string) error {
+	doc := buildExport(sess)
+	switch format {
+	case exportMarkdown:
+		return writeMarkdownExport(w, doc)
+	case exportHTML:
+		return writeHTMLExport(w, doc)
+	case exportJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	}
+	return fmt.Errorf("unknown export format %q (want md, html or json)", format)
+}
+
+// buildExport flattens a session's messages into entries
+func buildExport(sess *SessionFile) exportDocument {
+	doc := exportDocument{Title: sess.Meta.Title, Meta: sess.Meta, Usage: sess.Usage, Todos: sess.Todos}
+	if doc.Title == "" {
+		doc.Title = excerpt(firstUserMessage(sess.Messages), 80)
+	}
+	if doc.Title == "" {
+		doc.Title = "Session " + sess.Meta.ID
+	}
+
+	toolNames := make(map[string]string) // tool_use id -> tool name
+	for i, m := range sess.Messages {
+		for _, b := range claude.ContentBlocks(m.Content) {
+			switch {
+			case b.Type == "text" && m.Role == "user":
+				doc.Entries = append(doc.Entries, exportEntry{Kind: entryUser, Text: b.Text})
+			case b.Type == "text" && strings.TrimSpace(b.Text) != "":
+				doc.Entries = append(doc.Entries, exportEntry{Kind: entryAssistant, Text: b.Text, Model: sess.MessageModels[i]})
+			case b.Type == "thinking":
+				doc.Entries = append(doc.Entries, exportEntry{Kind: entryThinking, Text: b.Thinking})
+			case b.Type == "redacted_thinking":
+				doc.Entries = append(doc.Entries, exportEntry{Kind: entryThinking, Text: "(redacted)"})
+			case b.Type == "tool_use":
+				toolNames[b.ID] = b.Name
+				doc.Entries = append(doc.Entries, toolCallEntry(b))
+			case b.Type == "tool_result":
+				text, cut := truncateExport(toolResultText(b))
+				doc.Entries = append(doc.Entries, exportEntry{
+					Kind: entryToolResult, Tool: toolNames[b.ToolUseID], ToolID: b.ToolUseID, Text: text, Truncated: cut,
+				})
+			}
+		}
+	}
+	return doc
+}
+
+// toolResultText is tool_result content without terminal styling
+func toolResultText(b claude.WireBlock) string {
+	return strings.TrimRight(ansiEscape.ReplaceAllString(b.ResultText(), ""), "\n")
+}
+
+// toolCallEntry shows file edits as diffs, todo updates as lists and
+// everything else as indented input
+func toolCallEntry(b claude.WireBlock) exportEntry {
+	e := exportEntry{Kind: entryToolCall, Tool: b.Name, ToolID: b.ID}
+	var args struct {
+		Path    string       `json:"path"`
+		OldText string       `json:"old_text"`
+		NewText string       `json:"new_text"`
+		Content string       `json:"content"`
+		Todos   []tools.Todo `json:"todos"`
+	}
+	json.Unmarshal(b.Input, &args)
+
+	switch b.Name {
+	case "TodoWrite":
+		e.Kind, e.Todos = entryTodos, args.Todos
+		return e
+	case "ReplaceText":
+		e.Text = args.Path
+		e.Diff, e.Truncated = truncateExport(prefixLines("-", args.OldText) + "\n" + prefixLines("+", args.NewText))
+		return e
+	case "WriteFile":
+		e.Text = args.Path
+		e.Diff, e.Truncated = truncateExport(prefixLines("+", args.Content))
+		return e
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, b.Input, "", "  "); err != nil {
+		indented.Reset()
+		indented.Write(b.Input)
+	}
+	e.Input, e.Truncated = truncateExport(indented.String())
+	return e
+}
+
+func prefixLines(prefix, s string) string {
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = prefix + l
+	}
+	return strings.Join(lines, "\n")
+}
+
+// truncateExport cuts s to exportMaxLines lines and exportMaxBytes bytes
+func truncateExport(s string) (string, bool) {
+	cut := false
+	if lines := strings.Split(s, "\n"); len(lines) > exportMaxLines {
+		s, cut = strings.Join(lines[:exportMaxLines], "\n"), true
+	}
+	if len(s) > exportMaxBytes {
+		s, cut = strings.ToValidUTF8(s[:exportMaxBytes], ""), true
+	}
+	return s, cut
+}
+
+// exportFacts are the header rows shared by the Markdown and HTML exports
+func exportFacts(doc exportDocument) [][2]string {
+	m := doc.Meta
+	facts := [][2]string{{"Session", m.ID}, {"Model", m.Model}}
+	if project := m.Project(); project != "" {
+		where := project
+		if m.Branch != "" {
+			where += " · " + m.Branch
+		}
+		if len(m.Commit) >= 7 {
+			where += " @ " + m.Commit[:7]
+		}
+		facts = append(facts, [2]string{"Project", where})
+	}
+	if m.Parent != "" {
+		facts = append(facts, [2]string{"Forked from", fmt.Sprintf("%s at message %d", m.Parent, m.ForkedAt)})
+	}
+	facts = append(facts,
+		[2]string{"Created", m.CreatedAt.Format("2006-01-02 15:04")},
+		[2]string{"Updated", m.UpdatedAt.Format("2006-01-02 15:04")},
+		[2]string{"Tokens", fmt.Sprintf("%d in · %d out", doc.Usage.InputTokens, doc.Usage.OutputTokens)},
+	)
+	return facts
+}
+
+// assistantSide reports whether an entry belongs under the assistant heading
+func assistantSide(kind string) bool {
+	return kind != entryUser
+}
+
+func writeMarkdownExport(w io.Writer, doc exportDocument) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n| | |\n|---|---|\n", doc.Title)
+	for _, f := range exportFacts(doc) {
+		fmt.Fprintf(&b, "| %s | %s |\n", f[0], strings.ReplaceAll(f[1], "|", `\|`))
+	}
+	b.WriteString("\n")
+
+	side := ""
+	for _, e := range doc.Entries {
+		if e.Kind == entryUser {
+			b.WriteString("## User\n\n")
+			side = entryUser
+		} else if side != entryAssistant && assistantSide(e.Kind) {
+			b.WriteString("## Assistant\n\n")
+			side = entryAssistant
+		}
+
+		switch e.Kind {
+		case entryUser, entryAssistant:
+			b.WriteString(strings.TrimSpace(e.Text) + "\n\n")
+		case entryThinking:
+			fmt.Fprintf(&b, "<details><summary>Thinking</summary>\n\n%s\n\n</details>\n\n", strings.TrimSpace(e.Text))
+		case entryToolCall:
+			fmt.Fprintf(&b, "**Tool call:** `%s`", e.Tool)
+			if e.Text != "" {
+				fmt.Fprintf(&b, " `%s`", e.Text)
+			}
+			b.WriteString("\n\n")
+			if e.Diff != "" {
+				b.WriteString(codeBlock("diff", e.Diff))
+			} else if e.Input != "" {
+				b.WriteString(codeBlock("json", e.Input))
+			}
+			if e.Truncated {
+				b.WriteString("_(truncated)_\n\n")
+			}
+		case entryToolResult:
+			fmt.Fprintf(&b, "**Result:** `%s`\n\n", e.Tool)
+			b.WriteString(codeBlock("text", e.Text))
+			if e.Truncated {
+				b.WriteString("_(output truncated)_\n\n")
+			}
+		case entryTodos:
+			b.WriteString("**Todos:**\n\n" + markdownTodos(e.Todos) + "\n")
+		}
+	}
+
+	if len(doc.Todos) > 0 {
+		b.WriteString("---\n\n## Final todos\n\n" + markdownTodos(doc.Todos))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// codeBlock fences s with more backticks than it contains in a row
+func codeBlock(lang, s string) string {
+	fence := "```"
+	for strings.Contains(s, fence) {
+		fence += "`"
+	}
+	return fence + lang + "\n" + s + "\n" + fence + "\n\n"
+}
+
+func markdownTodos(todos []tools.Todo) string {
+	var b strings.Builder
+	for _, t := range todos {
+		switch t.Status {
+		case "completed":
+			fmt.Fprintf(&b, "- [x] %s\n", t.Content)
+		case "in_progress":
+			fmt.Fprintf(&b, "- [ ] **%s** (in progress)\n", t.Content)
+		default:
+			fmt.Fprintf(&b, "- [ ] %s\n", t.Content)
+		}
+	}
+	return b.String()
+}
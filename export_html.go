@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"simpleagent/tools"
+)
+
+// writeHTMLExport renders a self-contained page: inline CSS in the terminal
+// palette, thinking and tool output in collapsible sections, no scripts
+func writeHTMLExport(w io.Writer, doc exportDocument) error {
+	return htmlExportTemplate.Execute(w, struct {
+		exportDocument
+		Facts   [][2]string
+		Palette map[string]string
+	}{doc, exportFacts(doc), tools.Palette})
+}
+
+var htmlExportTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"markdown": func(s string) template.HTML {
+		var buf bytes.Buffer
+		if err := goldmark.Convert([]byte(s), &buf); err != nil {
+			return template.HTML(template.HTMLEscapeString(s))
+		}
+		return template.HTML(buf.String()) // goldmark drops raw HTML by default
+	},
+	"lines": func(s string) []string { return strings.Split(s, "\n") },
+	"diffClass": func(line string) string {
+		switch {
+		case strings.HasPrefix(line, "+"):
+			return "add"
+		case strings.HasPrefix(line, "-"):
+			return "del"
+		}
+		return ""
+	},
+	"startsTurn": func(entries []exportEntry, i int) string {
+		e := entries[i]
+		if e.Kind == entryUser {
+			return "User"
+		}
+		if i == 0 || !assistantSide(entries[i-1].Kind) {
+			return "Assistant"
+		}
+		return ""
+	},
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+:root {
+  --primary: {{index .Palette "primary"}}; --secondary: {{index .Palette "secondary"}}; --accent: {{index .Palette "accent"}};
+  --success: {{index .Palette "success"}}; --error: {{index .Palette "error"}}; --info: {{index .Palette "info"}};
+  --muted: {{index .Palette "muted"}}; --subtle: {{index .Palette "subtle"}}; --border: {{index .Palette "border"}};
+  --text: {{index .Palette "text"}}; --dim: {{index .Palette "dim"}};
+}
+body { background: #111827; color: var(--text); font: 15px/1.55 system-ui, sans-serif; max-width: 960px; margin: 2rem auto; padding: 0 1rem; }
+h1 { color: var(--primary); }
+h2 { margin-top: 2rem; font-size: 1rem; text-transform: uppercase; letter-spacing: .05em; }
+h2.user { color: var(--accent); }
+h2.assistant { color: var(--secondary); }
+table.facts td { padding: .15rem 1rem .15rem 0; }
+table.facts td:first-child { color: var(--muted); }
+pre, code { font: 13px/1.45 ui-monospace, monospace; }
+pre { background: #0b0f19; border: 1px solid var(--subtle); border-radius: 6px; padding: .75rem; overflow-x: auto; }
+.badge { display: inline-block; padding: 0 .5rem; border-radius: 4px; font-weight: bold; font-size: 13px; }
+.tool { background: var(--primary); color: #fff; }
+.result { background: var(--info); color: #fff; }
+.path { color: var(--dim); }
+.add { color: var(--success); }
+.del { color: var(--error); }
+details { margin: .75rem 0; }
+summary { cursor: pointer; color: var(--muted); }
+details.thinking div { color: var(--muted); border-left: 2px solid var(--subtle); padding-left: .75rem; }
+.note { color: var(--muted); font-style: italic; }
+ul.todos { list-style: none; padding-left: 0; }
+.completed { color: var(--dim); text-decoration: line-through; }
+.in_progress { color: var(--accent); font-weight: bold; }
+a { color: var(--secondary); }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table class="facts">
+{{- range .Facts}}
+<tr><td>{{index . 0}}</td><td>{{index . 1}}</td></tr>
+{{- end}}
+</table>
+{{- $entries := .Entries}}
+{{- range $i, $e := .Entries}}
+{{- with startsTurn $entries $i}}
+<h2 class="{{if eq . "User"}}user{{else}}assistant{{end}}">{{.}}</h2>
+{{- end}}
+{{- if or (eq $e.Kind "user") (eq $e.Kind "assistant")}}
+<div class="{{$e.Kind}}">{{markdown $e.Text}}</div>
+{{- else if eq $e.Kind "thinking"}}
+<details class="thinking"><summary>Thinking</summary><div>{{markdown $e.Text}}</div></details>
+{{- else if eq $e.Kind "tool_call"}}
+<p><span class="badge tool">{{$e.Tool}}</span>{{with $e.Text}} <code class="path">{{.}}</code>{{end}}</p>
+{{- if $e.Diff}}
+<pre>{{range lines $e.Diff}}<span class="{{diffClass .}}">{{.}}</span>
+{{end}}</pre>
+{{- else if $e.Input}}
+<pre>{{$e.Input}}</pre>
+{{- end}}
+{{- if $e.Truncated}}<p class="note">(truncated)</p>{{end}}
+{{- else if eq $e.Kind "tool_result"}}
+<details><summary><span class="badge result">{{$e.Tool}}</span> result</summary><pre>{{$e.Text}}</pre>
+{{- if $e.Truncated}}<p class="note">(output truncated)</p>{{end}}</details>
+{{- else if eq $e.Kind "todos"}}
+{{template "todos" $e.Todos}}
+{{- end}}
+{{- end}}
+{{- if .Todos}}
+<h2>Final todos</h2>
+{{template "todos" .Todos}}
+{{- end}}
+</body>
+</html>
+{{define "todos"}}<ul class="todos">
+{{- range .}}
+<li class="{{.Status}}">{{if eq .Status "completed"}}☑{{else}}☐{{end}} {{.Content}}</li>
+{{- end}}
+</ul>{{end}}
+`))
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+func exportFixture() *SessionFile {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &SessionFile{
+		Meta: SessionMeta{
+			ID: "0193-export", Model: "test-model", Title: "Rename the config loader", CreatedAt: at, UpdatedAt: at.Add(time.Hour),
+			Workspace: Workspace{Cwd: "/src/app", GitRoot: "/src/app", Branch: "main", Commit: "0123456789abcdef0123456789abcdef01234567"},
+		},
+		Messages: []claude.MessageParam{
+			{Role: "user", Content: "rename `loadCfg` to **loadConfig** <script>alert(1)</script>"},
+			{Role: "assistant", Content: []claude.ContentBlock{
+				{Type: "thinking", Thinking: "Find the definition first.", Signature: "sig"},
+				{Type: "text", Text: "I'll track this and make the edit."},
+				{Type: "tool_use", ID: "t1", Name: "TodoWrite", Input: json.RawMessage(`{"todos":[{"content":"Rename loader","active_form":"Renaming loader","status":"in_progress"}]}`)},
+				{Type: "tool_use", ID: "t2", Name: "ReplaceText", Input: json.RawMessage(`{"path":"config.go","old_text":"func loadCfg() {","new_text":"func loadConfig() {"}`)},
+				{Type: "tool_use", ID: "t3", Name: "Bash", Input: json.RawMessage(`{"command":"go build ./..."}`)},
+			}},
+			{Role: "user", Content: []claude.ToolResultBlock{
+				{Type: "tool_result", ToolUseID: "t1", Content: "todos updated"},
+				{Type: "tool_result", ToolUseID: "t2", Content: "replaced"},
+				{Type: "tool_result", ToolUseID: "t3", Content: "\x1b[31mok\x1b[0m\n" + strings.Repeat("line\n", 50)},
+			}},
+			{Role: "assistant", Content: []claude.ContentBlock{{Type: "text", Text: "Renamed. ```go\nloadConfig()\n```"}}},
+		},
+		MessageModels: map[int]string{1: "test-model", 3: "test-model"},
+		Todos:         []tools.Todo{{Content: "Rename loader", ActiveForm: "Renaming loader", Status: "completed"}},
+		Usage:         claude.Usage{InputTokens: 1200, OutputTokens: 80},
+	}
+}
+
+func TestExportSession_Markdown(t *testing.T) {
+	// given
+	sess := exportFixture()
+
+	// when
+	var buf bytes.Buffer
+	err := exportSession(&buf, sess, exportMarkdown)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertGolden(t, "export.golden.md", buf.Bytes())
+}
+
+func TestExportSession_HTML(t *testing.T) {
+	// when
+	var buf bytes.Buffer
+	err := exportSession(&buf, exportFixture(), exportHTML)
+
+	// then - self-contained and escaped
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	html := buf.String()
+	if strings.Contains(html, "<script>") || strings.Contains(html, "src=") || strings.Contains(html, "href=\"http") {
+		t.Error("expected no scripts or external resources")
+	}
+	if !strings.Contains(html, tools.Palette["primary"]) {
+		t.Error("expected palette colors inlined")
+	}
+	assertGolden(t, "export.golden.html", buf.Bytes())
+}
+
+func TestExportSession_JSON(t *testing.T) {
+	// when
+	var buf bytes.Buffer
+	err := exportSession(&buf, exportFixture(), exportJSON)
+	var doc exportDocument
+	json.Unmarshal(buf.Bytes(), &doc)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var kinds []string
+	for _, e := range doc.Entries {
+		kinds = append(kinds, e.Kind)
+	}
+	want := "user thinking assistant todos tool_call tool_call tool_result tool_result tool_result assistant"
+	if strings.Join(kinds, " ") != want {
+		t.Errorf("expected entries %q, got %q", want, strings.Join(kinds, " "))
+	}
+	bash := doc.Entries[8]
+	if bash.Tool != "Bash" || !bash.Truncated || strings.Contains(bash.Text, "\x1b") || strings.Count(bash.Text, "\n") != exportMaxLines-1 {
+		t.Errorf("expected truncated, unstyled Bash output, got %+v", bash)
+	}
+	if doc.Entries[4].Diff != "-func loadCfg() {\n+func loadConfig() {" {
+		t.Errorf("unexpected diff: %q", doc.Entries[4].Diff)
+	}
+}
+
+func TestExportSession_UnknownFormat(t *testing.T) {
+	if err := exportSession(&bytes.Buffer{}, exportFixture(), "pdf"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// forkPoints lists the message counts a session can be cut at: before each
+// typed user message, and at its end. Cutting anywhere else would split a
+// tool call from its result.
+func forkPoints(sess *SessionFile) []int {
+	var points []int
+	for i, m := range sess.Messages {
+		if m.Role == "user" && !isToolResultMessage(m) {
+			points = append(points, i)
+		}
+	}
+	return append(points, len(sess.Messages))
+}
+
+// forkSession clones the first upTo messages (all when upTo < 0) of the
+// session id recorded in records into a new session whose meta points back
+// at it. Todos and modes are those recorded before message upTo, so a fork
+// from early on does not inherit state from the end.
+func forkSession(id string, records []Record, upTo int, now time.Time) (*SessionFile, error) {
+	src := replaySession(id, records)
+	if upTo < 0 {
+		upTo = len(src.Messages)
+	}
+	if points := forkPoints(src); !slices.Contains(points, upTo) {
+		return nil, fmt.Errorf("cannot fork %s at message %d: must be one of %v", id, upTo, points)
+	}
+	state := replaySession(id, recordsBefore(records, upTo))
+
+	fork := &SessionFile{
+		Meta:            src.Meta,
+		Messages:        slices.Clone(src.Messages[:upTo]),
+		Todos:           state.Todos,
+		PlanMode:        state.PlanMode,
+		PermissionsMode: state.PermissionsMode,
+	}
+	fork.Meta.ID = newSessionID()
+	fork.Meta.CreatedAt, fork.Meta.UpdatedAt = now, now
+	fork.Meta.Parent, fork.Meta.ForkedAt = id, upTo
+	if len(src.MessageModels) > 0 {
+		fork.MessageModels = maps.Clone(src.MessageModels)
+		maps.DeleteFunc(fork.MessageModels, func(i int, _ string) bool { return i >= upTo })
+	}
+	return fork, nil
+}
+
+// recordsBefore returns the records written before message n
+func recordsBefore(records []Record, n int) []Record {
+	messages := 0
+	for i, r := range records {
+		if r.Message != nil && (r.Type == recordUser || r.Type == recordAssistant || r.Type == recordToolResult) {
+			if messages == n {
+				return records[:i]
+			}
+			messages++
+		}
+	}
+	return records
+}
+
+// createFork forks session id on disk and returns the new session
+func createFork(id string, upTo int) (*SessionFile, error) {
+	records, err := readSessionRecords(id)
+	if err != nil {
+		return nil, fmt.Errorf("loading session: %w", err)
+	}
+	fork, err := forkSession(id, records, upTo, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	path, err := sessionPath(fork.Meta.ID)
+	if err != nil {
+		return nil, err
+	}
+	records = sessionRecords(fork)
+	if err := writeTranscriptAtomic(path, records); err != nil {
+		return nil, fmt.Errorf("writing fork: %w", err)
+	}
+	updateSearchIndex(fork.Meta.ID, 0, records)
+	return fork, nil
+}
+
+// sessionRow is one line of the session tree
+type sessionRow struct {
+	sess  *SessionFile
+	depth int
+}
+
+// sessionTree orders sessions as a fork tree: roots in the given order, each
+// followed by its forks (depth-first, same order). Sessions whose parent is
+// not listed are roots.
+func sessionTree(sessions []SessionFile) []sessionRow {
+	listed := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		listed[s.Meta.ID] = true
+	}
+	children := make(map[string][]*SessionFile)
+	var roots []*SessionFile
+	for i := range sessions {
+		s := &sessions[i]
+		if s.Meta.Parent != "" && listed[s.Meta.Parent] && s.Meta.Parent != s.Meta.ID {
+			children[s.Meta.Parent] = append(children[s.Meta.Parent], s)
+		} else {
+			roots = append(roots, s)
+		}
+	}
+
+	var rows []sessionRow
+	visited := make(map[string]bool)
+	var walk func(s *SessionFile, depth int)
+	walk = func(s *SessionFile, depth int) {
+		if visited[s.Meta.ID] {
+			return
+		}
+		visited[s.Meta.ID] = true
+		rows = append(rows, sessionRow{s, depth})
+		for _, c := range children[s.Meta.ID] {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	for i := range sessions {
+		walk(&sessions[i], 0) // parent cycles have no root
+	}
+	return rows
+}
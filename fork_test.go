@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+func forkSource() *SessionFile {
+	return &SessionFile{
+		Meta: SessionMeta{ID: "src", Model: "m", Title: "Fix the parser"},
+		Messages: []claude.MessageParam{
+			{Role: "user", Content: "fix the parser"},
+			{Role: "assistant", Content: []claude.ContentBlock{{Type: "tool_use", ID: "t1", Name: "Read", Input: []byte(`{}`)}}},
+			{Role: "user", Content: []claude.ToolResultBlock{{Type: "tool_result", ToolUseID: "t1", Content: "code"}}},
+			{Role: "assistant", Content: "fixed"},
+			{Role: "user", Content: "now add tests"},
+			{Role: "assistant", Content: "added"},
+		},
+		MessageModels: map[int]string{1: "m", 3: "m", 5: "other"},
+		Todos:         []tools.Todo{{Content: "a", ActiveForm: "doing a", Status: "pending"}},
+	}
+}
+
+// forkRecords is forkSource's transcript, with its todos written and plan
+// mode entered during the second exchange
+func forkRecords() []Record {
+	plan := true
+	return append(sessionRecords(forkSource()), Record{Type: recordMode, PlanMode: &plan})
+}
+
+func TestForkSession(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("truncated at a user message", func(t *testing.T) {
+		// when
+		fork, err := forkSession("src", forkRecords(), 4, now)
+
+		// then
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fork.Meta.ID == "src" || fork.Meta.Parent != "src" || fork.Meta.ForkedAt != 4 || !fork.Meta.CreatedAt.Equal(now) {
+			t.Errorf("unexpected fork meta: %+v", fork.Meta)
+		}
+		if len(fork.Messages) != 4 || len(fork.MessageModels) != 2 || fork.MessageModels[5] != "" {
+			t.Errorf("expected 4 messages and their models, got %d / %v", len(fork.Messages), fork.MessageModels)
+		}
+		if fork.Meta.Title != "Fix the parser" {
+			t.Errorf("expected title carried over, got %+v", fork.Meta)
+		}
+		if len(fork.Todos) != 0 || fork.PlanMode {
+			t.Errorf("expected no todos or plan mode from after the fork point, got %+v / %v", fork.Todos, fork.PlanMode)
+		}
+	})
+
+	t.Run("whole session", func(t *testing.T) {
+		fork, err := forkSession("src", forkRecords(), -1, now)
+		if err != nil || len(fork.Messages) != 6 || fork.Meta.ForkedAt != 6 {
+			t.Fatalf("expected full copy, got %v / %+v", err, fork)
+		}
+		if len(fork.Todos) != 1 || !fork.PlanMode {
+			t.Errorf("expected todos and plan mode carried over, got %+v / %v", fork.Todos, fork.PlanMode)
+		}
+	})
+
+	t.Run("cut between tool call and result", func(t *testing.T) {
+		_, err := forkSession("src", forkRecords(), 2, now)
+		if err == nil || !strings.Contains(err.Error(), "[0 4 6]") {
+			t.Errorf("expected error listing fork points, got %v", err)
+		}
+	})
+}
+
+func TestSessionTree(t *testing.T) {
+	// given - most recent first; e's parent is not listed; x and y form a cycle
+	session := func(id, parent string) SessionFile {
+		return SessionFile{Meta: SessionMeta{ID: id, Parent: parent}}
+	}
+	sessions := []SessionFile{
+		session("c", "a"), session("a", ""), session("e", "gone"),
+		session("d", "c"), session("b", "a"), session("x", "y"), session("y", "x"),
+	}
+
+	// when
+	rows := sessionTree(sessions)
+
+	// then
+	var got []string
+	for _, r := range rows {
+		got = append(got, strings.Repeat(">", r.depth)+r.sess.Meta.ID)
+	}
+	want := "a >c >>d >b e x >y"
+	if strings.Join(got, " ") != want {
+		t.Errorf("expected %q, got %q", want, strings.Join(got, " "))
+	}
+}
+
+func TestAgent_Fork(t *testing.T) {
+	// given - a saved two-exchange session
+	useTempSessionDir(t)
+	todos := []tools.Todo{}
+	agent, _ := NewAgent("orig", nil, &claude.Client{}, nil, "", "m", nil, &todos)
+	agent.messages = forkSource().Messages
+	agent.Save()
+	todos = append(todos, tools.Todo{Content: "b", ActiveForm: "doing b", Status: "pending"}) // TodoWrite after the fork point
+	agent.Save()
+
+	// when - fork before the second prompt, then keep talking
+	err := agent.Fork(4)
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "user", Content: "try another approach"})
+	saveErr := agent.Save()
+
+	// then
+	if err != nil || saveErr != nil {
+		t.Fatalf("unexpected errors: %v / %v", err, saveErr)
+	}
+	if agent.sessionID == "orig" || agent.parent != "orig" {
+		t.Fatalf("expected agent moved to a fork of orig, got %s (parent %q)", agent.sessionID, agent.parent)
+	}
+	orig, _ := loadSession("orig")
+	fork, _ := loadSession(agent.sessionID)
+	if len(orig.Messages) != 6 {
+		t.Errorf("expected original untouched, got %d messages", len(orig.Messages))
+	}
+	if len(fork.Messages) != 5 || fork.Meta.Parent != "orig" || fork.Meta.ForkedAt != 4 {
+		t.Errorf("unexpected fork: %d messages, meta %+v", len(fork.Messages), fork.Meta)
+	}
+	if len(todos) != 0 || len(fork.Todos) != 0 || len(orig.Todos) != 1 {
+		t.Errorf("expected the fork without the later todo, got %+v / %+v", todos, fork.Todos)
+	}
+	if got := loadSearchIndex().Sessions[agent.sessionID].Messages; got != 5 {
+		t.Errorf("expected the fork's 5 messages indexed, got %d", got)
+	}
+}
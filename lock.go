@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// errSessionLocked means another process has the session open
+var errSessionLocked = errors.New("session is open in another process")
+
+const lockExt = ".lock"
+
+// sessionLock is an advisory lock held by the process writing a session.
+// It goes away with the process, so a crash never leaves a session stuck.
+type sessionLock struct {
+	id string
+	f  *os.File
+}
+
+func lockPath(id string) (string, error) {
+	if err := checkSessionID(id); err != nil {
+		return "", err
+	}
+	return filepath.Join(sessionDir, id+lockExt), nil
+}
+
+// lockSession takes session id's lock without waiting, returning
+// errSessionLocked (wrapped with the holder's PID) when it is taken
+func lockSession(id string) (*sessionLock, error) {
+	path, err := lockPath(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return nil, err
+	}
+	var f *os.File
+	for {
+		f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		if err := tryLock(f); err != nil {
+			f.Close()
+			if errors.Is(err, errSessionLocked) {
+				if pid := lockHolder(id); pid != 0 {
+					return nil, fmt.Errorf("%w (pid %d)", errSessionLocked, pid)
+				}
+			}
+			return nil, err
+		}
+		// the previous holder may have removed the file after we opened it;
+		// a lock on the removed file excludes no one, so start over
+		if isLockFile(f, path) {
+			break
+		}
+		f.Close()
+	}
+	// record who holds it, for the message other processes show
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return &sessionLock{id, f}, nil
+}
+
+// isLockFile reports whether f is still the file at path
+func isLockFile(f *os.File, path string) bool {
+	held, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	current, err := os.Stat(path)
+	return err == nil && os.SameFile(held, current)
+}
+
+// lockHolder reads the PID written by the lock's holder (0 if unknown)
+func lockHolder(id string) int {
+	path, err := lockPath(id)
+	if err != nil {
+		return 0
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// Release drops the lock. The lock file of a session that was never saved
+// is removed first, while still held; lockSession re-checks the file it
+// locked, so a process that opened it meanwhile does not keep a stale lock.
+func (l *sessionLock) Release() {
+	if l == nil || l.f == nil {
+		return
+	}
+	// l.id passed lockPath's check in lockSession
+	transcript, _ := sessionPath(l.id)
+	if _, err := os.Stat(transcript); errors.Is(err, os.ErrNotExist) {
+		lock, _ := lockPath(l.id)
+		os.Remove(lock) // never saved
+	}
+	unlock(l.f)
+	l.f.Close()
+	l.f = nil
+}
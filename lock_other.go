@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// Without flock, sessions are not locked; concurrent writers can still
+// interleave appends.
+func tryLock(f *os.File) error { return nil }
+
+func unlock(f *os.File) error { return nil }
@@ -0,0 +1,178 @@
+//go:build unix
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+func TestLockSession(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	held, err := lockSession("s1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// when - flock conflicts across open files, even in one process
+	_, err = lockSession("s1")
+
+	// then
+	if !errors.Is(err, errSessionLocked) || !strings.Contains(err.Error(), "pid") {
+		t.Fatalf("expected errSessionLocked naming the holder, got %v", err)
+	}
+	held.Release()
+	again, err := lockSession("s1")
+	if err != nil {
+		t.Fatalf("expected lock free after release, got %v", err)
+	}
+	again.Release()
+}
+
+func TestLockSession_SeesRemovedLockFile(t *testing.T) {
+	// given - another process opened the lock file of a never-saved
+	// session just before its holder released and removed it
+	useTempSessionDir(t)
+	held, _ := lockSession("s1")
+	path, _ := lockPath("s1")
+	opened, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer opened.Close()
+	held.Release()
+
+	// when
+	lockErr := tryLock(opened)
+	again, err := lockSession("s1")
+
+	// then - the removed file can be locked, but is not the lock file any more
+	if lockErr != nil || isLockFile(opened, path) {
+		t.Errorf("expected the removed file detected as stale, got lock err %v", lockErr)
+	}
+	if err != nil || !isLockFile(again.f, path) {
+		t.Fatalf("expected a fresh lock on the current file, got %v", err)
+	}
+	again.Release()
+}
+
+func TestClaimSession(t *testing.T) {
+	open := func(t *testing.T) *sessionLock {
+		useTempSessionDir(t)
+		saveSearchSession(t, "s1", claude.MessageParam{Role: "user", Content: "hello"})
+		lock, err := lockSession("s1")
+		if err != nil {
+			t.Fatalf("lock: %v", err)
+		}
+		t.Cleanup(lock.Release)
+		return lock
+	}
+
+	t.Run("free session", func(t *testing.T) {
+		useTempSessionDir(t)
+		id, lock, readOnly, err := claimSession("s1", bufio.NewReader(strings.NewReader("")), &strings.Builder{})
+		if err != nil || id != "s1" || lock == nil || readOnly {
+			t.Fatalf("expected s1 locked for writing, got %q %v %v %v", id, lock, readOnly, err)
+		}
+		lock.Release()
+	})
+
+	t.Run("fork", func(t *testing.T) {
+		open(t)
+		var out strings.Builder
+		id, lock, readOnly, err := claimSession("s1", bufio.NewReader(strings.NewReader("x\nf\n")), &out)
+		if err != nil || id == "s1" || lock == nil || readOnly {
+			t.Fatalf("expected a locked fork, got %q %v %v %v", id, lock, readOnly, err)
+		}
+		defer lock.Release()
+		if fork, _ := loadSession(id); fork == nil || fork.Meta.Parent != "s1" || len(fork.Messages) != 1 {
+			t.Errorf("expected full fork of s1, got %+v", fork)
+		}
+		if strings.Count(out.String(), "[f]ork") != 2 {
+			t.Errorf("expected the prompt repeated after bad input, got %q", out.String())
+		}
+	})
+
+	t.Run("read-only", func(t *testing.T) {
+		open(t)
+		id, lock, readOnly, err := claimSession("s1", bufio.NewReader(strings.NewReader("r\n")), &strings.Builder{})
+		if err != nil || id != "s1" || lock != nil || !readOnly {
+			t.Fatalf("expected s1 read-only, got %q %v %v %v", id, lock, readOnly, err)
+		}
+	})
+
+	t.Run("quit", func(t *testing.T) {
+		open(t)
+		_, _, _, err := claimSession("s1", bufio.NewReader(strings.NewReader("")), &strings.Builder{})
+		if !errors.Is(err, errPickerCanceled) {
+			t.Errorf("expected errPickerCanceled at EOF, got %v", err)
+		}
+	})
+}
+
+func TestAgent_ReadOnly(t *testing.T) {
+	// given - a session open elsewhere, resumed read-only with a new turn
+	useTempSessionDir(t)
+	saveSearchSession(t, "s1", claude.MessageParam{Role: "user", Content: "hello"}, claude.MessageParam{Role: "assistant", Content: "hi"})
+	held, _ := lockSession("s1")
+	defer held.Release()
+	sess, _ := loadSession("s1")
+	todos := []tools.Todo{}
+	agent, _ := NewAgent("s1", sess, &claude.Client{}, nil, "", "m", nil, &todos)
+	agent.readOnly = true
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "user", Content: "local question"})
+
+	// when
+	saveErr := agent.Save()
+
+	// then - nothing reaches the transcript
+	if saveErr != nil {
+		t.Fatalf("unexpected error: %v", saveErr)
+	}
+	if after, _ := loadSession("s1"); len(after.Messages) != 2 {
+		t.Errorf("expected transcript untouched, got %d messages", len(after.Messages))
+	}
+
+	// when - forking keeps the unsaved turn and makes the agent writable
+	err := agent.Fork(-1)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer agent.lock.Release()
+	fork, _ := loadSession(agent.sessionID)
+	if agent.readOnly || agent.lock == nil || len(fork.Messages) != 3 {
+		t.Errorf("expected writable fork with 3 messages, got readOnly=%v lock=%v messages=%d", agent.readOnly, agent.lock, len(fork.Messages))
+	}
+}
+
+func TestRemoveSession_KeepsOpenSession(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	saveSearchSession(t, "s1", claude.MessageParam{Role: "user", Content: "hello"})
+	held, _ := lockSession("s1")
+
+	// when
+	_, err := removeSession("s1")
+
+	// then
+	if !errors.Is(err, errSessionLocked) {
+		t.Fatalf("expected errSessionLocked, got %v", err)
+	}
+	held.Release()
+	if found, err := removeSession("s1"); !found || err != nil {
+		t.Fatalf("expected removal once released, got %v / %v", found, err)
+	}
+	lockFile, _ := lockPath("s1")
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		t.Errorf("expected lock file removed with the session, got %v", err)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -74,6 +75,54 @@ var (
 	sessionTodos []tools.Todo
 )
 
+// optionalString is a string flag that may also be given bare. The flag
+// package only allows that for bool flags, so a bare value reports "true".
+type optionalString struct {
+	set   bool
+	value string
+}
+
+func (o *optionalString) String() string { return o.value }
+
+func (o *optionalString) Set(s string) error {
+	o.set = true
+	if s != "true" {
+		o.value = s
+	}
+	return nil
+}
+
+func (o *optionalString) IsBoolFlag() bool { return true }
+
+// parseFlags parses args into fs. A bare optional flag stops parsing at the
+// value given after it, so that value is taken and the rest parsed again.
+// Arguments left over are an error rather than silently ignored.
+func parseFlags(fs *flag.FlagSet, opt *optionalString, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if opt.set && opt.value == "" && fs.NArg() > 0 {
+		opt.value = fs.Arg(0) // -resume <id>: bare flag, ID left as an argument
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return nil
+}
+
+// stringList is a flag that may be repeated
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
 var mdRenderer *glamour.TermRenderer
 
 func init() {
@@ -88,17 +137,71 @@ func init() {
 }
 
 func main() {
-	resumeFlag := flag.String("resume", "", "Resume a session by ID")
-	listFlag := flag.Bool("sessions", false, "List all sessions")
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		if err := RunBatch(os.Args[2:]); err != nil {
+			fmt.Println(tools.Error(err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		if err := RunDoctor(os.Stdout); err != nil {
+			fmt.Println(tools.Error(err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		if err := RunConfigCommand(os.Args[2:], os.Stdout); err != nil {
+			fmt.Println(tools.Error(err.Error()))
+			os.Exit(1)
+		}
+		return
+	}
+
+	var resumeFlag optionalString
+	flag.Var(&resumeFlag, "resume", "Resume a session by ID, or pick one interactively when given no ID")
+	continueFlag := flag.Bool("continue", false, "Resume the most recent session in this project")
+	forkFlag := flag.String("fork", "", "Continue in a copy of a session, by ID")
+	forkAtFlag := flag.Int("fork-at", -1, "With -fork: keep only the first N messages")
+	listFlag := flag.Bool("sessions", false, "List sessions of this project")
+	allFlag := flag.Bool("all", false, "With -sessions or -resume: include every project")
 	deleteFlag := flag.String("delete", "", "Delete a session by ID")
-	flag.Parse()
+	exportFlag := flag.String("export", "", "Write a session transcript to stdout, by ID")
+	formatFlag := flag.String("format", exportMarkdown, "With -export: md, html or json")
+	searchFlag := flag.String("search", "", "Search saved sessions for words")
+	pruneFlag := flag.Bool("prune", false, "Remove sessions outside the configured retention policy")
+	dryRunFlag := flag.Bool("dry-run", false, "With -prune: list what would be removed")
+	pinFlag := flag.String("pin", "", "Protect a session from -prune, by ID")
+	unpinFlag := flag.String("unpin", "", "Let -prune remove a session again, by ID")
+	replayFlag := flag.String("replay", "", "Re-render a saved session in the terminal, by ID")
+	paceFlag := flag.Float64("pace", 0, "With -replay: play on its own at this multiple of real time (0: step with enter)")
+	profileFlag := flag.String("profile", "", "Model profile from config (default: default_profile or env)")
+	flag.Var((*stringList)(&configOverrides), "c", "Override a config setting for this run, as key=value (repeatable)")
+	if err := parseFlags(flag.CommandLine, &resumeFlag, os.Args[1:]); err != nil {
+		fmt.Println(tools.Error(err.Error()))
+		os.Exit(2)
+	}
 
 	if err := os.MkdirAll(sessionDir, 0755); err != nil {
 		fmt.Println(tools.Error(fmt.Sprintf("creating session dir: %v", err)))
 		os.Exit(1)
 	}
 
-	shouldContinue, err := RunCLI(listFlag, deleteFlag, resumeFlag)
+	shouldContinue, err := RunCLI(CLIOptions{
+		List:   *listFlag,
+		All:    *allFlag,
+		Delete: *deleteFlag,
+		Export: *exportFlag,
+		Format: *formatFlag,
+		Search: *searchFlag,
+		Prune:  *pruneFlag,
+		DryRun: *dryRunFlag,
+		Pin:    *pinFlag,
+		Unpin:  *unpinFlag,
+		Replay: *replayFlag,
+		Pace:   *paceFlag,
+	})
 	if err != nil {
 		fmt.Println(tools.Error(err.Error()))
 		os.Exit(1)
@@ -107,7 +210,18 @@ func main() {
 		return
 	}
 
-	if err := AgentSession(resumeFlag); err != nil {
+	choice := SessionChoice{
+		ID:       resumeFlag.value,
+		Pick:     resumeFlag.set && resumeFlag.value == "",
+		Continue: *continueFlag,
+		All:      *allFlag,
+		Fork:     *forkFlag,
+		ForkAt:   *forkAtFlag,
+	}
+	if err := AgentSession(choice, profileFlag); err != nil {
+		if errors.Is(err, errPickerCanceled) {
+			return
+		}
 		fmt.Println(tools.Error(err.Error()))
 		os.Exit(1)
 	}
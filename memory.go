@@ -0,0 +1,227 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"simpleagent/tools"
+)
+
+// localMemoryFileName holds personal notes for a project, kept out of git
+const localMemoryFileName = "CLAUDE.local.md"
+
+// memoryFileNames are discovered in every directory from the repository
+// root down to cwd, and in subdirectories as files beneath them are read
+var memoryFileNames = []string{"CLAUDE.md", "AGENTS.md", localMemoryFileName}
+
+// memoryHome is where ~/.claude/CLAUDE.md and ~/ imports are found
+var memoryHome = os.Getenv("HOME")
+
+// userMemoryFile applies to every project
+func userMemoryFile() string {
+	return filepath.Join(memoryHome, ".claude", "CLAUDE.md")
+}
+
+// maxImportDepth caps how many @imports deep a memory file may pull in
+const maxImportDepth = 5
+
+// importPattern matches @path mentions at the start of a line or after
+// whitespace; a path needs a dot or slash, so @mentions of people are left
+var importPattern = regexp.MustCompile(`(?:^|\s)@((?:~/)?[^\s@]*[./][^\s@]*)`)
+
+// codePattern matches fenced blocks and inline code, where @ is literal
+var codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+
+// memoryBlock is one loaded memory file
+type memoryBlock struct {
+	source  string // as shown to the model
+	content string
+}
+
+// memoryLoader loads memory files and their imports, each at most once
+type memoryLoader struct {
+	cwd       string
+	root      string  // repository root, or cwd outside one
+	config    *Config // memory_files, resolved against configDir
+	configDir string
+	loaded    map[string]bool // absolute paths already in context
+	order     []string        // loaded paths, in the order loaded
+	problems  []error
+}
+
+func newMemoryLoader() *memoryLoader {
+	cwd, _ := os.Getwd()
+	root := currentWorkspace().Project()
+	if root == "" {
+		root = cwd
+	}
+	return &memoryLoader{cwd: cwd, root: root, config: &Config{}, loaded: make(map[string]bool)}
+}
+
+// discover lists the memory files that apply at startup, most general first:
+// the user file, then each directory from the root down to cwd, then any
+// configured files
+func (m *memoryLoader) discover() []string {
+	paths := []string{userMemoryFile()}
+	var dirs []string
+	for dir := m.cwd; ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == m.root || dir == filepath.Dir(dir) || !strings.HasPrefix(m.cwd, m.root) {
+			break
+		}
+	}
+	slices.Reverse(dirs)
+	for _, dir := range dirs {
+		for _, name := range memoryFileNames {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	for _, f := range m.config.MemoryFiles {
+		if m.configDir != "" && !filepath.IsAbs(f) {
+			f = filepath.Join(m.configDir, f)
+		}
+		paths = append(paths, f)
+	}
+	return paths
+}
+
+// read loads path and then what it imports. stack holds the importing
+// files; a missing file is only a problem when it was imported.
+func (m *memoryLoader) read(path string, stack []string) []memoryBlock {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	if i := slices.Index(stack, abs); i >= 0 {
+		chain := append(slices.Clone(stack[i:]), abs)
+		for j := range chain {
+			chain[j] = m.display(chain[j])
+		}
+		m.problems = append(m.problems, fmt.Errorf("memory import cycle: %s", strings.Join(chain, " → ")))
+		return nil
+	}
+	if m.loaded[abs] {
+		return nil
+	}
+	if len(stack) > maxImportDepth {
+		m.problems = append(m.problems, fmt.Errorf("%s: imports nested deeper than %d, skipping %s", m.display(stack[0]), maxImportDepth, m.display(abs)))
+		return nil
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		var pathErr *os.PathError
+		if errors.As(err, &pathErr) {
+			err = pathErr.Err
+		}
+		if len(stack) > 0 {
+			m.problems = append(m.problems, fmt.Errorf("%s: import %s: %w", m.display(stack[len(stack)-1]), m.display(abs), err))
+		}
+		return nil
+	}
+	m.loaded[abs] = true
+	m.order = append(m.order, abs)
+
+	var blocks []memoryBlock
+	content := strings.TrimSpace(string(data))
+	if content != "" {
+		blocks = append(blocks, memoryBlock{source: m.display(abs), content: content})
+	}
+	for _, imp := range parseImports(content) {
+		if rest, ok := strings.CutPrefix(imp, "~/"); ok {
+			imp = filepath.Join(memoryHome, rest)
+		} else if !filepath.IsAbs(imp) {
+			imp = filepath.Join(filepath.Dir(abs), imp)
+		}
+		blocks = append(blocks, m.read(imp, append(stack, abs))...)
+	}
+	return blocks
+}
+
+// carryOver marks the files prev loaded as loaded here too, except those in
+// stale, so a rebuilt loader does not add them to the context again
+func (m *memoryLoader) carryOver(prev *memoryLoader, stale []string) {
+	for _, abs := range prev.order {
+		if !m.loaded[abs] && !slices.Contains(stale, abs) {
+			m.loaded[abs] = true
+			m.order = append(m.order, abs)
+		}
+	}
+}
+
+// parseImports returns the @paths in content, outside code
+func parseImports(content string) []string {
+	var imports []string
+	for _, match := range importPattern.FindAllStringSubmatch(codePattern.ReplaceAllString(content, ""), -1) {
+		path := strings.TrimRight(match[1], ".,;:!?)")
+		if path != "" && !slices.Contains(imports, path) {
+			imports = append(imports, path)
+		}
+	}
+	return imports
+}
+
+// display shows abs relative to cwd, or under ~ when it lies in the home
+// directory outside the project
+func (m *memoryLoader) display(abs string) string {
+	if rel, err := filepath.Rel(memoryHome, abs); err == nil && !strings.HasPrefix(rel, "..") && !strings.HasPrefix(abs, m.root+string(filepath.Separator)) {
+		return filepath.Join("~", rel)
+	}
+	if rel, err := filepath.Rel(m.cwd, abs); err == nil {
+		return rel
+	}
+	return abs
+}
+
+// nested loads memory files in the directories between cwd and the file at
+// path that are not loaded yet, nearest to cwd first
+func (m *memoryLoader) nested(path string) []memoryBlock {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(m.cwd, filepath.Dir(abs))
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return nil // only directories beneath cwd; the rest loaded at startup
+	}
+	var blocks []memoryBlock
+	dir := m.cwd
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		dir = filepath.Join(dir, part)
+		for _, name := range memoryFileNames {
+			blocks = append(blocks, m.read(filepath.Join(dir, name), nil)...)
+		}
+	}
+	return blocks
+}
+
+// formatMemory wraps blocks for the model, returning their sources
+func formatMemory(blocks []memoryBlock) (string, []string) {
+	var contents, sources []string
+	for _, b := range blocks {
+		contents = append(contents, wrapXML("memory", b.source, b.content))
+		sources = append(sources, b.source)
+	}
+	return strings.Join(contents, "\n\n"), sources
+}
+
+// globalMemory tracks loaded memory for lazy loading (set during BuildSystemPrompt)
+var globalMemory *memoryLoader
+
+// GetNestedMemory returns memory files beneath cwd that apply to filePath
+// and were not loaded yet. Returns (memory content, loaded sources)
+func GetNestedMemory(filePath string) (string, []string) {
+	if globalMemory == nil {
+		return "", nil
+	}
+	globalMemory.problems = nil
+	content, sources := formatMemory(globalMemory.nested(filePath))
+	for _, err := range globalMemory.problems {
+		fmt.Println(tools.Warning(err.Error()))
+	}
+	return content, sources
+}
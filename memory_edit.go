@@ -0,0 +1,169 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"simpleagent/tools"
+)
+
+// memoryTarget is a memory file that # notes and /memory can write
+type memoryTarget struct {
+	key   string // answer that picks it
+	label string
+	path  string
+}
+
+// memoryTargets are the project, user and local memory files
+func memoryTargets() []memoryTarget {
+	root := currentWorkspace().Project()
+	return []memoryTarget{
+		{"p", "project", filepath.Join(root, "CLAUDE.md")},
+		{"u", "user", userMemoryFile()},
+		{"l", "local", filepath.Join(root, localMemoryFileName)},
+	}
+}
+
+// prepareMemoryFile creates the directory of a memory file about to be
+// written; a new local file is added to the repository's .gitignore
+func prepareMemoryFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	ws := currentWorkspace()
+	if filepath.Base(path) == localMemoryFileName && ws.GitRoot != "" && filepath.Dir(path) == ws.GitRoot {
+		return ensureIgnored(ws.GitRoot, localMemoryFileName)
+	}
+	return nil
+}
+
+// appendMemoryNote adds note to the memory file at path as a list item
+func appendMemoryNote(path, note string) error {
+	if err := prepareMemoryFile(path); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		data = append(data, '\n')
+	}
+	return writeFileAtomic(path, append(data, "- "+note+"\n"...))
+}
+
+// rememberNote asks which memory file note belongs in, appends it there and
+// reloads the system prompt
+func (a *Agent) rememberNote(note string) error {
+	if a.reader == nil {
+		return fmt.Errorf("no input to choose a memory file")
+	}
+	targets := memoryTargets()
+	fmt.Println(tools.Status("remember") + " " + note)
+	for _, t := range targets {
+		fmt.Println(tools.Dim(fmt.Sprintf("  [%s] %-8s %s", t.key, t.label, displayPath(t.path))))
+	}
+	fmt.Print(tools.Dim("save to (enter to cancel): "))
+	line, _ := a.reader.ReadString('\n')
+	answer := strings.ToLower(strings.TrimSpace(line))
+	for _, t := range targets {
+		if answer == t.key || answer == t.label {
+			if err := appendMemoryNote(t.path, note); err != nil {
+				return err
+			}
+			fmt.Println(tools.Success("saved to " + displayPath(t.path)))
+			return a.reloadSystemPrompt()
+		}
+	}
+	fmt.Println(tools.Dim("not saved"))
+	return nil
+}
+
+// editMemory lists the loaded memory files, plus the standard ones not yet
+// created, and opens the one picked in $VISUAL or $EDITOR
+func (a *Agent) editMemory() error {
+	if a.reader == nil {
+		return fmt.Errorf("no input to choose a memory file")
+	}
+	var paths []string
+	if globalMemory != nil {
+		paths = append(paths, globalMemory.order...)
+	}
+	for _, t := range memoryTargets() {
+		if !memoryLoaded(t.path) {
+			paths = append(paths, t.path)
+		}
+	}
+	for i, path := range paths {
+		line := fmt.Sprintf("  %d. %s", i+1, displayPath(path))
+		if _, err := os.Stat(path); err != nil {
+			line += " (new)"
+		}
+		fmt.Println(tools.Dim(line))
+	}
+	fmt.Print(tools.Dim("open (enter to cancel): "))
+	line, _ := a.reader.ReadString('\n')
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(paths) {
+		return fmt.Errorf("pick 1-%d", len(paths))
+	}
+	path := paths[n-1]
+	if err := prepareMemoryFile(path); err != nil {
+		return err
+	}
+	if err := openEditor(path); err != nil {
+		return err
+	}
+	return a.reloadSystemPrompt()
+}
+
+// memoryLoaded reports whether path is in the current system prompt
+func memoryLoaded(path string) bool {
+	return globalMemory != nil && globalMemory.loaded[path]
+}
+
+// displayPath shows path the way memory sources are shown
+func displayPath(path string) string {
+	if globalMemory != nil {
+		return globalMemory.display(path)
+	}
+	return newMemoryLoader().display(path)
+}
+
+// openEditor runs $VISUAL or $EDITOR (default vi) on path in the terminal
+func openEditor(path string) error {
+	editor := getEnvOrDefault("VISUAL", getEnvOrDefault("EDITOR", "vi"))
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path) // editor may carry flags, e.g. "code -w"
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", editor, err)
+	}
+	return nil
+}
+
+// reloadSystemPrompt rebuilds the system prompt so memory edits apply to
+// the next request, for the agent and its subagents. Subdirectory memory
+// already read into the conversation stays loaded unless it is stale.
+func (a *Agent) reloadSystemPrompt(stale ...string) error {
+	previous := globalMemory
+	prompt, loaded, _, err := BuildSystemPrompt()
+	if previous != nil && globalMemory != nil {
+		globalMemory.carryOver(previous, stale)
+	}
+	a.systemPrompt = prompt
+	tools.SetSubagentSystemPrompt(prompt)
+	if a.watcher != nil {
+		a.watcher.rescan()
+	}
+	fmt.Println(tools.Status("loaded") + " " + tools.Dim(fmt.Sprintf("%d memory file(s)", len(loaded))))
+	return err
+}
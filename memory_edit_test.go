@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// useTempMemory runs the test in a temp repository holding CLAUDE.md, with
+// user config and memory in temp dirs, and loads the system prompt
+func useTempMemory(t *testing.T, input string) (*Agent, string) {
+	t.Helper()
+	root := useTempRepo(t, "", map[string]string{"CLAUDE.md": "project memory"})
+	old := userConfigDir
+	userConfigDir = t.TempDir()
+	t.Cleanup(func() { userConfigDir, globalMemory = old, nil })
+	prompt, _, _, err := BuildSystemPrompt()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Agent{reader: bufio.NewReader(strings.NewReader(input)), systemPrompt: prompt}, root
+}
+
+func TestAppendMemoryNote(t *testing.T) {
+	// given - a file without a trailing newline
+	path := filepath.Join(t.TempDir(), "notes", "CLAUDE.md")
+	writeConfigFile(t, path, "# Notes")
+
+	// when
+	err1 := appendMemoryNote(path, "one")
+	err2 := appendMemoryNote(path, "two")
+
+	// then
+	data, _ := os.ReadFile(path)
+	if err1 != nil || err2 != nil || string(data) != "# Notes\n- one\n- two\n" {
+		t.Errorf("unexpected %q %v %v", data, err1, err2)
+	}
+}
+
+func TestAgent_HandleInput_RememberNote(t *testing.T) {
+	// given
+	agent, root := useTempMemory(t, "l\n")
+
+	// when
+	shouldInfer, err := agent.HandleInput("# run tests with -race")
+
+	// then
+	if err != nil || shouldInfer {
+		t.Fatalf("expected a handled command, got %v %v", shouldInfer, err)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, localMemoryFileName))
+	if string(data) != "- run tests with -race\n" {
+		t.Errorf("expected the note in %s, got %q", localMemoryFileName, data)
+	}
+	ignore, _ := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if !strings.Contains(string(ignore), localMemoryFileName) {
+		t.Errorf("expected %s git-ignored, got %q", localMemoryFileName, ignore)
+	}
+	if !strings.Contains(agent.systemPrompt, "run tests with -race") || !strings.Contains(agent.systemPrompt, "project memory") {
+		t.Errorf("expected the system prompt reloaded, got %q", agent.systemPrompt)
+	}
+}
+
+func TestAgent_HandleInput_RememberNoteCanceled(t *testing.T) {
+	// given
+	agent, root := useTempMemory(t, "\n")
+	before := agent.systemPrompt
+
+	// when
+	_, err := agent.HandleInput("#  ")
+	_, err2 := agent.HandleInput("# keep it")
+
+	// then
+	if err == nil {
+		t.Error("expected usage error for an empty note")
+	}
+	if err2 != nil || agent.systemPrompt != before {
+		t.Errorf("expected nothing saved, got %v", err2)
+	}
+	if _, err := os.Stat(filepath.Join(root, localMemoryFileName)); !os.IsNotExist(err) {
+		t.Errorf("expected no local memory file, got %v", err)
+	}
+}
+
+func TestAgent_HandleInput_MemoryEditor(t *testing.T) {
+	// given - an editor that appends a line
+	agent, root := useTempMemory(t, "1\n")
+	editor := filepath.Join(t.TempDir(), "editor")
+	writeConfigFile(t, editor, "#!/bin/sh\necho 'edited in editor' >> \"$1\"\n")
+	os.Chmod(editor, 0755)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", editor)
+
+	// when
+	var err error
+	out := captureStdout(t, func() { _, err = agent.HandleInput("/memory") })
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "1. CLAUDE.md") || !strings.Contains(out, localMemoryFileName+" (new)") {
+		t.Errorf("expected loaded and new memory files listed, got:\n%s", out)
+	}
+	data, _ := os.ReadFile(filepath.Join(root, "CLAUDE.md"))
+	if !strings.HasPrefix(string(data), "project memory") || !strings.HasSuffix(string(data), "edited in editor\n") {
+		t.Errorf("expected the editor to run on CLAUDE.md, got %q", data)
+	}
+	if !strings.Contains(agent.systemPrompt, "edited in editor") {
+		t.Errorf("expected the system prompt reloaded, got %q", agent.systemPrompt)
+	}
+}
+
+func TestAgent_ReloadSystemPrompt_KeepsNestedMemory(t *testing.T) {
+	// given - subdirectory memory already read into the conversation
+	agent, root := useTempMemory(t, "")
+	nested := filepath.Join(root, "pkg", "CLAUDE.md")
+	writeConfigFile(t, nested, "pkg memory")
+	if content, _ := GetNestedMemory("pkg/main.go"); content == "" {
+		t.Fatal("expected pkg memory loaded")
+	}
+
+	// when
+	captureStdout(t, func() { agent.reloadSystemPrompt() })
+
+	// then - not added again by the next read
+	if content, _ := GetNestedMemory("pkg/main.go"); content != "" {
+		t.Errorf("expected pkg memory kept loaded, got %q", content)
+	}
+
+	// when - the file changed
+	captureStdout(t, func() { agent.reloadSystemPrompt(nested) })
+
+	// then - the next read brings in the new version
+	if content, _ := GetNestedMemory("pkg/main.go"); !strings.Contains(content, "pkg memory") {
+		t.Errorf("expected stale pkg memory loaded again, got %q", content)
+	}
+}
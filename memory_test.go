@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// useTempRepo makes a git repository with the given files, points the
+// user home at a temp dir and runs the test from dir inside the repository
+func useTempRepo(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	old := memoryHome
+	memoryHome = t.TempDir()
+	t.Cleanup(func() { memoryHome = old })
+	root := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", root).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v %s", err, out)
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if rest, ok := strings.CutPrefix(name, "~/"); ok {
+			path = filepath.Join(memoryHome, rest)
+		}
+		writeConfigFile(t, path, content)
+	}
+	t.Chdir(filepath.Join(root, dir))
+	return root
+}
+
+func TestGetMemoryFilesContent_Discovery(t *testing.T) {
+	// given
+	useTempRepo(t, "pkg/api", map[string]string{
+		"~/.claude/CLAUDE.md":  "user prefs",
+		"CLAUDE.md":            "repo rules",
+		"pkg/AGENTS.md":        "pkg rules",
+		"pkg/api/CLAUDE.md":    "api rules",
+		"pkg/api/v2/AGENTS.md": "not loaded until read",
+	})
+
+	// when
+	content, loaded, errs := GetMemoryFilesContent(&Config{MemoryFiles: DefaultMemoryFiles()}, "")
+
+	// then - most general first, and CLAUDE.md in cwd only once
+	want := []string{"~/.claude/CLAUDE.md", "../../CLAUDE.md", "../AGENTS.md", "CLAUDE.md"}
+	if !slices.Equal(loaded, want) || len(errs) != 0 {
+		t.Errorf("expected %q, got %q %v", want, loaded, errs)
+	}
+	if strings.Contains(content, "not loaded until read") {
+		t.Error("expected subdirectory memory left for later")
+	}
+}
+
+func TestGetMemoryFilesContent_Imports(t *testing.T) {
+	// given
+	files := map[string]string{
+		"CLAUDE.md":     "See @docs/style.md and @docs/missing.md.\nNot `@code.md` or bob@example.com or @alice\n```\n@fenced.md\n```",
+		"docs/style.md": "style, back to @../CLAUDE.md",
+		"AGENTS.md":     "also @docs/style.md and @~/notes.md",
+		"~/notes.md":    "notes",
+	}
+	for i := range maxImportDepth + 2 {
+		files[fmt.Sprintf("deep/%d.md", i)] = fmt.Sprintf("@%d.md", i+1)
+	}
+	files["AGENTS.md"] += " @deep/0.md"
+	useTempRepo(t, "", files)
+
+	// when
+	_, loaded, errs := GetMemoryFilesContent(&Config{}, "")
+
+	// then
+	want := []string{"CLAUDE.md", "docs/style.md", "AGENTS.md", "~/notes.md", "deep/0.md", "deep/1.md", "deep/2.md", "deep/3.md", "deep/4.md"}
+	if !slices.Equal(loaded, want) {
+		t.Errorf("expected %q, got %q", want, loaded)
+	}
+	var problems []string
+	for _, err := range errs {
+		problems = append(problems, err.Error())
+	}
+	for _, w := range []string{"memory import cycle: CLAUDE.md → docs/style.md → CLAUDE.md", "CLAUDE.md: import docs/missing.md: no such file or directory", "AGENTS.md: imports nested deeper than 5, skipping deep/5.md"} {
+		if !slices.ContainsFunc(problems, func(p string) bool { return strings.Contains(p, w) }) {
+			t.Errorf("expected a problem containing %q, got %q", w, problems)
+		}
+	}
+}
+
+func TestGetNestedMemory(t *testing.T) {
+	// given
+	useTempRepo(t, "", map[string]string{
+		"CLAUDE.md":          "repo",
+		"pkg/CLAUDE.md":      "pkg, see @notes.md",
+		"pkg/notes.md":       "pkg notes",
+		"pkg/api/AGENTS.md":  "api",
+		"pkg/api/handler.go": "package api",
+		"other/CLAUDE.md":    "other",
+	})
+	GetMemoryFilesContent(&Config{}, "")
+	t.Cleanup(func() { globalMemory = nil })
+
+	// when
+	content, sources := GetNestedMemory("pkg/api/handler.go")
+	again, _ := GetNestedMemory("./pkg/api/other.go")
+
+	// then
+	want := []string{"pkg/CLAUDE.md", "pkg/notes.md", "pkg/api/AGENTS.md"}
+	if !slices.Equal(sources, want) || !strings.Contains(content, "pkg notes") {
+		t.Errorf("expected %q, got %q", want, sources)
+	}
+	if again != "" {
+		t.Errorf("expected memory loaded only once, got %q", again)
+	}
+	if content, _ := GetNestedMemory("README.md"); content != "" {
+		t.Errorf("expected nothing for files in cwd, got %q", content)
+	}
+}
+
+func TestParseImports(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"@a.md", []string{"a.md"}},
+		{"see @docs/a.md, then @~/b.md.", []string{"docs/a.md", "~/b.md"}},
+		{"mail a@b.com or ping @alice", nil},
+		{"`@inline.md` and\n```\n@fenced.md\n```", nil},
+		{"@a.md twice @a.md", []string{"a.md"}},
+	}
+	for _, tt := range tests {
+		if got := parseImports(tt.content); !slices.Equal(got, tt.want) {
+			t.Errorf("%q: expected %q, got %q", tt.content, tt.want, got)
+		}
+	}
+}
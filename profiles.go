@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"simpleagent/claude"
+)
+
+// envProfileName names the implicit profile built from ANTHROPIC_* env vars
+const envProfileName = "env"
+
+// Profile is a named model endpoint in config. base_url and
+// api_key_command are ignored in the shared project config.
+type Profile struct {
+	Model         string   `json:"model"`
+	BaseURL       string   `json:"base_url,omitempty"`
+	APIKeyEnv     string   `json:"api_key_env,omitempty"`     // env var holding the API key
+	APIKeyCommand string   `json:"api_key_command,omitempty"` // shell command printing the API key
+	Pricing       *Pricing `json:"pricing,omitempty"`
+	ModelConfig
+}
+
+// Pricing is USD per million tokens
+type Pricing struct {
+	InputPerMTok  float64 `json:"input_per_mtok"`
+	OutputPerMTok float64 `json:"output_per_mtok"`
+}
+
+// Cost prices usage in USD (0 without pricing)
+func (p *Pricing) Cost(u claude.Usage) float64 {
+	if p == nil {
+		return 0
+	}
+	return (float64(u.InputTokens)*p.InputPerMTok + float64(u.OutputTokens)*p.OutputPerMTok) / 1e6
+}
+
+// ResolvedProfile is a profile with defaults filled in
+type ResolvedProfile struct {
+	Name string
+	Profile
+}
+
+// ProfileNames returns configured profile names, sorted
+func (c *Config) ProfileNames() []string {
+	var names []string
+	if c != nil {
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ResolveProfile looks up a profile by name. An empty name selects
+// default_profile, or the env profile (ANTHROPIC_BASE_URL/ANTHROPIC_MODEL)
+// when none is configured. Unset limits fall back to models[<model>].
+func (c *Config) ResolveProfile(name string) (*ResolvedProfile, error) {
+	if name == "" && c != nil {
+		name = c.DefaultProfile
+	}
+
+	var p Profile
+	if name == "" || name == envProfileName {
+		name = envProfileName
+		p = Profile{Model: model, BaseURL: baseURL}
+	} else {
+		var ok bool
+		if c != nil {
+			p, ok = c.Profiles[name]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(append(c.ProfileNames(), envProfileName), ", "))
+		}
+		if p.Model == "" {
+			return nil, fmt.Errorf("profile %q has no model", name)
+		}
+	}
+
+	base := c.ModelConfigFor(p.Model)
+	if p.Provider == "" {
+		p.Provider = base.Provider
+	}
+	if p.MaxTokens <= 0 {
+		p.MaxTokens = base.MaxTokens
+	}
+	if p.ThinkingBudget == 0 {
+		p.ThinkingBudget = base.ThinkingBudget
+	}
+	if p.ContextWindow <= 0 {
+		p.ContextWindow = base.ContextWindow
+	}
+	if p.MaxContinuations == 0 {
+		p.MaxContinuations = base.MaxContinuations
+	}
+	if p.MaxOutputTokens <= 0 {
+		p.MaxOutputTokens = base.MaxOutputTokens
+	}
+	if p.Temperature == nil {
+		p.Temperature = base.Temperature
+	}
+	if p.TopP == nil {
+		p.TopP = base.TopP
+	}
+	if p.TopK == nil {
+		p.TopK = base.TopK
+	}
+	if p.StopSequences == nil {
+		p.StopSequences = base.StopSequences
+	}
+	if p.BaseURL == "" {
+		p.BaseURL = baseURL
+	}
+	return &ResolvedProfile{Name: name, Profile: p}, nil
+}
+
+// APIKey returns the key from api_key_command, api_key_env, or "" for the
+// client default (ANTHROPIC_API_KEY)
+func (p *ResolvedProfile) APIKey() (string, error) {
+	if p.APIKeyCommand != "" {
+		out, err := exec.Command("sh", "-c", p.APIKeyCommand).Output()
+		if err != nil {
+			return "", fmt.Errorf("profile %s: api_key_command: %w", p.Name, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+	if p.APIKeyEnv != "" {
+		key := os.Getenv(p.APIKeyEnv)
+		if key == "" {
+			return "", fmt.Errorf("profile %s: %s is not set", p.Name, p.APIKeyEnv)
+		}
+		return key, nil
+	}
+	return "", nil
+}
+
+// NewClient builds an API client for the profile
+func (p *ResolvedProfile) NewClient() (*claude.Client, error) {
+	provider, err := claude.NewProvider(p.Provider)
+	if err != nil {
+		return nil, fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	key, err := p.APIKey()
+	if err != nil {
+		return nil, err
+	}
+	opts := []claude.ClientOption{claude.WithBaseURL(p.BaseURL), claude.WithProvider(provider)}
+	if key != "" {
+		opts = append(opts, claude.WithAPIKey(key))
+	}
+	return claude.NewClient(opts...), nil
+}
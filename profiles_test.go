@@ -0,0 +1,155 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"simpleagent/claude"
+)
+
+func TestResolveProfile_Named(t *testing.T) {
+	// given - profile without limits, model with configured limits
+	cfg := &Config{
+		Models: map[string]ModelConfig{"qwen": {Provider: "openai", MaxTokens: 8192}},
+		Profiles: map[string]Profile{
+			"local": {Model: "qwen", BaseURL: "http://localhost:8080"},
+		},
+	}
+
+	// when
+	p, err := cfg.ResolveProfile("local")
+
+	// then
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if p.Name != "local" || p.Model != "qwen" || p.BaseURL != "http://localhost:8080" {
+		t.Errorf("unexpected profile: %+v", p)
+	}
+	if p.Provider != "openai" || p.MaxTokens != 8192 {
+		t.Errorf("expected model limits inherited, got provider=%q max=%d", p.Provider, p.MaxTokens)
+	}
+}
+
+func TestResolveProfile_DefaultAndEnv(t *testing.T) {
+	// given
+	cfg := &Config{Profiles: map[string]Profile{"fast": {Model: "m1"}}, DefaultProfile: "fast"}
+
+	// when
+	def, err1 := cfg.ResolveProfile("")
+	env, err2 := (&Config{}).ResolveProfile("")
+
+	// then
+	if err1 != nil || def.Name != "fast" {
+		t.Errorf("expected default profile 'fast', got %+v (%v)", def, err1)
+	}
+	if err2 != nil || env.Name != envProfileName || env.Model != model || env.MaxTokens != DefaultMaxTokens {
+		t.Errorf("expected env profile, got %+v (%v)", env, err2)
+	}
+}
+
+func TestResolveProfile_Unknown(t *testing.T) {
+	// given
+	cfg := &Config{}
+
+	// when
+	_, err := cfg.ResolveProfile("nope")
+
+	// then
+	if err == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestResolvedProfile_APIKey(t *testing.T) {
+	// given
+	t.Setenv("TEST_PROFILE_KEY", "from-env")
+	fromEnv := &ResolvedProfile{Name: "a", Profile: Profile{APIKeyEnv: "TEST_PROFILE_KEY"}}
+	fromCmd := &ResolvedProfile{Name: "b", Profile: Profile{APIKeyCommand: "echo from-cmd"}}
+
+	// when
+	k1, err1 := fromEnv.APIKey()
+	k2, err2 := fromCmd.APIKey()
+
+	// then
+	if err1 != nil || k1 != "from-env" {
+		t.Errorf("expected from-env, got %q (%v)", k1, err1)
+	}
+	if err2 != nil || k2 != "from-cmd" {
+		t.Errorf("expected from-cmd, got %q (%v)", k2, err2)
+	}
+}
+
+func TestAgent_HandleInput_ModelSwitch(t *testing.T) {
+	// given
+	agent := &Agent{
+		config: &Config{Profiles: map[string]Profile{"local": {Model: "qwen", BaseURL: "http://localhost:8080"}}},
+	}
+
+	// when
+	shouldInfer, err := agent.HandleInput("/model local")
+	_, unknownErr := agent.HandleInput("/model missing")
+
+	// then
+	if err != nil || shouldInfer {
+		t.Fatalf("expected switch without inference, got infer=%v err=%v", shouldInfer, err)
+	}
+	if agent.model != "qwen" || agent.profile != "local" || agent.client == nil {
+		t.Errorf("expected agent on profile local/qwen, got %s/%s", agent.profile, agent.model)
+	}
+	if unknownErr == nil {
+		t.Error("expected error for unknown profile")
+	}
+}
+
+func TestResolveProfile_InheritsModelSampling(t *testing.T) {
+	// given
+	temp, override := 0.7, 0.1
+	cfg := &Config{
+		Models: map[string]ModelConfig{"qwen": {Sampling: claude.Sampling{Temperature: &temp, TopK: claude.Int(20)}}},
+		Profiles: map[string]Profile{
+			"local": {Model: "qwen"},
+			"cold":  {Model: "qwen", ModelConfig: ModelConfig{Sampling: claude.Sampling{Temperature: &override}}},
+		},
+	}
+
+	// when
+	local, _ := cfg.ResolveProfile("local")
+	cold, _ := cfg.ResolveProfile("cold")
+
+	// then
+	if local.Temperature == nil || *local.Temperature != 0.7 || local.TopK == nil || *local.TopK != 20 {
+		t.Errorf("expected model sampling inherited, got %+v", local.Sampling)
+	}
+	if *cold.Temperature != 0.1 || cold.TopK == nil {
+		t.Errorf("expected profile temperature to win, got %+v", cold.Sampling)
+	}
+}
+
+func TestAgent_RecordUsage_Cost(t *testing.T) {
+	// given - $3 in and $15 out per million tokens
+	agent := &Agent{}
+	agent.ApplyProfile(&ResolvedProfile{Name: "priced", Profile: Profile{Model: "m", Pricing: &Pricing{InputPerMTok: 3, OutputPerMTok: 15}}}, nil)
+
+	// when
+	agent.recordUsage(&claude.Usage{InputTokens: 1000, OutputTokens: 100})
+	agent.recordUsage(&claude.Usage{InputTokens: 2000, OutputTokens: 200})
+
+	// then
+	if want := 0.0135; math.Abs(agent.cost-want) > 1e-9 {
+		t.Errorf("expected $%v, got $%v", want, agent.cost)
+	}
+	if prefix := agent.promptPrefix(); !strings.Contains(prefix, "$0.0135") {
+		t.Errorf("expected the cost before the prompt, got %q", prefix)
+	}
+
+	// when - a profile without pricing
+	agent.ApplyProfile(&ResolvedProfile{Name: "free", Profile: Profile{Model: "m"}}, nil)
+	agent.recordUsage(&claude.Usage{InputTokens: 1000, OutputTokens: 100})
+
+	// then
+	if want := 0.0135; math.Abs(agent.cost-want) > 1e-9 {
+		t.Errorf("expected the cost unchanged, got $%v", agent.cost)
+	}
+}
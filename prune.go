@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"simpleagent/tools"
+)
+
+// RetentionPolicy bounds what sessionDir keeps; zero fields are unlimited.
+// Pinned sessions are never pruned but count toward the limits.
+type RetentionPolicy struct {
+	MaxAgeDays  int `json:"max_age_days,omitempty"`
+	MaxSessions int `json:"max_sessions,omitempty"`
+	MaxTotalMB  int `json:"max_total_mb,omitempty"`
+}
+
+func (p RetentionPolicy) isZero() bool {
+	return p == RetentionPolicy{}
+}
+
+// sessionPaths lists what is on disk for session id
+func sessionPaths(id string) ([]string, error) {
+	transcript, err := sessionPath(id)
+	if err != nil {
+		return nil, err
+	}
+	legacy, _ := legacySessionPath(id) // same check as sessionPath
+	var paths []string
+	for _, p := range []string{transcript, legacy} {
+		if _, err := os.Lstat(p); err == nil {
+			paths = append(paths, p)
+		}
+	}
+	return paths, nil
+}
+
+// sessionSize is the bytes session id takes on disk
+func sessionSize(id string) int64 {
+	var size int64
+	paths, _ := sessionPaths(id) // an invalid id has nothing on disk
+	for _, p := range paths {
+		if info, err := os.Lstat(p); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// removeSession deletes everything on disk for session id, reporting
+// whether there was anything to delete. Sessions open elsewhere are kept.
+func removeSession(id string) (bool, error) {
+	paths, err := sessionPaths(id)
+	if err != nil {
+		return false, err
+	}
+	if len(paths) == 0 {
+		return false, nil
+	}
+	lock, err := lockSession(id)
+	if err != nil {
+		return true, err
+	}
+	defer lock.Release()
+	lockFile, _ := lockPath(id) // same check as sessionPaths
+	for _, p := range append(paths, lockFile) {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return true, err
+		}
+	}
+	if err := removeFromSearchIndex(id); err != nil {
+		// search drops missing sessions anyway
+		fmt.Println(tools.Warning(fmt.Sprintf("updating search index: %v", err)))
+	}
+	return true, nil
+}
+
+// setPinned pins or unpins session id, protecting it from pruning
+func setPinned(id string, pinned bool) error {
+	if _, err := loadSession(id); err != nil {
+		return fmt.Errorf("loading session: %w", err)
+	}
+	lock, err := lockSession(id)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+	return appendRecords(id, []Record{{Type: recordPin, Time: time.Now(), Pinned: &pinned}})
+}
+
+// pruneCandidate is a session the retention policy would remove
+type pruneCandidate struct {
+	sess   *SessionFile
+	size   int64
+	reason string
+}
+
+// planPrune picks the sessions policy removes. sessions must be newest
+// first; the newest unpinned sessions are kept while they fit, after the
+// pinned ones are counted.
+func planPrune(sessions []SessionFile, sizes map[string]int64, policy RetentionPolicy, now time.Time) []pruneCandidate {
+	var count int
+	var total int64
+	for _, s := range sessions {
+		if s.Pinned {
+			count++
+			total += sizes[s.Meta.ID]
+		}
+	}
+
+	var prune []pruneCandidate
+	maxBytes := int64(policy.MaxTotalMB) << 20
+	for i := range sessions {
+		s := &sessions[i]
+		if s.Pinned {
+			continue
+		}
+		size := sizes[s.Meta.ID]
+		reason := ""
+		switch {
+		case policy.MaxAgeDays > 0 && now.Sub(s.Meta.UpdatedAt) > time.Duration(policy.MaxAgeDays)*24*time.Hour:
+			reason = fmt.Sprintf("older than %d days", policy.MaxAgeDays)
+		case policy.MaxSessions > 0 && count >= policy.MaxSessions:
+			reason = fmt.Sprintf("over %d sessions", policy.MaxSessions)
+		case maxBytes > 0 && total+size > maxBytes:
+			reason = fmt.Sprintf("over %d MB", policy.MaxTotalMB)
+		}
+		if reason != "" {
+			prune = append(prune, pruneCandidate{s, size, reason})
+			continue
+		}
+		count++
+		total += size
+	}
+	return prune
+}
+
+// pruneSessions applies policy to every saved session, only listing what
+// would go when dryRun is set
+func pruneSessions(w io.Writer, policy RetentionPolicy, dryRun bool) error {
+	if policy.isZero() {
+		return fmt.Errorf("no retention policy: set retention.max_age_days, max_sessions or max_total_mb in %s", filepath.Join(configDirName, configFileName))
+	}
+	sessions, err := loadSessions()
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading sessions: %w", err)
+	}
+	sizes := make(map[string]int64, len(sessions))
+	for _, s := range sessions {
+		sizes[s.Meta.ID] = sessionSize(s.Meta.ID)
+	}
+
+	candidates := planPrune(sessions, sizes, policy, time.Now())
+	if len(candidates) == 0 {
+		fmt.Fprintln(w, tools.Dim("Nothing to prune"))
+		return nil
+	}
+	var freed int64
+	removed := 0
+	for _, c := range candidates {
+		title := c.sess.Meta.Title
+		if title == "" {
+			title = firstUserMessage(c.sess.Messages)
+		}
+		fmt.Fprintf(w, "  %s  %7s  %s  %s\n", c.sess.Meta.ID, formatSize(c.size), tools.Dim(c.reason), excerpt(title, 40))
+		if dryRun {
+			continue
+		}
+		if _, err := removeSession(c.sess.Meta.ID); err != nil {
+			fmt.Fprintln(w, tools.Error(fmt.Sprintf("removing %s: %v", c.sess.Meta.ID, err)))
+			continue
+		}
+		freed += c.size
+		removed++
+	}
+	if dryRun {
+		for _, c := range candidates {
+			freed += c.size
+		}
+		fmt.Fprintln(w, tools.Dim(fmt.Sprintf("Would remove %d session(s), freeing %s (dry run)", len(candidates), formatSize(freed))))
+		return nil
+	}
+	fmt.Fprintln(w, tools.Success(fmt.Sprintf("removed %d session(s), freed %s", removed, formatSize(freed))))
+	return nil
+}
+
+// formatSize renders bytes compactly: 512B, 1.5K, 23M, 1.2G
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value, suffix := float64(n)/unit, "K"
+	for _, s := range []string{"M", "G", "T"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	if value < 10 {
+		return fmt.Sprintf("%.1f%s", value, suffix)
+	}
+	return fmt.Sprintf("%.0f%s", value, suffix)
+}
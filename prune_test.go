@@ -0,0 +1,152 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+)
+
+func TestPlanPrune(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	session := func(id string, daysOld int, pinned bool) SessionFile {
+		return SessionFile{Meta: SessionMeta{ID: id, UpdatedAt: now.AddDate(0, 0, -daysOld)}, Pinned: pinned}
+	}
+	// newest first, as loadSessions returns them
+	sessions := []SessionFile{
+		session("a", 1, false),
+		session("b", 5, false),
+		session("c", 10, true),
+		session("d", 20, false),
+		session("e", 40, false),
+	}
+	sizes := map[string]int64{"a": 3 << 20, "b": 2 << 20, "c": 4 << 20, "d": 1 << 20, "e": 1 << 20}
+
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   string
+	}{
+		{"unlimited", RetentionPolicy{}, ""},
+		{"max age", RetentionPolicy{MaxAgeDays: 30}, "e:older than 30 days"},
+		{"max count keeps pinned first", RetentionPolicy{MaxSessions: 3}, "d:over 3 sessions e:over 3 sessions"},
+		{"max size skips what does not fit", RetentionPolicy{MaxTotalMB: 8}, "b:over 8 MB e:over 8 MB"},
+		{"pinned over the limit", RetentionPolicy{MaxTotalMB: 4, MaxAgeDays: 15}, "a:over 4 MB b:over 4 MB d:older than 15 days e:older than 15 days"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			var got []string
+			for _, c := range planPrune(sessions, sizes, tt.policy, now) {
+				got = append(got, c.sess.Meta.ID+":"+c.reason)
+			}
+
+			// then
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, strings.Join(got, " "))
+			}
+		})
+	}
+}
+
+func TestSetPinned(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	saveSearchSession(t, "s1", claude.MessageParam{Role: "user", Content: "hello"})
+	before, _ := loadSession("s1")
+
+	// when
+	err := setPinned("s1", true)
+
+	// then - pinning is recorded without counting as activity
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	after, _ := loadSession("s1")
+	if !after.Pinned || !after.Meta.UpdatedAt.Equal(before.Meta.UpdatedAt) {
+		t.Errorf("expected pinned with unchanged UpdatedAt, got %v / %v -> %v", after.Pinned, before.Meta.UpdatedAt, after.Meta.UpdatedAt)
+	}
+	setPinned("s1", false)
+	if sess, _ := loadSession("s1"); sess.Pinned {
+		t.Error("expected unpinned")
+	}
+	if err := setPinned("missing", true); err == nil {
+		t.Error("expected error pinning a missing session")
+	}
+}
+
+func TestPruneSessions(t *testing.T) {
+	// given - an old session, and a pinned old one
+	useTempSessionDir(t)
+	saveSearchSession(t, "old", claude.MessageParam{Role: "user", Content: "old work"})
+	saveSearchSession(t, "kept", claude.MessageParam{Role: "user", Content: "old but pinned"})
+	setPinned("kept", true)
+	policy := RetentionPolicy{MaxSessions: 1}
+
+	// when - a dry run first
+	var out strings.Builder
+	err := pruneSessions(&out, policy, true)
+
+	// then
+	if err != nil || !strings.Contains(out.String(), "Would remove 1 session(s)") {
+		t.Fatalf("unexpected dry run: %v / %q", err, out.String())
+	}
+	if paths, _ := sessionPaths("old"); len(paths) != 1 {
+		t.Fatalf("expected dry run to keep old, got %v", paths)
+	}
+
+	// when
+	err = pruneSessions(&out, policy, false)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if paths, _ := sessionPaths("old"); len(paths) != 0 {
+		t.Errorf("expected old removed, got %v", paths)
+	}
+	if _, err := loadSession("kept"); err != nil {
+		t.Errorf("expected pinned session kept: %v", err)
+	}
+	if _, ok := loadSearchIndex().Sessions["old"]; ok {
+		t.Error("expected old dropped from the search index")
+	}
+}
+
+func TestRemoveSession_RejectsPaths(t *testing.T) {
+	// given - sessionDir nested in a dir holding other data
+	parent := t.TempDir()
+	old := sessionDir
+	sessionDir = filepath.Join(parent, "sessions")
+	t.Cleanup(func() { sessionDir = old })
+	saveSearchSession(t, "s1", claude.MessageParam{Role: "user", Content: "keep me"})
+	os.WriteFile(filepath.Join(parent, "config.json"), []byte("{}"), 0644)
+
+	for _, id := range []string{"..", ".", "", "../sessions", "s1/..", `..\s1`} {
+		// when
+		found, err := removeSession(id)
+
+		// then
+		if err == nil || found {
+			t.Errorf("%q: expected an invalid id error, got %v %v", id, found, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(parent, "config.json")); err != nil {
+		t.Errorf("expected data beside sessionDir kept: %v", err)
+	}
+	if _, err := loadSession("s1"); err != nil {
+		t.Errorf("expected sessions kept: %v", err)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := map[int64]string{0: "0B", 1023: "1023B", 1536: "1.5K", 20 << 20: "20M", 3 << 30: "3.0G"}
+	for n, want := range tests {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package main
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // AgentState holds state needed for reminder checks
 type AgentState struct {
 	PlanMode            bool
 	TurnsSinceTodoWrite int
 	HasPendingTodos     bool
+	ContextPercent      int // context window usage, 0 when unknown
 }
 
 // GetReminders returns all applicable reminders for current state
@@ -18,6 +22,9 @@ func GetReminders(state *AgentState) string {
 	if r := planModeReminder(state); r != "" {
 		parts = append(parts, r)
 	}
+	if r := contextReminder(state); r != "" {
+		parts = append(parts, r)
+	}
 	return strings.Join(parts, "\n")
 }
 
@@ -40,3 +47,22 @@ When your plan is complete, tell the user to exit plan mode with /plan to execut
 	}
 	return ""
 }
+
+// planRequestReminder asks for the plan when /plan <task> ended without ExitPlanMode
+const planRequestReminder = `<system-reminder>
+Present your plan for approval by calling ExitPlanMode with the complete plan.
+</system-reminder>`
+
+// maxTokensReminder asks for the rest of a response cut off at max_tokens
+const maxTokensReminder = `<system-reminder>
+Your previous response was cut off at the output token limit. Continue exactly where it stopped, without repeating what was already written.
+</system-reminder>`
+
+func contextReminder(state *AgentState) string {
+	if state.ContextPercent >= compactThresholdPercent {
+		return fmt.Sprintf(`<system-reminder>
+The context window is %d%% full. Keep tool output small (line ranges, limits), record progress in the todo list, and wrap up or summarize findings soon.
+</system-reminder>`, state.ContextPercent)
+	}
+	return ""
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// replayMaxPause caps the wait between steps when pacing, so idle time in
+// the original session does not stall a replay
+const replayMaxPause = 5 * time.Second
+
+// replayStep is what one step of a replay shows: a typed prompt, or an
+// assistant message together with the results of its tool calls
+type replayStep struct {
+	message int               // index into the session's messages
+	results map[string]string // tool_use ID -> output, for assistant steps
+	at      time.Time         // when the message was recorded (zero if unknown)
+}
+
+// replaySteps splits a session into steps. Reminders injected by the agent
+// were never shown live, so they are left out.
+func replaySteps(sess *SessionFile) []replayStep {
+	var steps []replayStep
+	for i, m := range sess.Messages {
+		step := replayStep{message: i}
+		if i < len(sess.MessageTimes) {
+			step.at = sess.MessageTimes[i]
+		}
+		switch {
+		case m.Role == "assistant":
+			if i+1 < len(sess.Messages) && isToolResultMessage(sess.Messages[i+1]) {
+				step.results = make(map[string]string)
+				for _, b := range claude.ContentBlocks(sess.Messages[i+1].Content) {
+					if b.Type == "tool_result" {
+						step.results[b.ToolUseID] = stripReminders(b.ResultText())
+					}
+				}
+			}
+		case isToolResultMessage(m):
+			continue // shown with the calls that produced them
+		case strings.HasPrefix(messageText(m), "<system-reminder>"):
+			continue
+		}
+		steps = append(steps, step)
+	}
+	return steps
+}
+
+// stripReminders drops the system reminders appended to a tool result
+func stripReminders(s string) string {
+	if i := strings.Index(s, "\n<system-reminder>"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// renderReplayStep prints a step to w the way the live session printed it
+func renderReplayStep(w io.Writer, sess *SessionFile, step replayStep, thinkingDisplay string) {
+	m := sess.Messages[step.message]
+	if m.Role == "user" {
+		fmt.Fprintf(w, "%s %s\n", tools.User(), messageText(m))
+		return
+	}
+
+	var text strings.Builder
+	var calls []claude.WireBlock
+	for _, b := range claude.ContentBlocks(m.Content) {
+		switch b.Type {
+		case "thinking":
+			switch thinkingDisplay {
+			case thinkingShow:
+				fmt.Fprint(w, tools.Thinking(b.Thinking))
+			case thinkingCollapse:
+				fmt.Fprint(w, tools.Dim(fmt.Sprintf("(thinking: %d chars, /thinking show to expand)", len(b.Thinking))))
+			}
+		case "text":
+			text.WriteString(b.Text)
+		case "tool_use":
+			calls = append(calls, b)
+		}
+	}
+	fmt.Fprintln(w, "")
+	printAgentText(w, text.String())
+
+	for _, call := range calls {
+		output, ok := step.results[call.ID]
+		if !ok {
+			fmt.Fprintf(w, "\n%s %s\n", tools.Tool(call.Name), tools.Dim("(no result recorded)"))
+			continue
+		}
+		tools.Replay(call.Name, call.Input, output).Render(w)
+		if call.Name == "ExitPlanMode" && strings.Contains(output, `"decision":"Accept"`) {
+			fmt.Fprintln(w, "\n"+tools.Status("plan mode off")+" "+tools.Dim("full access"))
+		}
+	}
+	if len(calls) == 0 {
+		fmt.Fprintln(w)
+	}
+}
+
+// playReplay shows sess step by step. With pace 0 it waits for input after
+// each step; otherwise it plays on its own, pausing as long as the original
+// session did divided by pace. It never calls the API or runs tools.
+func playReplay(sess *SessionFile, r *bufio.Reader, w io.Writer, pace float64, thinkingDisplay string) error {
+	if pace < 0 {
+		return fmt.Errorf("pace must be 0 (step through) or positive, got %g", pace)
+	}
+	steps := replaySteps(sess)
+	if len(steps) == 0 {
+		return fmt.Errorf("session %s has no messages to replay", sess.Meta.ID)
+	}
+	title := sess.Meta.Title
+	if title == "" {
+		title = excerpt(firstUserMessage(sess.Messages), 60)
+	}
+	fmt.Fprintln(w, tools.Status("replay")+" "+tools.Highlight(title)+" "+tools.Dim(fmt.Sprintf("%s · %d steps", sess.Meta.ID, len(steps))))
+	if pace == 0 {
+		fmt.Fprintln(w, tools.Dim("enter next · b back · <n> go to step · c play to the end · q quit"))
+	}
+	fmt.Fprintln(w, tools.Separator())
+
+	interactive := pace == 0
+	for i := 0; i < len(steps); {
+		renderReplayStep(w, sess, steps[i], thinkingDisplay)
+		if i == len(steps)-1 {
+			break
+		}
+		if !interactive {
+			if pace > 0 {
+				time.Sleep(replayPause(steps[i], steps[i+1], pace))
+			}
+			i++
+			continue
+		}
+
+		next, ok := replayCommand(r, w, i, len(steps))
+		if !ok {
+			return nil
+		}
+		if next < 0 { // input ended or c: play out the rest
+			interactive, next = false, i+1
+		}
+		i = next
+	}
+	fmt.Fprintln(w, tools.Separator())
+	fmt.Fprintln(w, tools.Dim("end of replay"))
+	return nil
+}
+
+// replayCommand reads where to go after step i of n. It returns the next
+// step, -1 to play to the end, or false to quit.
+func replayCommand(r *bufio.Reader, w io.Writer, i, n int) (int, bool) {
+	for {
+		fmt.Fprint(w, tools.Dim(fmt.Sprintf("[%d/%d] ", i+1, n)))
+		line, err := r.ReadString('\n')
+		cmd := strings.TrimSpace(line)
+		switch {
+		case err != nil && cmd == "", cmd == "c":
+			return -1, true
+		case cmd == "":
+			return i + 1, true
+		case cmd == "q":
+			return 0, false
+		case cmd == "b":
+			return max(i-1, 0), true
+		}
+		if step, err := strconv.Atoi(cmd); err == nil && step >= 1 && step <= n {
+			return step - 1, true
+		}
+		fmt.Fprintln(w, tools.Dim(fmt.Sprintf("steps are 1-%d", n)))
+	}
+}
+
+// replayPause is how long to wait before the next step at pace
+func replayPause(cur, next replayStep, pace float64) time.Duration {
+	if cur.at.IsZero() || next.at.IsZero() || !next.at.After(cur.at) {
+		return 0
+	}
+	return min(time.Duration(float64(next.at.Sub(cur.at))/pace), replayMaxPause)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+)
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdout
+	os.Stdout = w
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	defer func() { os.Stdout = old }()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func replaySource() *SessionFile {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	return &SessionFile{
+		Meta: SessionMeta{ID: "s1", Title: "Fix the flaky test"},
+		Messages: []claude.MessageParam{
+			{Role: "user", Content: "fix the flaky test"},
+			{Role: "assistant", Content: []claude.ContentBlock{
+				{Type: "thinking", Thinking: "look at the test first"},
+				{Type: "text", Text: "Running it."},
+				{Type: "tool_use", ID: "t1", Name: "Bash", Input: json.RawMessage(`{"args":"go test ./..."}`)},
+				{Type: "tool_use", ID: "t2", Name: "TodoWrite", Input: json.RawMessage(`{"todos":[{"content":"Fix timing","active_form":"Fixing timing","status":"in_progress"}]}`)},
+			}},
+			{Role: "user", Content: []claude.ToolResultBlock{
+				{Type: "tool_result", ToolUseID: "t1", Content: "FAIL TestRetry\n<system-reminder>\nsecret reminder\n</system-reminder>"},
+				{Type: "tool_result", ToolUseID: "t2", Content: `{"success":true,"count":1}`},
+			}},
+			{Role: "assistant", Content: "Fixed **the** timing."},
+			{Role: "user", Content: maxTokensReminder},
+			{Role: "assistant", Content: "Done."},
+		},
+		MessageTimes: []time.Time{start, start.Add(2 * time.Second), start.Add(3 * time.Second), start.Add(time.Minute), start.Add(time.Minute), start.Add(2 * time.Minute)},
+	}
+}
+
+func TestReplaySteps(t *testing.T) {
+	// when
+	steps := replaySteps(replaySource())
+
+	// then - tool results ride with their calls; injected reminders are dropped
+	var got []int
+	for _, s := range steps {
+		got = append(got, s.message)
+	}
+	if len(got) != 4 || got[0] != 0 || got[1] != 1 || got[2] != 3 || got[3] != 5 {
+		t.Fatalf("expected steps at messages [0 1 3 5], got %v", got)
+	}
+	if steps[1].results["t1"] != "FAIL TestRetry" || steps[1].results["t2"] == "" {
+		t.Errorf("expected results without reminders, got %q", steps[1].results)
+	}
+}
+
+func TestReplayPause(t *testing.T) {
+	steps := replaySteps(replaySource())
+	tests := []struct {
+		name string
+		i    int
+		pace float64
+		want time.Duration
+	}{
+		{"real time", 0, 1, 2 * time.Second},
+		{"faster", 0, 2, time.Second},
+		{"capped", 1, 1, replayMaxPause},
+		{"unknown times", 0, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cur, next := steps[tt.i], steps[tt.i+1]
+			if tt.name == "unknown times" {
+				cur.at = time.Time{}
+			}
+			if got := replayPause(cur, next, tt.pace); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReplayCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		wantNext int
+		wantOK   bool
+	}{
+		{"\n", 3, true},
+		{"b\n", 1, true},
+		{"4\n", 3, true},
+		{"9\n1\n", 0, true}, // out of range asks again
+		{"c\n", -1, true},
+		{"", -1, true}, // end of input plays out the rest
+		{"q\n", 0, false},
+	}
+	for _, tt := range tests {
+		next, ok := replayCommand(bufio.NewReader(strings.NewReader(tt.input)), io.Discard, 2, 4)
+		if next != tt.wantNext || ok != tt.wantOK {
+			t.Errorf("%q: expected (%d, %v), got (%d, %v)", tt.input, tt.wantNext, tt.wantOK, next, ok)
+		}
+	}
+}
+
+func TestPlayReplay(t *testing.T) {
+	// given - step twice, go back once, then play to the end
+	input := bufio.NewReader(strings.NewReader("\nb\nc\n"))
+
+	// when
+	var buf strings.Builder
+	err := playReplay(replaySource(), input, &buf, 0, thinkingCollapse)
+
+	// then
+	out := buf.String()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"fix the flaky test", "(thinking: 22 chars", "Running it.", "FAIL TestRetry", "Fixing timing", "timing", "Done.", "end of replay"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in replay, got:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"secret reminder", "cut off at the output token limit", "look at the test first"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected %q hidden, got:\n%s", unwanted, out)
+		}
+	}
+	if n := strings.Count(out, "Running it."); n != 2 {
+		t.Errorf("expected step 2 shown twice after going back, got %d", n)
+	}
+}
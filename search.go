@@ -0,0 +1,442 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// searchIndexName is the inverted index file in sessionDir. Appending to a
+// transcript updates it; a lost or unreadable index is rebuilt on search.
+const (
+	searchIndexName    = "search.index"
+	searchIndexVersion = 1
+	searchMaxResults   = 10
+	snippetRadius      = 60 // runes either side of the match
+)
+
+// searchIndex maps terms to the sessions and messages containing them
+type searchIndex struct {
+	Version  int                         `json:"version"`
+	Sessions map[string]indexedSession   `json:"sessions"`
+	Terms    map[string]map[string][]int `json:"terms"` // term -> session ID -> message indexes
+}
+
+// indexedSession records how much of a transcript the index covers
+type indexedSession struct {
+	Messages int       `json:"messages"`
+	Size     int64     `json:"size"` // transcript bytes when indexed
+	Modified time.Time `json:"modified"`
+}
+
+// searchHit is one ranked session
+type searchHit struct {
+	ID       string
+	Session  SessionFile
+	Score    float64
+	Messages []int  // indexes of messages matching any query term
+	Snippet  string // context around the first match
+}
+
+func searchIndexPath() string {
+	return filepath.Join(sessionDir, searchIndexName)
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		Version:  searchIndexVersion,
+		Sessions: make(map[string]indexedSession),
+		Terms:    make(map[string]map[string][]int),
+	}
+}
+
+// loadSearchIndex reads the index, starting afresh if it is missing,
+// unreadable or from another version
+func loadSearchIndex() *searchIndex {
+	data, err := os.ReadFile(searchIndexPath())
+	if err != nil {
+		return newSearchIndex()
+	}
+	var ix searchIndex
+	if json.Unmarshal(data, &ix) != nil || ix.Version != searchIndexVersion || ix.Sessions == nil || ix.Terms == nil {
+		return newSearchIndex()
+	}
+	return &ix
+}
+
+func (ix *searchIndex) save() error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return err
+	}
+	return writeFileAtomic(searchIndexPath(), data)
+}
+
+// add indexes messages as session id's messages from index first on
+func (ix *searchIndex) add(id string, first int, messages []claude.MessageParam) {
+	for i, m := range messages {
+		for _, term := range searchTerms(searchableText(m)) {
+			postings := ix.Terms[term]
+			if postings == nil {
+				postings = make(map[string][]int)
+				ix.Terms[term] = postings
+			}
+			postings[id] = append(postings[id], first+i)
+		}
+	}
+	entry := ix.Sessions[id]
+	entry.Messages = first + len(messages)
+	ix.Sessions[id] = entry
+}
+
+// remove drops every posting of session id
+func (ix *searchIndex) remove(id string) {
+	for term, postings := range ix.Terms {
+		delete(postings, id)
+		if len(postings) == 0 {
+			delete(ix.Terms, term)
+		}
+	}
+	delete(ix.Sessions, id)
+}
+
+// updateSearchIndex indexes the messages in records, just appended to
+// session id's transcript when it was before bytes long. The transcript is
+// already saved, so a failure is only reported: the next save re-indexes it.
+func updateSearchIndex(id string, before int64, records []Record) {
+	ix := loadSearchIndex()
+	err := ix.append(id, before, records)
+	if err == nil {
+		err = ix.save()
+	}
+	if err != nil {
+		fmt.Println(tools.Warning(fmt.Sprintf("updating search index: %v", err)))
+	}
+}
+
+// append indexes records added to session id's transcript. When the index
+// did not cover the transcript up to them, e.g. after a failed update, the
+// whole transcript is re-indexed instead.
+func (ix *searchIndex) append(id string, before int64, records []Record) error {
+	entry, indexed := ix.Sessions[id]
+	first := entry.Messages
+	var messages []claude.MessageParam
+	if before > 0 && (!indexed || entry.Size != before) {
+		sess, err := loadSession(id)
+		if err != nil {
+			return err
+		}
+		first, messages = 0, sess.Messages
+	} else {
+		if before == 0 {
+			first = 0 // a new transcript under an id indexed before
+		}
+		for _, r := range records {
+			if r.Message != nil && (r.Type == recordUser || r.Type == recordAssistant || r.Type == recordToolResult) {
+				messages = append(messages, *r.Message)
+			}
+		}
+	}
+	if first == 0 {
+		ix.remove(id)
+	}
+	ix.add(id, first, messages)
+	ix.stat(id)
+	return nil
+}
+
+// stat records the size and modification time of session id's transcript
+func (ix *searchIndex) stat(id string) {
+	path, err := sessionPath(id)
+	if err != nil {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	entry := ix.Sessions[id]
+	entry.Size, entry.Modified = info.Size(), info.ModTime()
+	ix.Sessions[id] = entry
+}
+
+// removeFromSearchIndex forgets a deleted session
+func removeFromSearchIndex(id string) error {
+	ix := loadSearchIndex()
+	if _, ok := ix.Sessions[id]; !ok {
+		return nil
+	}
+	ix.remove(id)
+	return ix.save()
+}
+
+// refresh repairs the index from the transcripts on disk: ones that grew
+// have their new messages indexed, ones that shrank or were rewritten are
+// re-indexed, and deleted ones are forgotten. It reports whether the index
+// changed.
+func (ix *searchIndex) refresh() (bool, error) {
+	ids, err := sessionIDs()
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	changed := false
+	present := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		present[id] = true
+		entry, indexed := ix.Sessions[id]
+		path, err := sessionPath(id)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err == nil && entry.Size == info.Size() {
+			continue
+		}
+		grown := indexed && err == nil && info.Size() > entry.Size
+		sess, err := loadSession(id) // migrates legacy sessions
+		if err != nil {
+			continue
+		}
+		from := entry.Messages
+		if !grown || from > len(sess.Messages) {
+			ix.remove(id) // transcripts are append-only, so anything else was rewritten
+			from = 0
+		}
+		ix.add(id, from, sess.Messages[from:])
+		ix.stat(id)
+		changed = true
+	}
+	for id := range ix.Sessions {
+		if !present[id] {
+			ix.remove(id)
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+// rank scores sessions containing every query term by tf-idf. A query term
+// also matches longer index terms it prefixes ("flak" finds "flaky").
+func (ix *searchIndex) rank(query []string) map[string]*searchHit {
+	hits := make(map[string]*searchHit)
+	total := float64(len(ix.Sessions))
+	for n, q := range query {
+		matched := make(map[string][]int) // session ID -> message indexes
+		for term, postings := range ix.Terms {
+			if !strings.HasPrefix(term, q) {
+				continue
+			}
+			for id, msgs := range postings {
+				matched[id] = append(matched[id], msgs...)
+			}
+		}
+		idf := math.Log(1 + total/float64(max(len(matched), 1)))
+		for id, msgs := range matched {
+			hit := hits[id]
+			if hit == nil {
+				if n > 0 {
+					continue // missed an earlier term
+				}
+				hit = &searchHit{ID: id}
+				hits[id] = hit
+			}
+			hit.Score += float64(len(msgs)) * idf
+			hit.Messages = append(hit.Messages, msgs...)
+		}
+		for id := range hits {
+			if _, ok := matched[id]; !ok {
+				delete(hits, id)
+			}
+		}
+	}
+	for _, hit := range hits {
+		slices.Sort(hit.Messages)
+		hit.Messages = slices.Compact(hit.Messages)
+	}
+	return hits
+}
+
+// searchSessions finds sessions matching query, best first
+func searchSessions(query string) ([]searchHit, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("no words to search for in %q", query)
+	}
+	ix := loadSearchIndex()
+	if len(ix.Sessions) == 0 {
+		// missing, unreadable or from another version: rebuild it
+		changed, err := ix.refresh()
+		if err != nil {
+			return nil, err
+		}
+		if changed {
+			ix.save() // best effort: a failed write just means rebuilding next time
+		}
+	}
+
+	ranked := slices.Collect(maps.Values(ix.rank(terms)))
+	slices.SortFunc(ranked, func(a, b *searchHit) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return ix.Sessions[b.ID].Modified.Compare(ix.Sessions[a.ID].Modified)
+	})
+
+	// Only the sessions shown are read back, for their titles and snippets
+	var hits []searchHit
+	for _, hit := range ranked {
+		if len(hits) == searchMaxResults {
+			break
+		}
+		sess, err := loadSession(hit.ID)
+		if err != nil {
+			continue
+		}
+		hit.Session = *sess
+		hit.Snippet = snippet(sess.Messages, hit.Messages, terms)
+		hits = append(hits, *hit)
+	}
+	return hits, nil
+}
+
+// searchableText is what search sees of a message: typed user and assistant
+// text plus the string values of tool inputs (not thinking or tool output)
+func searchableText(m claude.MessageParam) string {
+	var parts []string
+	for _, b := range claude.ContentBlocks(m.Content) {
+		switch b.Type {
+		case "text":
+			parts = append(parts, b.Text)
+		case "tool_use":
+			var input any
+			if json.Unmarshal(b.Input, &input) == nil {
+				parts = appendStrings(parts, input)
+			}
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// appendStrings collects the string values in decoded JSON
+func appendStrings(out []string, v any) []string {
+	switch v := v.(type) {
+	case string:
+		return append(out, v)
+	case []any:
+		for _, e := range v {
+			out = appendStrings(out, e)
+		}
+	case map[string]any:
+		for _, e := range v {
+			out = appendStrings(out, e)
+		}
+	}
+	return out
+}
+
+// searchTerms splits text into distinct lowercase words of two or more
+// letters or digits
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var terms []string
+	for _, w := range words {
+		if len([]rune(w)) >= 2 && !slices.Contains(terms, w) {
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// snippet shows the first matching message's text around the first term
+func snippet(messages []claude.MessageParam, matches []int, terms []string) string {
+	for _, i := range matches {
+		if i >= len(messages) {
+			continue
+		}
+		text := strings.Join(strings.Fields(searchableText(messages[i])), " ")
+		lower := []rune(strings.ToLower(text))
+		runes := []rune(text)
+		for _, term := range terms {
+			at := runeIndex(lower, []rune(term))
+			if at < 0 {
+				continue
+			}
+			start, end := max(at-snippetRadius, 0), min(at+len([]rune(term))+snippetRadius, len(runes))
+			s := string(runes[start:end])
+			if start > 0 {
+				s = "…" + s
+			}
+			if end < len(runes) {
+				s += "…"
+			}
+			return s
+		}
+	}
+	return ""
+}
+
+func runeIndex(s, sub []rune) int {
+	for i := 0; i+len(sub) <= len(s); i++ {
+		if slices.Equal(s[i:i+len(sub)], sub) {
+			return i
+		}
+	}
+	return -1
+}
+
+// printSearchResults lists hits with their matching messages and a snippet
+func printSearchResults(w io.Writer, query string, hits []searchHit, now time.Time) {
+	if len(hits) == 0 {
+		fmt.Fprintln(w, tools.Dim(fmt.Sprintf("No sessions match %q", query)))
+		return
+	}
+	fmt.Fprintln(w)
+	for i, h := range hits {
+		m := h.Session.Meta
+		title := m.Title
+		if title == "" {
+			title = firstUserMessage(h.Session.Messages)
+		}
+		where := filepath.Base(m.Project())
+		if m.Project() == "" {
+			where = "?"
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", tools.OptionNumber(i+1), tools.Highlight(excerpt(title, 60)),
+			tools.Dim(fmt.Sprintf("%s · %s · %s", m.ID, where, formatAge(m.UpdatedAt, now))))
+		fmt.Fprintf(w, "     %s %s\n", tools.Muted("messages"), tools.Dim(formatIndexes(h.Messages)))
+		if h.Snippet != "" {
+			fmt.Fprintf(w, "     %s\n", h.Snippet)
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// formatIndexes lists message indexes, eliding past eight
+func formatIndexes(idx []int) string {
+	var parts []string
+	for i, n := range idx {
+		if i == 8 {
+			parts = append(parts, fmt.Sprintf("+%d more", len(idx)-i))
+			break
+		}
+		parts = append(parts, fmt.Sprint(n))
+	}
+	return strings.Join(parts, ", ")
+}
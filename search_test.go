@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// saveSearchSession saves messages as session id through an agent
+func saveSearchSession(t *testing.T, id string, messages ...claude.MessageParam) {
+	t.Helper()
+	todos := []tools.Todo{}
+	agent, _ := NewAgent(id, nil, &claude.Client{}, nil, "", "m", nil, &todos)
+	agent.messages = messages
+	if err := agent.Save(); err != nil {
+		t.Fatalf("save %s: %v", id, err)
+	}
+}
+
+func TestAgentSave_UpdatesSearchIndex(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	todos := []tools.Todo{}
+	agent, _ := NewAgent("s1", nil, &claude.Client{}, nil, "", "m", nil, &todos)
+	agent.messages = []claude.MessageParam{{Role: "user", Content: "the parser is flaky"}}
+	agent.Save()
+
+	// when - a second turn only indexes the new messages
+	agent.messages = append(agent.messages,
+		claude.MessageParam{Role: "assistant", Content: []claude.ContentBlock{
+			{Type: "thinking", Thinking: "secret musing"},
+			{Type: "tool_use", ID: "t1", Name: "Grep", Input: []byte(`{"pattern":"tokenizer","path":"src"}`)},
+		}},
+		claude.MessageParam{Role: "user", Content: []claude.ToolResultBlock{{Type: "tool_result", ToolUseID: "t1", Content: "lexer output"}}},
+	)
+	agent.Save()
+
+	// then
+	ix := loadSearchIndex()
+	if got := ix.Sessions["s1"].Messages; got != 3 {
+		t.Errorf("expected 3 messages indexed, got %d", got)
+	}
+	if got := ix.Terms["flaky"]["s1"]; !slices.Equal(got, []int{0}) {
+		t.Errorf("expected flaky in message 0, got %v", got)
+	}
+	if got := ix.Terms["tokenizer"]["s1"]; !slices.Equal(got, []int{1}) {
+		t.Errorf("expected tool input indexed in message 1, got %v", got)
+	}
+	for _, term := range []string{"secret", "lexer"} {
+		if _, ok := ix.Terms[term]; ok {
+			t.Errorf("expected %q (thinking / tool output) not indexed", term)
+		}
+	}
+}
+
+func TestAgentSave_ReindexesSessionTheIndexMissed(t *testing.T) {
+	// given - a save the index never saw
+	useTempSessionDir(t)
+	todos := []tools.Todo{}
+	agent, _ := NewAgent("s1", nil, &claude.Client{}, nil, "", "m", nil, &todos)
+	agent.messages = []claude.MessageParam{{Role: "user", Content: "the parser is flaky"}}
+	agent.Save()
+	stale := loadSearchIndex()
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "assistant", Content: "the lexer drops CRLF"})
+	agent.Save()
+	stale.save()
+
+	// when
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "user", Content: "fix the tokenizer"})
+	agent.Save()
+
+	// then
+	ix := loadSearchIndex()
+	for term, want := range map[string][]int{"flaky": {0}, "lexer": {1}, "tokenizer": {2}} {
+		if got := ix.Terms[term]["s1"]; !slices.Equal(got, want) {
+			t.Errorf("%s: expected messages %v, got %v", term, want, got)
+		}
+	}
+	if got := ix.Sessions["s1"].Messages; got != 3 {
+		t.Errorf("expected 3 messages indexed, got %d", got)
+	}
+}
+
+func TestSearchSessions(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	saveSearchSession(t, "parser",
+		claude.MessageParam{Role: "user", Content: "Why is the parser flaky on Windows?"},
+		claude.MessageParam{Role: "assistant", Content: "The parser reads CRLF line endings wrong."},
+	)
+	saveSearchSession(t, "docs",
+		claude.MessageParam{Role: "user", Content: "Update the docs for the parser"},
+	)
+	saveSearchSession(t, "other",
+		claude.MessageParam{Role: "user", Content: "Add a windows installer"},
+	)
+
+	t.Run("ranks by term frequency", func(t *testing.T) {
+		hits, err := searchSessions("parser")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := hitIDs(hits); !slices.Equal(got, []string{"parser", "docs"}) {
+			t.Fatalf("expected parser then docs, got %v", got)
+		}
+		if !slices.Equal(hits[0].Messages, []int{0, 1}) {
+			t.Errorf("expected matching messages [0 1], got %v", hits[0].Messages)
+		}
+		if !strings.Contains(hits[0].Snippet, "parser flaky") {
+			t.Errorf("expected snippet around the match, got %q", hits[0].Snippet)
+		}
+	})
+
+	t.Run("every word must match", func(t *testing.T) {
+		hits, _ := searchSessions("Parser WINDOWS")
+		if got := hitIDs(hits); !slices.Equal(got, []string{"parser"}) {
+			t.Errorf("expected only parser, got %v", got)
+		}
+	})
+
+	t.Run("prefixes match", func(t *testing.T) {
+		hits, _ := searchSessions("instal")
+		if got := hitIDs(hits); !slices.Equal(got, []string{"other"}) {
+			t.Errorf("expected other, got %v", got)
+		}
+	})
+
+	t.Run("no words", func(t *testing.T) {
+		if _, err := searchSessions(" ?! "); err == nil {
+			t.Error("expected error for an empty query")
+		}
+	})
+}
+
+func TestSearchSessions_RefreshesStaleIndex(t *testing.T) {
+	// given - the index is lost, one session is deleted and one written
+	// behind the index's back
+	useTempSessionDir(t)
+	saveSearchSession(t, "kept", claude.MessageParam{Role: "user", Content: "refactor the lexer"})
+	saveSearchSession(t, "gone", claude.MessageParam{Role: "user", Content: "refactor the lexer again"})
+	gone, _ := sessionPath("gone")
+	os.Remove(gone)
+	os.Remove(searchIndexPath())
+	sess := &SessionFile{
+		Meta:     SessionMeta{ID: "outside"},
+		Messages: []claude.MessageParam{{Role: "user", Content: "lexer from another process"}},
+	}
+	outside, _ := sessionPath("outside")
+	writeTranscriptAtomic(outside, sessionRecords(sess))
+
+	// when
+	hits, err := searchSessions("lexer")
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := hitIDs(hits)
+	slices.Sort(got)
+	if !slices.Equal(got, []string{"kept", "outside"}) {
+		t.Errorf("expected kept and outside, got %v", got)
+	}
+	if _, ok := loadSearchIndex().Sessions["gone"]; ok {
+		t.Error("expected deleted session dropped from the index")
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	got := searchTerms("Fix the parser's CRLF handling, fix it in v2 — a café")
+	want := []string{"fix", "the", "parser", "crlf", "handling", "it", "in", "v2", "café"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func hitIDs(hits []searchHit) []string {
+	var ids []string
+	for _, h := range hits {
+		ids = append(ids, h.ID)
+	}
+	return ids
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
@@ -17,19 +21,62 @@ import (
 
 var sessionDir = filepath.Join(os.Getenv("HOME"), ".config", "agent", "sessions")
 
+// Session files: append-only JSONL transcripts, plus the legacy single-JSON
+// format which is migrated on first load
+const (
+	transcriptExt    = ".jsonl"
+	legacySessionExt = ".json"
+)
+
 type SessionMeta struct {
 	ID        string    `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Model     string    `json:"model"`
+	Profile   string    `json:"profile,omitempty"`
+	Title     string    `json:"title,omitempty"`     // generated from the first exchange
+	Parent    string    `json:"parent,omitempty"`    // session this one was forked from
+	ForkedAt  int       `json:"forked_at,omitempty"` // parent messages copied into the fork
+	Workspace           // where the session was started
 }
 
+// SessionFile is a session's state, rebuilt by replaying its transcript
 type SessionFile struct {
 	Meta            SessionMeta           `json:"meta"`
 	Messages        []claude.MessageParam `json:"messages"`
+	MessageModels   map[int]string        `json:"message_models,omitempty"` // assistant message index -> model
 	Todos           []tools.Todo          `json:"todos,omitempty"`
 	PlanMode        bool                  `json:"plan_mode,omitempty"`
 	PermissionsMode string                `json:"permissions_mode,omitempty"` // "prompt" or "accept_all"
+	Usage           claude.Usage          `json:"usage"`                      // summed over all requests
+	Pinned          bool                  `json:"pinned,omitempty"`           // kept by -prune
+	MessageTimes    []time.Time           `json:"-"`                          // when each message was recorded
+}
+
+// Transcript record types
+const (
+	recordMeta       = "meta"        // session header; repeated when model/profile change
+	recordUser       = "user"        // user message
+	recordAssistant  = "assistant"   // assistant message, tool calls included
+	recordToolResult = "tool_result" // user message carrying tool results
+	recordTodos      = "todos"       // todo list replaced
+	recordMode       = "mode"        // plan/permissions mode changed
+	recordUsage      = "usage"       // tokens used by one request
+	recordPin        = "pin"         // pinned or unpinned; not an update
+)
+
+// Record is one line of a session transcript
+type Record struct {
+	Type            string               `json:"type"`
+	Time            time.Time            `json:"ts"`
+	Meta            *SessionMeta         `json:"meta,omitempty"`
+	Message         *claude.MessageParam `json:"message,omitempty"`
+	Model           string               `json:"model,omitempty"` // assistant records
+	Todos           *[]tools.Todo        `json:"todos,omitempty"` // pointer so an emptied list is recorded
+	PlanMode        *bool                `json:"plan_mode,omitempty"`
+	PermissionsMode string               `json:"permissions_mode,omitempty"`
+	Usage           *claude.Usage        `json:"usage,omitempty"`
+	Pinned          *bool                `json:"pinned,omitempty"`
 }
 
 func newSessionID() string {
@@ -37,79 +84,425 @@ func newSessionID() string {
 	return id.String()
 }
 
-func sessionPath(id string) string {
-	return filepath.Join(sessionDir, id+".json")
+// checkSessionID rejects ids that are not a plain file name, so they cannot
+// reach outside sessionDir
+func checkSessionID(id string) error {
+	if id == "" || id == "." || id == ".." || filepath.Base(id) != id || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+	return nil
 }
 
-func saveSession(id string, messages []claude.MessageParam, todos []tools.Todo, planMode bool, permissionsMode string) error {
-	sess := SessionFile{
-		Meta: SessionMeta{
-			ID:        id,
-			UpdatedAt: time.Now(),
-			Model:     "MiniMax-M2.1",
-		},
-		Messages:        messages,
-		Todos:           todos,
-		PlanMode:        planMode,
-		PermissionsMode: permissionsMode,
+// sessionPath is session id's transcript. Ids come from flags and commands,
+// so every path is built through checkSessionID.
+func sessionPath(id string) (string, error) {
+	if err := checkSessionID(id); err != nil {
+		return "", err
 	}
+	return filepath.Join(sessionDir, id+transcriptExt), nil
+}
+
+func legacySessionPath(id string) (string, error) {
+	if err := checkSessionID(id); err != nil {
+		return "", err
+	}
+	return filepath.Join(sessionDir, id+legacySessionExt), nil
+}
 
-	if existing, err := loadSession(id); err == nil {
-		sess.Meta.CreatedAt = existing.Meta.CreatedAt
-	} else {
-		sess.Meta.CreatedAt = time.Now()
+// messageRecord wraps a message, classifying tool results apart from user input
+func messageRecord(m claude.MessageParam, model string, now time.Time) Record {
+	typ := recordUser
+	switch {
+	case m.Role == "assistant":
+		typ = recordAssistant
+	case isToolResultMessage(m):
+		typ = recordToolResult
+	}
+	return Record{Type: typ, Time: now, Message: &m, Model: model}
+}
+
+func isToolResultMessage(m claude.MessageParam) bool {
+	if _, ok := m.Content.([]claude.ToolResultBlock); ok {
+		return true
+	}
+	data, err := json.Marshal(m.Content)
+	if err != nil {
+		return false
+	}
+	var blocks []struct {
+		Type string `json:"type"`
+	}
+	return json.Unmarshal(data, &blocks) == nil && len(blocks) > 0 && blocks[0].Type == "tool_result"
+}
+
+// appendRecords writes records to the session transcript as whole lines in a
+// single write, syncs, then indexes them for search. A partial line left by
+// an earlier crash is cut off first so new records never fuse with it.
+func appendRecords(id string, records []Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
 	}
 
-	data, err := json.MarshalIndent(sess, "", "  ")
+	path, err := sessionPath(id)
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(sessionPath(id), data, 0644)
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return err
+	}
+	if err := truncatePartialLine(path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	updateSearchIndex(id, info.Size(), records)
+	return nil
 }
 
-func loadSession(id string) (*SessionFile, error) {
-	data, err := os.ReadFile(sessionPath(id))
+// truncatePartialLine drops trailing bytes after the last newline
+func truncatePartialLine(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 	if err != nil {
-		return nil, err
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.Size() == 0 {
+		return err
+	}
+	// scan back in chunks for the last newline
+	const chunk = 4096
+	end := info.Size()
+	for off := end; off > 0; {
+		n := min(int64(chunk), off)
+		off -= n
+		buf := make([]byte, n)
+		if _, err := f.ReadAt(buf, off); err != nil {
+			return err
+		}
+		if i := bytes.LastIndexByte(buf, '\n'); i >= 0 {
+			if keep := off + int64(i) + 1; keep != end {
+				return f.Truncate(keep)
+			}
+			return nil
+		}
+	}
+	return f.Truncate(0)
+}
+
+// writeTranscriptAtomic replaces a transcript via temp file and rename
+func writeTranscriptAtomic(path string, records []Record) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	return writeFileAtomic(path, buf.Bytes())
+}
+
+// writeFileAtomic replaces path via a synced temp file and rename, so readers
+// see the old or the new content, never a mix
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// readTranscript parses a transcript, skipping a torn final line and any
+// corrupt lines rather than failing the whole session
+func readTranscript(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	var records []Record
+	for {
+		line, err := br.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return records, nil // unterminated last line = interrupted write
+		}
+		if err != nil {
+			return records, err
+		}
+		var rec Record
+		if json.Unmarshal(line, &rec) == nil && rec.Type != "" {
+			records = append(records, rec)
+		}
+	}
+}
+
+// replaySession rebuilds session state from transcript records
+func replaySession(id string, records []Record) *SessionFile {
+	sess := &SessionFile{Meta: SessionMeta{ID: id}}
+	for _, r := range records {
+		if r.Type != recordPin && sess.Meta.UpdatedAt.Before(r.Time) {
+			sess.Meta.UpdatedAt = r.Time
+		}
+		switch r.Type {
+		case recordMeta:
+			if r.Meta == nil {
+				continue
+			}
+			if sess.Meta.CreatedAt.IsZero() {
+				sess.Meta.CreatedAt = r.Meta.CreatedAt
+			}
+			sess.Meta.Model, sess.Meta.Profile = r.Meta.Model, r.Meta.Profile
+			if sess.Meta.Workspace == (Workspace{}) {
+				sess.Meta.Workspace = r.Meta.Workspace // keep where it started
+			}
+			if sess.Meta.Parent == "" {
+				sess.Meta.Parent, sess.Meta.ForkedAt = r.Meta.Parent, r.Meta.ForkedAt
+			}
+			if r.Meta.Title != "" {
+				sess.Meta.Title = r.Meta.Title
+			}
+		case recordUser, recordAssistant, recordToolResult:
+			if r.Message == nil {
+				continue
+			}
+			sess.Messages = append(sess.Messages, *r.Message)
+			sess.MessageTimes = append(sess.MessageTimes, r.Time)
+			if r.Model != "" {
+				if sess.MessageModels == nil {
+					sess.MessageModels = make(map[int]string)
+				}
+				sess.MessageModels[len(sess.Messages)-1] = r.Model
+			}
+		case recordTodos:
+			if r.Todos != nil {
+				sess.Todos = *r.Todos
+			}
+		case recordMode:
+			if r.PlanMode != nil {
+				sess.PlanMode = *r.PlanMode
+			}
+			if r.PermissionsMode != "" {
+				sess.PermissionsMode = r.PermissionsMode
+			}
+		case recordUsage:
+			if r.Usage != nil {
+				sess.Usage.InputTokens += r.Usage.InputTokens
+				sess.Usage.OutputTokens += r.Usage.OutputTokens
+			}
+		case recordPin:
+			if r.Pinned != nil {
+				sess.Pinned = *r.Pinned
+			}
+		}
+	}
+	if sess.Meta.CreatedAt.IsZero() && len(records) > 0 {
+		sess.Meta.CreatedAt = records[0].Time
+	}
+	return sess
+}
+
+// sessionRecords converts a whole session into transcript records (migration)
+func sessionRecords(sess *SessionFile) []Record {
+	ts := sess.Meta.UpdatedAt
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	meta := sess.Meta
+	records := []Record{{Type: recordMeta, Time: meta.CreatedAt, Meta: &meta}}
+	for i, m := range sess.Messages {
+		records = append(records, messageRecord(m, sess.MessageModels[i], ts))
+	}
+	if len(sess.Todos) > 0 {
+		todos := sess.Todos
+		records = append(records, Record{Type: recordTodos, Time: ts, Todos: &todos})
+	}
+	if sess.PlanMode || sess.PermissionsMode != "" {
+		plan := sess.PlanMode
+		records = append(records, Record{Type: recordMode, Time: ts, PlanMode: &plan, PermissionsMode: sess.PermissionsMode})
+	}
+	if sess.Pinned {
+		pinned := true
+		records = append(records, Record{Type: recordPin, Time: ts, Pinned: &pinned})
+	}
+	return records
+}
+
+// migrateLegacySession converts <id>.json into a transcript, then removes it
+func migrateLegacySession(id string) error {
+	legacy, err := legacySessionPath(id)
+	if err != nil {
+		return err
+	}
+	path, err := sessionPath(id)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(legacy)
+	if err != nil {
+		return err
 	}
 	var sess SessionFile
 	if err := json.Unmarshal(data, &sess); err != nil {
+		return fmt.Errorf("migrating session %s: %w", id, err)
+	}
+	if sess.Meta.ID == "" {
+		sess.Meta.ID = id
+	}
+	records := sessionRecords(&sess)
+	if err := writeTranscriptAtomic(path, records); err != nil {
+		return fmt.Errorf("migrating session %s: %w", id, err)
+	}
+	updateSearchIndex(id, 0, records)
+	if err := os.Remove(legacy); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err // another process may have migrated it first
+	}
+	return nil
+}
+
+// loadSession replays a session transcript, migrating the legacy format first
+func loadSession(id string) (*SessionFile, error) {
+	records, err := readSessionRecords(id)
+	if err != nil {
 		return nil, err
 	}
-	return &sess, nil
+	return replaySession(id, records), nil
 }
 
-func listSessions() {
-	entries, err := os.ReadDir(sessionDir)
+// readSessionRecords reads the records of a session transcript, migrating
+// the legacy format first
+func readSessionRecords(id string) ([]Record, error) {
+	path, err := sessionPath(id)
 	if err != nil {
-		if os.IsNotExist(err) {
-			fmt.Println(tools.Dim("No sessions saved"))
-			return
+		return nil, err
+	}
+	legacy, _ := legacySessionPath(id) // same check as sessionPath
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		if _, lerr := os.Stat(legacy); lerr == nil {
+			if err := migrateLegacySession(id); err != nil {
+				return nil, err
+			}
 		}
-		fmt.Println(tools.Error(fmt.Sprintf("reading sessions: %v", err)))
-		return
 	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records, err := readTranscript(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("session %s is empty", id)
+	}
+	return records, nil
+}
 
-	var sessions []SessionFile
+// sessionIDs lists session IDs on disk (transcripts and legacy files)
+func sessionIDs() ([]string, error) {
+	entries, err := os.ReadDir(sessionDir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
 	for _, e := range entries {
-		if !strings.HasSuffix(e.Name(), ".json") {
-			continue
+		name := e.Name()
+		for _, ext := range []string{transcriptExt, legacySessionExt} {
+			if id, ok := strings.CutSuffix(name, ext); ok && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
 		}
-		sess, err := loadSession(strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return ids, nil
+}
+
+// loadSessions loads every readable session, most recently updated first
+func loadSessions() ([]SessionFile, error) {
+	ids, err := sessionIDs()
+	if err != nil {
+		return nil, err
+	}
+	var sessions []SessionFile
+	for _, id := range ids {
+		sess, err := loadSession(id)
 		if err != nil {
 			continue
 		}
 		sessions = append(sessions, *sess)
 	}
+	slices.SortFunc(sessions, func(a, b SessionFile) int {
+		return b.Meta.UpdatedAt.Compare(a.Meta.UpdatedAt)
+	})
+	return sessions, nil
+}
 
-	if len(sessions) == 0 {
-		fmt.Println(tools.Dim("No sessions saved"))
+// listSessions prints a table of the current project's sessions, or of
+// every session when all is set
+func listSessions(all bool) {
+	sessions, err := loadSessions()
+	if err != nil {
+		if os.IsNotExist(err) {
+			fmt.Println(tools.Dim("No sessions saved"))
+			return
+		}
+		fmt.Println(tools.Error(fmt.Sprintf("reading sessions: %v", err)))
 		return
 	}
 
-	slices.SortFunc(sessions, func(a, b SessionFile) int {
-		return b.Meta.UpdatedAt.Compare(a.Meta.UpdatedAt)
-	})
+	total := len(sessions)
+	project := currentWorkspace().Project()
+	if !all {
+		sessions = projectSessions(sessions, project)
+	}
+	if len(sessions) == 0 {
+		if total > 0 {
+			fmt.Println(tools.Dim(fmt.Sprintf("No sessions in %s (%d in other projects, use -all)", project, total)))
+		} else {
+			fmt.Println(tools.Dim("No sessions saved"))
+		}
+		return
+	}
 
 	// Table styles
 	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#F9FAFB"))
@@ -117,33 +510,76 @@ func listSessions() {
 	cellStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#9CA3AF"))
 	borderStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#374151"))
 
+	// The fourth column is the branch within one project, the project otherwise
+	where, whereWidth := "Branch", 16
+	if all {
+		where, whereWidth = "Project", 20
+	}
+
+	// Forks are listed under their parent, indented one step per generation
+	rows := sessionTree(sessions)
+	idWidth := 36
+	for _, r := range rows {
+		idWidth = max(idWidth, 36+3*r.depth)
+	}
+
 	fmt.Println()
-	fmt.Printf("  %s  %s  %s  %s\n",
-		headerStyle.Width(36).Render("ID"),
-		headerStyle.Width(16).Render("Created"),
+	fmt.Printf("  %s  %s  %s  %s  %s  %s\n",
+		headerStyle.Width(idWidth).Render("ID"),
 		headerStyle.Width(16).Render("Updated"),
-		headerStyle.Render("Msgs"),
+		headerStyle.Width(4).Render("Msgs"),
+		headerStyle.Width(6).Render("Size"),
+		headerStyle.Width(whereWidth).Render(where),
+		headerStyle.Render("Title"),
 	)
-	fmt.Println(borderStyle.Render("  " + strings.Repeat("─", 78)))
-	for _, s := range sessions {
-		fmt.Printf("  %s  %s  %s  %s\n",
-			idStyle.Width(36).Render(s.Meta.ID),
-			cellStyle.Width(16).Render(s.Meta.CreatedAt.Format("2006-01-02 15:04")),
+	fmt.Println(borderStyle.Render("  " + strings.Repeat("─", idWidth+82)))
+	var diskTotal int64
+	for _, r := range rows {
+		s := r.sess
+		id := s.Meta.ID
+		if r.depth > 0 {
+			id = strings.Repeat("   ", r.depth-1) + "└─ " + id
+		}
+		place := s.Meta.Branch
+		if all {
+			place = ""
+			if p := s.Meta.Project(); p != "" {
+				place = filepath.Base(p)
+			}
+		}
+		title := s.Meta.Title
+		if title == "" {
+			title = firstUserMessage(s.Messages)
+		}
+		if s.Pinned {
+			title = "📌 " + title
+		}
+		size := sessionSize(s.Meta.ID)
+		diskTotal += size
+		fmt.Printf("  %s  %s  %s  %s  %s  %s\n",
+			idStyle.Width(idWidth).Render(id),
 			cellStyle.Width(16).Render(s.Meta.UpdatedAt.Format("2006-01-02 15:04")),
-			cellStyle.Render(fmt.Sprintf("%d", len(s.Messages))),
+			cellStyle.Width(4).Render(fmt.Sprintf("%d", len(s.Messages))),
+			cellStyle.Width(6).Render(formatSize(size)),
+			cellStyle.Width(whereWidth).Render(excerpt(place, whereWidth-2)),
+			cellStyle.Render(excerpt(title, 48)),
 		)
 	}
+	fmt.Println(tools.Dim(fmt.Sprintf("\n  %d session(s), %s on disk", len(rows), formatSize(diskTotal))))
+	if hidden := total - len(sessions); hidden > 0 {
+		fmt.Println(tools.Dim(fmt.Sprintf("\n  %d session(s) in other projects, use -all", hidden)))
+	}
 	fmt.Println()
 }
 
 func deleteSession(id string) {
-	path := sessionPath(id)
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		fmt.Println(tools.Error(fmt.Sprintf("session %s not found", id)))
+	found, err := removeSession(id)
+	if err != nil {
+		fmt.Println(tools.Error(fmt.Sprintf("delete failed: %v", err)))
 		return
 	}
-	if err := os.Remove(path); err != nil {
-		fmt.Println(tools.Error(fmt.Sprintf("delete failed: %v", err)))
+	if !found {
+		fmt.Println(tools.Error(fmt.Sprintf("session %s not found", id)))
 		return
 	}
 	fmt.Println(tools.Success("deleted " + id))
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// pickerPageSize caps how many sessions the picker lists at once
+const pickerPageSize = 15
+
+// errPickerCanceled is returned when the user quits the session picker
+var errPickerCanceled = errors.New("no session selected")
+
+// projectSessions keeps the sessions started in project (see Workspace.Project)
+func projectSessions(sessions []SessionFile, project string) []SessionFile {
+	var out []SessionFile
+	for _, s := range sessions {
+		if s.Meta.Project() == project {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// firstUserMessage returns the first typed user message on one line
+func firstUserMessage(messages []claude.MessageParam) string {
+	for _, m := range messages {
+		if m.Role != "user" || isToolResultMessage(m) {
+			continue
+		}
+		if text := messageText(m); text != "" {
+			return strings.Join(strings.Fields(text), " ")
+		}
+	}
+	return ""
+}
+
+// messageText joins the text of a message, whatever shape its content has
+func messageText(m claude.MessageParam) string {
+	var parts []string
+	for _, b := range claude.ContentBlocks(m.Content) {
+		if b.Type == "text" {
+			parts = append(parts, b.Text)
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// formatAge renders how long ago t was, coarsely
+func formatAge(t, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+	return t.Format("2006-01-02")
+}
+
+// fuzzyScore matches query as a case-insensitive subsequence of text. Runs of
+// consecutive characters and matches at word starts score higher.
+func fuzzyScore(query, text string) (int, bool) {
+	q := []rune(strings.ToLower(query))
+	if len(q) == 0 {
+		return 0, true
+	}
+	t := []rune(strings.ToLower(text))
+	score, qi, run := 0, 0, 0
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			run = 0
+			continue
+		}
+		run++
+		score += run
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 3
+		}
+		qi++
+	}
+	return score, qi == len(q)
+}
+
+// filterSessions keeps sessions matching every word of query, best first.
+// Ties keep recency order.
+func filterSessions(sessions []SessionFile, query string) []SessionFile {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return sessions
+	}
+	type scored struct {
+		sess  SessionFile
+		score int
+	}
+	var matches []scored
+	for _, s := range sessions {
+		haystack := strings.Join([]string{s.Meta.Title, firstUserMessage(s.Messages), s.Meta.Branch, s.Meta.Model, s.Meta.Cwd, s.Meta.ID}, " ")
+		total, ok := 0, true
+		for _, term := range terms {
+			score, matched := fuzzyScore(term, haystack)
+			if !matched {
+				ok = false
+				break
+			}
+			total += score
+		}
+		if ok {
+			matches = append(matches, scored{s, total})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b scored) int { return b.score - a.score })
+	out := make([]SessionFile, len(matches))
+	for i, m := range matches {
+		out[i] = m.sess
+	}
+	return out
+}
+
+// pickSession lists sessions and reads a choice: a number resumes that
+// session, enter resumes the first one shown, other text filters the list,
+// esc clears the filter and q cancels
+func pickSession(r *bufio.Reader, w io.Writer, sessions []SessionFile, now time.Time) (string, error) {
+	if len(sessions) == 0 {
+		return "", errors.New("no sessions saved")
+	}
+	query := ""
+	for {
+		matches := filterSessions(sessions, query)
+		shown := matches
+		if len(shown) > pickerPageSize {
+			shown = shown[:pickerPageSize]
+		}
+		renderPicker(w, shown, len(matches), len(sessions), query, now)
+
+		hint := "number, filter text, enter for 1, q to quit: "
+		if query != "" {
+			hint = "number, filter text, esc to clear, enter for 1, q to quit: "
+		}
+		fmt.Fprint(w, tools.Prompt()+tools.Dim(hint))
+		line, err := r.ReadString('\n')
+		input := strings.TrimSpace(line)
+		if err != nil && input == "" {
+			return "", errPickerCanceled
+		}
+
+		switch n, numErr := strconv.Atoi(input); {
+		case input == "q":
+			return "", errPickerCanceled
+		case input == "\x1b":
+			query = ""
+		case input == "":
+			if len(shown) > 0 {
+				return shown[0].Meta.ID, nil
+			}
+		case numErr == nil:
+			if n >= 1 && n <= len(shown) {
+				return shown[n-1].Meta.ID, nil
+			}
+			fmt.Fprintln(w, tools.Error(fmt.Sprintf("no session %d", n)))
+		default:
+			query = input
+		}
+	}
+}
+
+// renderPicker lists shown, the first page of matched sessions out of total
+func renderPicker(w io.Writer, shown []SessionFile, matched, total int, query string, now time.Time) {
+	fmt.Fprintln(w)
+	if query != "" {
+		fmt.Fprintf(w, "  %s %s\n", tools.Status("filter"), tools.Dim(fmt.Sprintf("%q · %d match(es)", query, matched)))
+	} else {
+		fmt.Fprintf(w, "  %s %s\n", tools.Status("sessions"), tools.Dim(fmt.Sprintf("%d saved", total)))
+	}
+	for i, s := range shown {
+		first := firstUserMessage(s.Messages)
+		title := s.Meta.Title
+		if title == "" {
+			title, first = first, ""
+		}
+		if title == "" {
+			title = "(no messages)"
+		}
+		details := fmt.Sprintf("%s · %d msgs · %s", formatAge(s.Meta.UpdatedAt, now), len(s.Messages), s.Meta.Model)
+		if s.Meta.Branch != "" {
+			details += " · " + s.Meta.Branch
+		}
+		fmt.Fprintf(w, "  %s %s %s\n", tools.OptionNumber(i+1), tools.Highlight(excerpt(title, 60)), tools.Dim(details))
+		if first != "" {
+			fmt.Fprintf(w, "     %s\n", tools.Muted(excerpt(first, 76)))
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+)
+
+func pickerSessions() []SessionFile {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	session := func(id, root, first string, age time.Duration) SessionFile {
+		return SessionFile{
+			Meta: SessionMeta{ID: id, Model: "m", UpdatedAt: now.Add(-age), Workspace: Workspace{Cwd: root + "/src", GitRoot: root}},
+			Messages: []claude.MessageParam{
+				{Role: "user", Content: []claude.ContentBlock{{Type: "text", Text: first}}},
+				{Role: "assistant", Content: "ok"},
+			},
+		}
+	}
+	// most recent first, as loadSessions returns them
+	return []SessionFile{
+		session("a", "/proj/web", "fix the login redirect", time.Hour),
+		session("b", "/proj/api", "add rate limiting to the api", 2*time.Hour),
+		session("c", "/proj/web", "refactor session store", 48*time.Hour),
+	}
+}
+
+func TestFuzzyScore(t *testing.T) {
+	tests := []struct {
+		query, text string
+		ok          bool
+	}{
+		{"rl", "rate limiting", true},
+		{"RATE", "add rate limiting", true},
+		{"lmt", "rate limiting", true},
+		{"zz", "rate limiting", false},
+		{"", "anything", true},
+	}
+	for _, tt := range tests {
+		if _, ok := fuzzyScore(tt.query, tt.text); ok != tt.ok {
+			t.Errorf("fuzzyScore(%q, %q) matched=%v, want %v", tt.query, tt.text, ok, tt.ok)
+		}
+	}
+
+	// word starts and runs beat scattered matches
+	tight, _ := fuzzyScore("login", "fix the login redirect")
+	loose, _ := fuzzyScore("login", "large old git index now")
+	if tight <= loose {
+		t.Errorf("expected contiguous match to score higher: %d <= %d", tight, loose)
+	}
+}
+
+func TestFilterSessions(t *testing.T) {
+	// given
+	sessions := pickerSessions()
+
+	// when
+	got := filterSessions(sessions, "web sess")
+
+	// then
+	if len(got) != 1 || got[0].Meta.ID != "c" {
+		t.Errorf("expected only session c, got %v", got)
+	}
+	if all := filterSessions(sessions, "  "); len(all) != 3 {
+		t.Errorf("expected empty query to keep all sessions, got %d", len(all))
+	}
+}
+
+func TestPickSession(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		err   error
+	}{
+		{"enter picks most recent", "\n", "a", nil},
+		{"number picks row", "2\n", "b", nil},
+		{"filter then number", "refactor\n1\n", "c", nil},
+		{"filter then enter", "rate lim\n\n", "b", nil},
+		{"out of range retries", "9\n3\n", "c", nil},
+		{"esc clears the filter", "refactor\n\x1b\n2\n", "b", nil},
+		{"q cancels", "q\n", "", errPickerCanceled},
+		{"eof cancels", "", "", errPickerCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			id, err := pickSession(bufio.NewReader(strings.NewReader(tt.input)), io.Discard, pickerSessions(), now)
+
+			// then
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if id != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, id)
+			}
+		})
+	}
+}
+
+func TestPickSession_CountsEveryMatch(t *testing.T) {
+	// given - more matching sessions than fit on a page
+	var sessions []SessionFile
+	for i := range pickerPageSize + 5 {
+		sessions = append(sessions, SessionFile{Meta: SessionMeta{ID: fmt.Sprintf("s%d", i), Title: "fix the parser"}})
+	}
+	var out strings.Builder
+
+	// when
+	pickSession(bufio.NewReader(strings.NewReader("parser\nq\n")), &out, sessions, time.Now())
+
+	// then
+	if !strings.Contains(out.String(), fmt.Sprintf("%d match(es)", pickerPageSize+5)) {
+		t.Errorf("expected all %d matches counted, got:\n%s", pickerPageSize+5, out.String())
+	}
+}
+
+func TestProjectSessions(t *testing.T) {
+	// given
+	sessions := append(pickerSessions(), SessionFile{Meta: SessionMeta{ID: "d", Workspace: Workspace{Cwd: "/proj/web"}}})
+
+	// when
+	web := projectSessions(sessions, "/proj/web")
+	other := projectSessions(sessions, "/elsewhere")
+
+	// then - matched by git root, or by cwd outside a repository, recency kept
+	var ids []string
+	for _, s := range web {
+		ids = append(ids, s.Meta.ID)
+	}
+	if strings.Join(ids, ",") != "a,c,d" {
+		t.Errorf("expected a,c,d for /proj/web, got %v", ids)
+	}
+	if len(other) != 0 {
+		t.Errorf("expected no sessions for an unknown project, got %d", len(other))
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{50 * time.Hour, "2d ago"},
+		{90 * 24 * time.Hour, "2025-12-01"},
+	}
+	for _, tt := range tests {
+		if got := formatAge(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("formatAge(-%v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
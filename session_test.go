@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"simpleagent/claude"
+	"simpleagent/tools"
+)
+
+// useTempSessionDir points sessionDir at a fresh directory for one test
+func useTempSessionDir(t *testing.T) {
+	t.Helper()
+	old := sessionDir
+	sessionDir = t.TempDir()
+	t.Cleanup(func() { sessionDir = old })
+}
+
+func TestAgentSave_AppendsOnlyChanges(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	todos := []tools.Todo{}
+	agent := &Agent{sessionID: "s1", model: "m", todos: &todos, permissionsMode: "prompt", workspace: currentWorkspace()}
+	agent.messages = []claude.MessageParam{{Role: "user", Content: "hi"}}
+	agent.recordModel(1, "m")
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "assistant", Content: []claude.ContentBlock{{Type: "text", Text: "hello"}}})
+	agent.recordUsage(&claude.Usage{InputTokens: 10, OutputTokens: 5})
+	agent.Save()
+
+	// when - one more exchange, a todo and plan mode
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "user", Content: []claude.ToolResultBlock{{Type: "tool_result", ToolUseID: "t1", Content: "ok"}}})
+	todos = append(todos, tools.Todo{Content: "a", ActiveForm: "doing a", Status: "pending"})
+	agent.planMode = true
+	agent.recordUsage(&claude.Usage{InputTokens: 20, OutputTokens: 5})
+	err := agent.Save()
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	path, _ := sessionPath("s1")
+	data, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	wantTypes := []string{recordMeta, recordUser, recordAssistant, recordMode, recordUsage, recordToolResult, recordTodos, recordMode, recordUsage}
+	if len(lines) != len(wantTypes) {
+		t.Fatalf("expected %d records, got %d:\n%s", len(wantTypes), len(lines), data)
+	}
+	for i, line := range lines {
+		var rec Record
+		json.Unmarshal([]byte(line), &rec)
+		if rec.Type != wantTypes[i] {
+			t.Errorf("record %d: expected %s, got %s", i, wantTypes[i], rec.Type)
+		}
+	}
+	sess, err := loadSession("s1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(sess.Messages) != 3 || sess.MessageModels[1] != "m" || len(sess.Todos) != 1 || !sess.PlanMode {
+		t.Errorf("unexpected replayed session: %+v", sess)
+	}
+	if sess.Meta.Workspace != currentWorkspace() || sess.Meta.GitRoot == "" {
+		t.Errorf("expected workspace %+v recorded, got %+v", currentWorkspace(), sess.Meta.Workspace)
+	}
+	if sess.Usage.InputTokens != 30 || sess.Usage.OutputTokens != 10 {
+		t.Errorf("expected summed usage, got %+v", sess.Usage)
+	}
+}
+
+func TestLoadSession_RecoversTornLine(t *testing.T) {
+	// given - a crash left half a record at the end
+	useTempSessionDir(t)
+	todos := []tools.Todo{}
+	agent := &Agent{sessionID: "s2", model: "m", todos: &todos}
+	agent.messages = []claude.MessageParam{{Role: "user", Content: "first"}}
+	agent.Save()
+	path, _ := sessionPath("s2")
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"type":"user","ts":"2026-01-01T00:00:00Z","message":{"role":"us`)
+	f.Close()
+
+	// when
+	sess, err := loadSession("s2")
+	agent.messages = append(agent.messages, claude.MessageParam{Role: "user", Content: "second"})
+	saveErr := agent.Save()
+	after, _ := loadSession("s2")
+
+	// then
+	if err != nil || len(sess.Messages) != 1 {
+		t.Fatalf("expected torn line skipped, got %v / %+v", err, sess)
+	}
+	if saveErr != nil || len(after.Messages) != 2 || after.Messages[1].Content != "second" {
+		t.Errorf("expected append after truncating the torn line, got %v / %+v", saveErr, after)
+	}
+}
+
+func TestLoadSession_MigratesLegacyJSON(t *testing.T) {
+	// given
+	useTempSessionDir(t)
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	legacy := SessionFile{
+		Meta:          SessionMeta{ID: "old", CreatedAt: created, UpdatedAt: created.Add(time.Hour), Model: "m"},
+		Messages:      []claude.MessageParam{{Role: "user", Content: "hi"}, {Role: "assistant", Content: "hello"}},
+		MessageModels: map[int]string{1: "m"},
+		Todos:         []tools.Todo{{Content: "x", ActiveForm: "xing", Status: "completed"}},
+		PlanMode:      true,
+	}
+	data, _ := json.Marshal(legacy)
+	legacyPath, _ := legacySessionPath("old")
+	os.WriteFile(legacyPath, data, 0644)
+
+	// when
+	sess, err := loadSession("old")
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sess.Meta.CreatedAt.Equal(created) || len(sess.Messages) != 2 || sess.MessageModels[1] != "m" || len(sess.Todos) != 1 || !sess.PlanMode {
+		t.Errorf("unexpected migrated session: %+v", sess)
+	}
+	if _, err := os.Stat(legacyPath); !os.IsNotExist(err) {
+		t.Error("expected legacy file removed")
+	}
+	path, _ := sessionPath("old")
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected transcript written: %v", err)
+	}
+}
+
+func TestSessionFiles_RejectIDsOutsideSessionDir(t *testing.T) {
+	// given - sessionDir nested in a dir an id could climb into
+	parent := t.TempDir()
+	old := sessionDir
+	sessionDir = filepath.Join(parent, "sessions")
+	t.Cleanup(func() { sessionDir = old })
+	writeConfigFile(t, filepath.Join(parent, "x"+transcriptExt), `{"type":"meta","meta":{"id":"x"}}`+"\n")
+	id := "../x"
+
+	// when
+	_, loadErr := loadSession(id)
+	lock, lockErr := lockSession(id)
+	appendErr := appendRecords(id, []Record{{Type: recordUser, Message: &claude.MessageParam{Role: "user", Content: "hi"}}})
+	pinErr := setPinned(id, true)
+	_, forkErr := createFork(id, -1)
+
+	// then
+	for name, err := range map[string]error{"load": loadErr, "lock": lockErr, "append": appendErr, "pin": pinErr, "fork": forkErr} {
+		if err == nil || !strings.Contains(err.Error(), "invalid session id") {
+			t.Errorf("%s: expected an invalid id error, got %v", name, err)
+		}
+	}
+	if lock != nil {
+		lock.Release()
+	}
+	if _, err := os.Stat(filepath.Join(parent, "x"+lockExt)); !os.IsNotExist(err) {
+		t.Errorf("expected no lock file outside sessionDir, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(parent, "x"+transcriptExt)); strings.Count(string(data), "\n") != 1 {
+		t.Errorf("expected the file outside sessionDir untouched, got %q", data)
+	}
+}
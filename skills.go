@@ -1,6 +1,8 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -29,16 +31,21 @@ var skillPaths = make(map[string]string) // name -> SKILL.md path
 
 // LoadSkillsMeta discovers skills and returns metadata for system prompt
 func LoadSkillsMeta() []SkillMeta {
-	var skills []SkillMeta
-	skillPaths = make(map[string]string)
+	skills, paths, _ := scanSkills()
+	skillPaths = paths
+	return skills
+}
 
-	// Search directories (project takes precedence)
-	dirs := []string{".claude/skills"}
+// skillDirs returns the project and home skill dirs that exist, project
+// first so its skills take precedence
+func skillDirs() []string {
+	baseDirs := []string{".claude/skills"}
 	if home, err := os.UserHomeDir(); err == nil {
-		dirs = append(dirs, filepath.Join(home, ".claude/skills"))
+		baseDirs = append(baseDirs, filepath.Join(home, ".claude/skills"))
 	}
 
-	for _, baseDir := range dirs {
+	var dirs []string
+	for _, baseDir := range baseDirs {
 		dir := findDir(baseDir)
 		if dir == "" {
 			// For home dir, check directly
@@ -48,10 +55,21 @@ func LoadSkillsMeta() []SkillMeta {
 				}
 			}
 		}
-		if dir == "" {
-			continue
+		if dir != "" {
+			dirs = append(dirs, dir)
 		}
+	}
+	return dirs
+}
+
+// scanSkills finds skills in the project and home skill dirs, returning
+// their metadata, SKILL.md paths by name, and why any skill was skipped
+func scanSkills() ([]SkillMeta, map[string]string, []error) {
+	var skills []SkillMeta
+	var errs []error
+	paths := make(map[string]string)
 
+	for _, dir := range skillDirs() {
 		entries, err := os.ReadDir(dir)
 		if err != nil {
 			continue
@@ -65,29 +83,35 @@ func LoadSkillsMeta() []SkillMeta {
 			skillPath := filepath.Join(dir, entry.Name(), "SKILL.md")
 			data, err := os.ReadFile(skillPath)
 			if err != nil {
+				if !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, err)
+				}
 				continue
 			}
 
 			fm, _ := ParseFrontmatter(string(data))
 			if fm == "" {
+				errs = append(errs, fmt.Errorf("%s: no frontmatter", skillPath))
 				continue
 			}
 
 			var skill Skill
 			if err := yaml.Unmarshal([]byte(fm), &skill); err != nil {
+				errs = append(errs, frontmatterError(skillPath, err))
 				continue
 			}
 
 			if skill.Name == "" || skill.Description == "" {
+				errs = append(errs, fmt.Errorf("%s: frontmatter needs name and description", skillPath))
 				continue
 			}
 
 			// Skip if already registered (project takes precedence)
-			if _, exists := skillPaths[skill.Name]; exists {
+			if _, exists := paths[skill.Name]; exists {
 				continue
 			}
 
-			skillPaths[skill.Name] = skillPath
+			paths[skill.Name] = skillPath
 			skills = append(skills, SkillMeta{
 				Name:        skill.Name,
 				Description: skill.Description,
@@ -95,7 +119,7 @@ func LoadSkillsMeta() []SkillMeta {
 		}
 	}
 
-	return skills
+	return skills, paths, errs
 }
 
 // FormatSkillsSection formats skills for system prompt injection
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Thinking display modes
+const (
+	thinkingShow     = "show"     // stream thinking inline (dimmed)
+	thinkingCollapse = "collapse" // print a one-line summary after the response
+	thinkingHide     = "hide"     // discard thinking output
+)
+
+// Thinking budgets for /think levels and keyword triggers
+const (
+	budgetThink     = 4000
+	budgetThinkHard = 10000
+	budgetUltra     = 31999
+)
+
+// minThinkingBudget is the smallest budget the API accepts
+const minThinkingBudget = 1024
+
+// thinkingLevels maps /think arguments to budgets
+var thinkingLevels = map[string]int{
+	"":       budgetThinkHard,
+	"think":  budgetThink,
+	"hard":   budgetThinkHard,
+	"harder": budgetUltra,
+	"ultra":  budgetUltra,
+}
+
+// thinkingKeywords are checked longest-first so "think harder" wins over "think hard"
+var thinkingKeywords = []struct {
+	phrase string
+	budget int
+}{
+	{"ultrathink", budgetUltra},
+	{"think harder", budgetUltra},
+	{"megathink", budgetThinkHard},
+	{"think hard", budgetThinkHard},
+}
+
+// keywordThinkingBudget returns the budget requested by phrases in input, or 0
+func keywordThinkingBudget(input string) int {
+	lower := strings.ToLower(input)
+	for _, k := range thinkingKeywords {
+		if strings.Contains(lower, k.phrase) {
+			return k.budget
+		}
+	}
+	return 0
+}
+
+// parseThinkCommand parses the argument of /think into a token budget
+func parseThinkCommand(arg string) (int, error) {
+	arg = strings.ToLower(strings.TrimSpace(arg))
+	if budget, ok := thinkingLevels[arg]; ok {
+		return budget, nil
+	}
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < minThinkingBudget {
+		return 0, fmt.Errorf("usage: /think [think|hard|harder|ultra|<tokens >= 1024>]")
+	}
+	return n, nil
+}
+
+// nextThinkingDisplay cycles show → collapse → hide
+func nextThinkingDisplay(mode string) string {
+	switch mode {
+	case thinkingShow:
+		return thinkingCollapse
+	case thinkingCollapse:
+		return thinkingHide
+	default:
+		return thinkingShow
+	}
+}
+
+// validThinkingDisplay reports whether mode is a known display mode
+func validThinkingDisplay(mode string) bool {
+	return mode == thinkingShow || mode == thinkingCollapse || mode == thinkingHide
+}
+
+// requestLimits returns max_tokens and thinking budget for a request.
+// A budget below the API minimum is raised to it, and max_tokens is raised
+// when needed so it always exceeds the budget.
+func requestLimits(maxTokens, budget int) (int, int) {
+	if budget > 0 && budget < minThinkingBudget {
+		budget = minThinkingBudget
+	}
+	if budget > 0 && maxTokens <= budget {
+		maxTokens = budget + DefaultMaxTokens
+	}
+	return maxTokens, budget
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+
+	"simpleagent/claude"
+)
+
+const (
+	titleMaxTokens   = 64
+	titleMaxRunes    = 80
+	titleExcerptSize = 2000 // runes of each side of the first exchange sent
+)
+
+const titlePrompt = `You name conversations between a user and a coding agent.
+Reply with a title of 3 to 7 words describing the user's task: no quotes, no trailing punctuation, nothing else.`
+
+// ensureTitle names the session after its first exchange. The model writes
+// the title; if that fails the first user message stands in.
+func (a *Agent) ensureTitle() {
+	if a.title != "" {
+		return
+	}
+	question := firstUserMessage(a.messages)
+	answer := firstAssistantText(a.messages)
+	if question == "" || answer == "" {
+		return
+	}
+
+	exchange := "User: " + excerpt(question, titleExcerptSize) + "\n\nAssistant: " + excerpt(answer, titleExcerptSize)
+	msg, err := a.client.Messages.Create(claude.MessageCreateParams{
+		Model:     a.model,
+		MaxTokens: titleMaxTokens,
+		System:    titlePrompt,
+		Messages:  []claude.MessageParam{{Role: "user", Content: exchange}},
+	})
+	if err == nil {
+		var text strings.Builder
+		for _, b := range msg.Content {
+			if b.Type == "text" {
+				text.WriteString(b.Text)
+			}
+		}
+		a.title = cleanTitle(text.String())
+	}
+	if a.title == "" {
+		a.title = cleanTitle(question)
+	}
+}
+
+// firstAssistantText returns the text of the first assistant reply that has any
+func firstAssistantText(messages []claude.MessageParam) string {
+	for _, m := range messages {
+		if m.Role != "assistant" {
+			continue
+		}
+		if text := strings.TrimSpace(messageText(m)); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// cleanTitle keeps the first line, strips quoting and trailing punctuation
+// and caps the length at a word boundary
+func cleanTitle(s string) string {
+	s, _, _ = strings.Cut(strings.TrimSpace(s), "\n")
+	s = strings.TrimPrefix(s, "Title:")
+	s = strings.Trim(strings.TrimSpace(s), "\"'`*#")
+	s = strings.TrimRight(strings.Join(strings.Fields(s), " "), ".!?:;,")
+	if r := []rune(s); len(r) > titleMaxRunes {
+		s = string(r[:titleMaxRunes])
+		if i := strings.LastIndex(s, " "); i > 0 {
+			s = s[:i]
+		}
+		s += "…"
+	}
+	return s
+}
+
+// excerpt truncates s to n runes
+func excerpt(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
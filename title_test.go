@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+
+	"simpleagent/claude"
+	"simpleagent/claude/claudetest"
+	"simpleagent/tools"
+)
+
+func TestAgent_EnsureTitle(t *testing.T) {
+	exchange := []claude.MessageParam{
+		{Role: "user", Content: "the login page redirects to /404 after signing in"},
+		{Role: "assistant", Content: []claude.ContentBlock{{Type: "text", Text: "The redirect target is built from a stale route."}}},
+	}
+	tests := []struct {
+		name  string
+		turns []claudetest.Turn
+		want  string
+	}{
+		{"model title cleaned", []claudetest.Turn{claudetest.Reply(claudetest.Text("\"Fix login redirect to 404.\"\nextra"))}, "Fix login redirect to 404"},
+		{"fallback to first message", nil, "the login page redirects to /404 after signing in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			srv := claudetest.NewServer(t, tt.turns...)
+			agent, _ := NewAgent("", nil, srv.Client(), nil, "system", "test-model", nil, &[]tools.Todo{})
+			agent.messages = exchange
+
+			// when
+			agent.ensureTitle()
+			agent.ensureTitle() // titled sessions make no further requests
+
+			// then
+			if agent.title != tt.want {
+				t.Errorf("expected title %q, got %q", tt.want, agent.title)
+			}
+			reqs := srv.Requests()
+			if len(reqs) != 1 || reqs[0].Stream || reqs[0].Thinking != nil || len(reqs[0].Tools) != 0 {
+				t.Errorf("expected one plain title request, got %+v", reqs)
+			}
+		})
+	}
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Fix login redirect", "Fix login redirect"},
+		{"Title: **Add rate limiting.**", "Add rate limiting"},
+		{"  'Refactor   session store'  \nBecause...", "Refactor session store"},
+		{"word word word word word word word word word word word word word word word word word", "word word word word word word word word word word word word word word word word…"},
+	}
+	for _, tt := range tests {
+		if got := cleanTitle(tt.in); got != tt.want {
+			t.Errorf("cleanTitle(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
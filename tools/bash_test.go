@@ -2,6 +2,7 @@ package tools
 
 import (
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -166,5 +167,5 @@ func TestBash_Render(t *testing.T) {
 	result := bash(input)
 
 	// then - should not panic
-	result.Render()
+	result.Render(io.Discard)
 }
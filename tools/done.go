@@ -26,6 +26,7 @@ var DoneTool = claude.Tool{
 func init() {
 	// Register executor only, not the tool definition (subagent-only)
 	registry["Done"] = executeDone
+	schemas["Done"] = DoneTool.InputSchema
 }
 
 func executeDone(input json.RawMessage) Result {
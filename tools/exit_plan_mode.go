@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -56,15 +57,15 @@ func (r exitPlanModeResult) String() string {
 	return string(data)
 }
 
-func (r exitPlanModeResult) Render() {
-	fmt.Println()
-	fmt.Println(Plan("plan review"))
-	fmt.Println()
-	fmt.Print(renderMarkdown(r.plan))
-	fmt.Println(Plan("options"))
-	fmt.Printf("  %s %s %s\n", OptionNumber(1), Highlight("Accept"), Dim("- exit plan mode, proceed"))
-	fmt.Printf("  %s %s %s\n", OptionNumber(2), Highlight("Deny"), Dim("- stay, agent revises"))
-	fmt.Printf("  %s %s %s\n", OptionNumber(3), Highlight("Continue"), Dim("- stay, keep exploring"))
+func (r exitPlanModeResult) Render(w io.Writer) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, Plan("plan review"))
+	fmt.Fprintln(w)
+	fmt.Fprint(w, renderMarkdown(r.plan))
+	fmt.Fprintln(w, Plan("options"))
+	fmt.Fprintf(w, "  %s %s %s\n", OptionNumber(1), Highlight("Accept"), Dim("- exit plan mode, proceed"))
+	fmt.Fprintf(w, "  %s %s %s\n", OptionNumber(2), Highlight("Deny"), Dim("- stay, agent revises"))
+	fmt.Fprintf(w, "  %s %s %s\n", OptionNumber(3), Highlight("Continue"), Dim("- stay, keep exploring"))
 }
 
 func executeExitPlanMode(input json.RawMessage) Result {
@@ -14,6 +14,10 @@ import (
 // Returns (rule content, matched rule sources)
 var RuleMatcher func(filePath string) (string, []string)
 
+// MemoryLoader returns memory files not yet loaded that apply to a file path
+// Returns (memory content, loaded sources)
+var MemoryLoader func(filePath string) (string, []string)
+
 func init() {
 	register(claude.Tool{
 		Name:        "ReadFile",
@@ -120,6 +124,12 @@ func readFile(input json.RawMessage) Result {
 			content += "\n\n" + rules
 		}
 	}
+	if MemoryLoader != nil {
+		if memory, sources := MemoryLoader(args.Path); memory != "" {
+			fmt.Println(Status(fmt.Sprintf("loaded memory: %v", sources)))
+			content += "\n\n" + memory
+		}
+	}
 
 	return newResult("ReadFile", content)
 }
@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -47,8 +48,8 @@ func (r globResult) String() string {
 	return sb.String()
 }
 
-func (r globResult) Render() {
-	fmt.Printf("\n%s\n", r)
+func (r globResult) Render(w io.Writer) {
+	fmt.Fprintf(w, "\n%s\n", r)
 }
 
 func plural(n int) string {
@@ -75,7 +76,8 @@ func init() {
 				},
 				"type": {
 					Type:        "string",
-					Description: "Filter by type: 'file', 'dir', 'symlink' (default: all)",
+					Description: "Filter by type (default: all)",
+					Enum:        []any{"file", "dir", "symlink"},
 				},
 				"hidden": {
 					Type:        "boolean",
@@ -84,6 +86,7 @@ func init() {
 				"limit": {
 					Type:        "integer",
 					Description: "Maximum number of results (default: 100)",
+					Minimum:     claude.Float(1),
 				},
 			},
 			Required: []string{"pattern"},
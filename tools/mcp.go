@@ -13,9 +13,10 @@ import (
 )
 
 type mcpServer struct {
-	name   string
-	client *client.Client
-	tools  []mcp.Tool
+	name    string
+	client  *client.Client
+	tools   []mcp.Tool
+	schemas map[string]claude.InputSchema // tool name -> converted input schema
 }
 
 // MCPClients manages connections to MCP servers
@@ -47,6 +48,17 @@ func NewMCPClients(ctx context.Context, configs []MCPServerConfig) *MCPClients {
 	return mc
 }
 
+// CheckMCPServer starts a server, completes the handshake and lists its
+// tools, returning how many it offers
+func CheckMCPServer(ctx context.Context, cfg MCPServerConfig) (int, error) {
+	srv, err := connectMCPServer(ctx, cfg.Name, cfg.Cmd, cfg.Args)
+	if err != nil {
+		return 0, err
+	}
+	defer srv.client.Close()
+	return len(srv.tools), nil
+}
+
 func connectMCPServer(ctx context.Context, name, cmd string, args []string) (*mcpServer, error) {
 	c, err := client.NewStdioMCPClient(cmd, nil, args...)
 	if err != nil {
@@ -73,7 +85,18 @@ func connectMCPServer(ctx context.Context, name, cmd string, args []string) (*mc
 		return nil, fmt.Errorf("list tools: %w", err)
 	}
 
-	return &mcpServer{name: name, client: c, tools: result.Tools}, nil
+	srv := &mcpServer{name: name, client: c, schemas: make(map[string]claude.InputSchema)}
+	for _, t := range result.Tools {
+		schema, err := convertMCPSchema(t)
+		if err != nil {
+			// offered without its schema, its input would go unchecked
+			fmt.Printf("[mcp:%s] skipping tool %s: %v\n", name, t.Name, err)
+			continue
+		}
+		srv.tools = append(srv.tools, t)
+		srv.schemas[t.Name] = schema
+	}
+	return srv, nil
 }
 
 // Tools returns all MCP tools in Claude format
@@ -84,25 +107,44 @@ func (mc *MCPClients) Tools() []claude.Tool {
 			tools = append(tools, claude.Tool{
 				Name:        srv.name + "__" + t.Name,
 				Description: t.Description,
-				InputSchema: convertMCPSchema(t.InputSchema),
+				InputSchema: srv.schemas[t.Name],
 			})
 		}
 	}
 	return tools
 }
 
-// Execute finds and calls the tool on the right server
-func (mc *MCPClients) Execute(ctx context.Context, name string, input json.RawMessage) (string, bool) {
+// lookup finds the server and raw tool name for a prefixed MCP tool name
+// ("server__tool"); names without a server's prefix match none of its tools
+func (mc *MCPClients) lookup(name string) (*mcpServer, string, bool) {
 	for _, srv := range mc.servers {
-		for _, t := range srv.tools {
-			// Handle prefixed name (format: "server__tool")
-			rawName := strings.TrimPrefix(name, srv.name+"__")
-			if t.Name == rawName {
-				return executeMCPTool(ctx, srv, rawName, input), true
-			}
+		rawName, ok := strings.CutPrefix(name, srv.name+"__")
+		if !ok {
+			continue
+		}
+		if _, ok := srv.schemas[rawName]; ok {
+			return srv, rawName, true
 		}
 	}
-	return "", false
+	return nil, "", false
+}
+
+// Schema returns the Claude-format input schema for a (prefixed) MCP tool name
+func (mc *MCPClients) Schema(name string) (claude.InputSchema, bool) {
+	srv, rawName, ok := mc.lookup(name)
+	if !ok {
+		return claude.InputSchema{}, false
+	}
+	return srv.schemas[rawName], true
+}
+
+// Execute finds and calls the tool on the right server
+func (mc *MCPClients) Execute(ctx context.Context, name string, input json.RawMessage) (string, bool) {
+	srv, rawName, ok := mc.lookup(name)
+	if !ok {
+		return "", false
+	}
+	return executeMCPTool(ctx, srv, rawName, input), true
 }
 
 func executeMCPTool(ctx context.Context, srv *mcpServer, name string, input json.RawMessage) string {
@@ -139,30 +181,26 @@ func (mc *MCPClients) Close() {
 	}
 }
 
-func convertMCPSchema(schema mcp.ToolInputSchema) claude.InputSchema {
-	props := make(map[string]claude.Property)
-	for name, p := range schema.Properties {
-		props[name] = convertMCPProperty(p)
-	}
-	return claude.InputSchema{
-		Type:       "object",
-		Properties: props,
-		Required:   schema.Required,
-	}
-}
-
-func convertMCPProperty(p any) claude.Property {
-	m, ok := p.(map[string]any)
-	if !ok {
-		return claude.Property{Type: "string"}
+// convertMCPSchema passes an MCP inputSchema through to Claude format via JSON,
+// keeping every keyword, including ones the validator does not check
+func convertMCPSchema(t mcp.Tool) (claude.InputSchema, error) {
+	data := []byte(t.RawInputSchema)
+	if len(data) == 0 {
+		var err error
+		if data, err = json.Marshal(t.InputSchema); err != nil {
+			return claude.InputSchema{}, fmt.Errorf("input schema: %w", err)
+		}
 	}
 
-	prop := claude.Property{}
-	if t, ok := m["type"].(string); ok {
-		prop.Type = t
+	var schema claude.InputSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return claude.InputSchema{}, fmt.Errorf("input schema: %w", err)
 	}
-	if d, ok := m["description"].(string); ok {
-		prop.Description = d
+	if schema.Boolean != nil && !*schema.Boolean {
+		return claude.InputSchema{}, fmt.Errorf("input schema is false, so no input is valid")
 	}
-	return prop
+	schema.Boolean = nil // the root must be an object schema
+	schema.Type = "object"
+	schema.Types = nil
+	return schema, nil
 }
@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"simpleagent/claude"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+func TestConvertMCPSchema_KeepsConstraints(t *testing.T) {
+	// given - MCP tool with enum, default and nested required
+	tool := mcp.Tool{
+		Name: "search",
+		InputSchema: mcp.ToolInputSchema{
+			Type: "object",
+			Properties: map[string]any{
+				"order": map[string]any{"type": "string", "enum": []any{"asc", "desc"}, "default": "asc"},
+				"filter": map[string]any{
+					"type":       "object",
+					"properties": map[string]any{"field": map[string]any{"type": "string"}},
+					"required":   []any{"field"},
+				},
+			},
+			Required: []string{"order"},
+		},
+	}
+
+	// when
+	schema, err := convertMCPSchema(tool)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	order := schema.Properties["order"]
+	if len(order.Enum) != 2 || order.Default != "asc" {
+		t.Errorf("expected enum and default preserved, got %+v", order)
+	}
+	if req := schema.Properties["filter"].Required; len(req) != 1 || req[0] != "field" {
+		t.Errorf("expected nested required preserved, got %v", req)
+	}
+	if len(schema.Required) != 1 {
+		t.Errorf("expected top-level required, got %v", schema.Required)
+	}
+}
+
+func TestConvertMCPSchema_RawSchema(t *testing.T) {
+	// given - MCP tool declared with a raw schema
+	tool := mcp.Tool{
+		Name:           "raw",
+		RawInputSchema: json.RawMessage(`{"type":"object","properties":{"n":{"type":"integer","minimum":1}},"additionalProperties":false}`),
+	}
+
+	// when
+	schema, err := convertMCPSchema(tool)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := schema.Properties["n"]; n.Minimum == nil || *n.Minimum != 1 {
+		t.Errorf("expected minimum preserved, got %+v", n)
+	}
+	if _, allowed := schema.AdditionalSchema(); allowed {
+		t.Error("expected additionalProperties false preserved")
+	}
+}
+
+func TestConvertMCPSchema_Errors(t *testing.T) {
+	tests := map[string]string{
+		"malformed":       `{"type":"object","properties":{"n":{"minimum":"one"}}}`,
+		"accepts nothing": `false`,
+	}
+	for name, raw := range tests {
+		t.Run(name, func(t *testing.T) {
+			// given
+			tool := mcp.Tool{Name: "raw", RawInputSchema: json.RawMessage(raw)}
+
+			// when
+			_, err := convertMCPSchema(tool)
+
+			// then
+			if err == nil {
+				t.Error("expected an error instead of an unconstrained schema")
+			}
+		})
+	}
+}
+
+func TestConvertMCPSchema_TupleItems(t *testing.T) {
+	// given - array-form items used to fail the whole schema
+	tool := mcp.Tool{
+		Name:           "raw",
+		RawInputSchema: json.RawMessage(`{"type":"object","properties":{"point":{"type":"array","items":[{"type":"number"},{"type":"number"}]}},"required":["point"]}`),
+	}
+
+	// when
+	schema, err := convertMCPSchema(tool)
+
+	// then
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(schema.Properties["point"].TupleItems) != 2 || len(schema.Required) != 1 {
+		t.Errorf("expected tuple items and required kept, got %+v", schema)
+	}
+}
+
+func TestMCPClients_SchemaNeedsServerPrefix(t *testing.T) {
+	// given - two servers, each with a tool called search
+	server := func(name string) *mcpServer {
+		return &mcpServer{
+			name:    name,
+			tools:   []mcp.Tool{{Name: "search"}},
+			schemas: map[string]claude.InputSchema{"search": {Type: "object", Description: name}},
+		}
+	}
+	mc := &MCPClients{servers: []*mcpServer{server("docs"), server("code")}}
+
+	// when
+	code, ok := mc.Schema("code__search")
+	_, bare := mc.Schema("search")
+	_, other := mc.Schema("web__search")
+
+	// then
+	if !ok || code.Description != "code" {
+		t.Errorf("expected code's schema, got %+v (%v)", code, ok)
+	}
+	if bare || other {
+		t.Errorf("expected names without a server prefix unmatched, got %v / %v", bare, other)
+	}
+}
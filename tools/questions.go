@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
 	"strings"
@@ -36,6 +37,8 @@ func init() {
 				"questions": {
 					Type:        "array",
 					Description: "Questions to ask (1-4)",
+					MinItems:    claude.Int(1),
+					MaxItems:    claude.Int(4),
 					Items: &claude.Property{
 						Type: "object",
 						Properties: map[string]claude.Property{
@@ -45,15 +48,19 @@ func init() {
 							"options": {
 								Type:        "array",
 								Description: "Available choices (2-4 options)",
+								MinItems:    claude.Int(2),
+								MaxItems:    claude.Int(4),
 								Items: &claude.Property{
 									Type: "object",
 									Properties: map[string]claude.Property{
 										"label":       {Type: "string", Description: "Option display text (1-5 words)"},
 										"description": {Type: "string", Description: "Explanation of option"},
 									},
+									Required: []string{"label"},
 								},
 							},
 						},
+						Required: []string{"question", "header", "options"},
 					},
 				},
 			},
@@ -75,8 +82,8 @@ func (r questionResult) String() string {
 	return `{"status":"awaiting_input"}`
 }
 
-func (r questionResult) Render() {
-	renderQuestions(r.questions)
+func (r questionResult) Render(w io.Writer) {
+	renderQuestions(w, r.questions)
 }
 
 func executeQuestion(input json.RawMessage) Result {
@@ -100,12 +107,12 @@ func executeQuestion(input json.RawMessage) Result {
 	return questionResult{questions: args.Questions, answers: questionAnswers}
 }
 
-func renderQuestions(questions []Question) {
-	fmt.Println()
+func renderQuestions(w io.Writer, questions []Question) {
+	fmt.Fprintln(w)
 	for _, q := range questions {
-		fmt.Printf("  %s %s\n", Status(q.Header), q.Question)
+		fmt.Fprintf(w, "  %s %s\n", Status(q.Header), q.Question)
 		for i, opt := range q.Options {
-			fmt.Printf("    %s %s %s\n", OptionNumber(i+1), Highlight(opt.Label), Dim("- "+opt.Description))
+			fmt.Fprintf(w, "    %s %s %s\n", OptionNumber(i+1), Highlight(opt.Label), Dim("- "+opt.Description))
 		}
 	}
 }
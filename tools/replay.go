@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Replay rebuilds the Result of a saved tool call from its input and output,
+// so a session can be rendered again without running anything. Prompts that
+// were answered live show the recorded answer instead of waiting for one.
+func Replay(name string, input json.RawMessage, output string) Result {
+	switch name {
+	case "Bash", "Grep":
+		return rawResult{output: output}
+	case "Glob":
+		return rawResult{output: "\n" + output + "\n"}
+	case "TodoWrite":
+		var args struct {
+			Todos []Todo `json:"todos"`
+		}
+		if json.Unmarshal(input, &args) == nil {
+			return replayedTodos{todos: args.Todos, output: output}
+		}
+	case "ExitPlanMode":
+		var args ExitPlanModeInput
+		var decision ExitPlanModeDecision
+		if json.Unmarshal(input, &args) == nil && json.Unmarshal([]byte(output), &decision) == nil {
+			return replayedPlan{exitPlanModeResult{plan: args.Plan, decision: decision.Decision, note: decision.Note}}
+		}
+	case "AskUserQuestion":
+		var args struct {
+			Questions []Question `json:"questions"`
+		}
+		var answers map[string]string
+		if json.Unmarshal(input, &args) == nil && json.Unmarshal([]byte(output), &answers) == nil {
+			return replayedQuestions{questionResult{questions: args.Questions, answers: answers}}
+		}
+	case "InvokeSkill":
+		var args struct {
+			Name string `json:"name"`
+		}
+		json.Unmarshal(input, &args)
+		return skillResult{name: args.Name, content: output}
+	case "Task":
+		var args struct {
+			Description string `json:"description"`
+		}
+		json.Unmarshal(input, &args)
+		return taskResult{description: args.Description, output: output}
+	}
+	return newResult(name, output)
+}
+
+// replayedTodos renders the list a TodoWrite call set
+type replayedTodos struct {
+	todos  []Todo
+	output string
+}
+
+func (r replayedTodos) String() string     { return r.output }
+func (r replayedTodos) Render(w io.Writer) { RenderTodos(w, r.todos) }
+
+// replayedPlan shows a plan review with the decision taken
+type replayedPlan struct{ exitPlanModeResult }
+
+func (r replayedPlan) Render(w io.Writer) {
+	r.exitPlanModeResult.Render(w)
+	fmt.Fprintf(w, "\n%s%s", Prompt(), Highlight(r.decision))
+	if r.note != "" {
+		fmt.Fprint(w, " "+Dim(r.note))
+	}
+	fmt.Fprintln(w)
+}
+
+// replayedQuestions shows questions with the answers given
+type replayedQuestions struct{ questionResult }
+
+func (r replayedQuestions) Render(w io.Writer) {
+	r.questionResult.Render(w)
+	for _, q := range r.questions {
+		if answer, ok := r.answers[q.Header]; ok {
+			fmt.Fprintf(w, "  %s %s\n", Prompt(), Highlight(answer))
+		}
+	}
+}
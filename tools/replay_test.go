@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	tests := []struct {
+		name   string
+		tool   string
+		input  string
+		output string
+		check  func(Result) bool
+	}{
+		{"raw output", "Bash", `{"args":"ls"}`, "a.go\n", func(r Result) bool {
+			_, ok := r.(rawResult)
+			return ok
+		}},
+		{"todos from input", "TodoWrite", `{"todos":[{"content":"a","active_form":"doing a","status":"pending"}]}`, `{"success":true,"count":1}`, func(r Result) bool {
+			todos, ok := r.(replayedTodos)
+			return ok && len(todos.todos) == 1 && todos.todos[0].Content == "a"
+		}},
+		{"plan with decision", "ExitPlanMode", `{"plan":"1. refactor"}`, `{"decision":"Deny","note":"smaller steps"}`, func(r Result) bool {
+			plan, ok := r.(replayedPlan)
+			return ok && plan.plan == "1. refactor" && plan.decision == "Deny" && plan.note == "smaller steps"
+		}},
+		{"questions with answers", "AskUserQuestion", `{"questions":[{"question":"Which?","header":"Pick","options":[]}]}`, `{"Pick":"left"}`, func(r Result) bool {
+			q, ok := r.(replayedQuestions)
+			return ok && len(q.questions) == 1 && q.answers["Pick"] == "left"
+		}},
+		{"task label", "Task", `{"prompt":"p","description":"find callers"}`, "summary", func(r Result) bool {
+			task, ok := r.(taskResult)
+			return ok && task.description == "find callers"
+		}},
+		{"unparseable falls back to badge", "ExitPlanMode", `{"plan":"x"}`, "error: bad input", func(r Result) bool {
+			_, ok := r.(toolResult)
+			return ok
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// when
+			r := Replay(tt.tool, json.RawMessage(tt.input), tt.output)
+
+			// then
+			if r.String() != tt.output {
+				t.Errorf("expected String() to be the recorded output, got %q", r.String())
+			}
+			if !tt.check(r) {
+				t.Errorf("unexpected result %#v", r)
+			}
+		})
+	}
+}
+
+func TestReplay_RenderShowsRecordedAnswers(t *testing.T) {
+	// given
+	plan := Replay("ExitPlanMode", json.RawMessage(`{"plan":"1. refactor"}`), `{"decision":"Deny","note":"smaller steps"}`)
+	questions := Replay("AskUserQuestion", json.RawMessage(`{"questions":[{"question":"Which?","header":"Pick","options":[]}]}`), `{"Pick":"left"}`)
+
+	// when
+	var buf strings.Builder
+	plan.Render(&buf)
+	questions.Render(&buf)
+
+	// then
+	for _, want := range []string{"1. refactor", "Deny", "smaller steps", "Which?", "left"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q rendered, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"simpleagent/claude"
 )
@@ -39,8 +40,8 @@ type skillResult struct {
 }
 
 func (r skillResult) String() string { return r.content }
-func (r skillResult) Render() {
-	fmt.Printf("\n%s %s\n", infoBadge.Render("skill"), dimStyle.Render(r.name))
+func (r skillResult) Render(w io.Writer) {
+	fmt.Fprintf(w, "\n%s %s\n", infoBadge.Render("skill"), dimStyle.Render(r.name))
 }
 
 func invokeSkill(input json.RawMessage) Result {
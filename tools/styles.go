@@ -27,6 +27,23 @@ var (
 	colorDimText = lipgloss.Color("#9CA3AF") // gray-400
 )
 
+// Palette exposes the colors by role for renderers outside the terminal
+// (HTML session export)
+var Palette = map[string]string{
+	"primary":   string(colorPrimary),
+	"secondary": string(colorSecondary),
+	"accent":    string(colorAccent),
+	"success":   string(colorSuccess),
+	"warning":   string(colorWarning),
+	"error":     string(colorError),
+	"info":      string(colorInfo),
+	"muted":     string(colorMuted),
+	"subtle":    string(colorSubtle),
+	"border":    string(colorBorder),
+	"text":      string(colorText),
+	"dim":       string(colorDimText),
+}
+
 // Styles
 var (
 	// Base text styles
@@ -175,3 +192,15 @@ func DiffRemove(s string) string {
 func Thinking(s string) string {
 	return mutedStyle.Render(s)
 }
+
+// ContextGauge formats context window usage, amber from 60% and red from 80%
+func ContextGauge(percent int) string {
+	style := dimStyle
+	switch {
+	case percent >= 80:
+		style = lipgloss.NewStyle().Foreground(colorError)
+	case percent >= 60:
+		style = lipgloss.NewStyle().Foreground(colorWarning)
+	}
+	return style.Render(fmt.Sprintf("%d%%", percent))
+}
@@ -25,30 +25,51 @@ func init() {
 	SubagentTools = append(SubagentTools, DoneTool)
 }
 
+// subagentMaxTurns bounds a subagent's tool loop
+const subagentMaxTurns = 100
+
+// subagentMaxTokens is max_tokens when the config sets none
+const subagentMaxTokens = 16384
+
 // RunSubagent executes a subagent with isolated message history
-// Returns summary from done tool or final text response
-func RunSubagent(client *claude.Client, model, systemPrompt, prompt string) (string, error) {
+// Returns summary from done tool or final text response. The last allowed
+// turn forces a Done call so findings are never lost to the turn limit.
+func RunSubagent(cfg SubagentConfig, prompt string) (string, error) {
 	// Note: timeout checked between turns; API calls use client.Timeout (10 min)
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Minute)
 	defer cancel()
 
 	messages := []claude.MessageParam{{Role: "user", Content: prompt}}
-	const maxTurns = 100
+	maxTokens := cfg.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = subagentMaxTokens
+	}
 
-	for turn := 0; turn < maxTurns; turn++ {
+	for turn := 0; turn < subagentMaxTurns; turn++ {
 		select {
 		case <-ctx.Done():
 			return "", errors.New("subagent timeout")
 		default:
 		}
 
-		msg, err := client.Messages.Create(claude.MessageCreateParams{
-			Model:     model,
-			MaxTokens: 16384,
-			System:    systemPrompt,
+		params := claude.MessageCreateParams{
+			Model:     cfg.Model,
+			MaxTokens: maxTokens,
+			System:    cfg.SystemPrompt,
 			Messages:  messages,
 			Tools:     SubagentTools,
-		})
+			Sampling:  cfg.Sampling,
+		}
+		if turn == subagentMaxTurns-1 {
+			params.ToolChoice = claude.ForceTool(DoneTool.Name)
+		} else if cfg.ThinkingBudget > 0 {
+			// a forced tool choice does not allow thinking
+			params.Thinking = &claude.ThinkingConfig{Type: "enabled", BudgetTokens: cfg.ThinkingBudget}
+			if cfg.Client.SendsThinking() {
+				params.Sampling = cfg.Sampling.WithThinking()
+			}
+		}
+		msg, err := cfg.Client.Messages.Create(params)
 		if err != nil {
 			return "", fmt.Errorf("api call: %w", err)
 		}
@@ -1,7 +1,11 @@
 package tools
 
 import (
+	"fmt"
 	"testing"
+
+	"simpleagent/claude"
+	"simpleagent/claude/claudetest"
 )
 
 func TestSubagentTools_ContainsExpectedTools(t *testing.T) {
@@ -61,3 +65,67 @@ func TestSubagentTools_HasDoneTool(t *testing.T) {
 		t.Error("SubagentTools must include done tool")
 	}
 }
+
+func TestRunSubagent_ForcesDoneOnLastTurn(t *testing.T) {
+	// given - a subagent that keeps listing until its final turn
+	var turns []claudetest.Turn
+	for i := 0; i < subagentMaxTurns-1; i++ {
+		turns = append(turns, claudetest.Reply(claudetest.ToolUse(fmt.Sprintf("toolu_%d", i), "Ls", map[string]string{"path": "."})))
+	}
+	turns = append(turns, claudetest.Reply(claudetest.ToolUse("toolu_done", "Done", map[string]string{"summary": "found it"})))
+	srv := claudetest.NewServer(t, turns...)
+	temp := 0.3
+
+	// when
+	summary, err := RunSubagent(SubagentConfig{Client: srv.Client(), Model: "m", Sampling: claude.Sampling{Temperature: &temp}}, "look around")
+
+	// then
+	if err != nil || summary != "found it" {
+		t.Fatalf("expected Done summary, got %q (%v)", summary, err)
+	}
+	reqs := srv.Requests()
+	if reqs[0].ToolChoice != nil || reqs[0].Temperature == nil {
+		t.Errorf("expected free tool choice with sampling on first turn, got %+v", reqs[0])
+	}
+	if tc := reqs[len(reqs)-1].ToolChoice; tc == nil || tc.Name != "Done" {
+		t.Errorf("expected Done forced on last turn, got %+v", tc)
+	}
+}
+
+func TestRunSubagent_ProfileLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxTokens     int
+		budget        int
+		wantMaxTokens int
+	}{
+		{"profile limits", 8000, 2048, 8000},
+		{"defaults without thinking", 0, 0, subagentMaxTokens},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// given
+			srv := claudetest.NewServer(t, claudetest.Reply(claudetest.Text("done")))
+			temp := 0.3
+			cfg := SubagentConfig{Client: srv.Client(), Model: "m", Sampling: claude.Sampling{Temperature: &temp}, MaxTokens: tt.maxTokens, ThinkingBudget: tt.budget}
+
+			// when
+			_, err := RunSubagent(cfg, "look around")
+
+			// then
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			req := srv.Requests()[0]
+			if req.MaxTokens != tt.wantMaxTokens {
+				t.Errorf("expected max_tokens %d, got %d", tt.wantMaxTokens, req.MaxTokens)
+			}
+			if tt.budget == 0 && (req.Thinking != nil || req.Temperature == nil) {
+				t.Errorf("expected no thinking and sampling kept, got %+v", req)
+			}
+			if tt.budget > 0 && (req.Thinking == nil || req.Thinking.BudgetTokens != tt.budget || req.Temperature != nil) {
+				t.Errorf("expected thinking with budget %d and no temperature, got %+v", tt.budget, req)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"simpleagent/claude"
 )
@@ -12,6 +13,10 @@ var (
 	subagentClient       *claude.Client
 	subagentModel        string
 	subagentSystemPrompt string
+	subagentSampling     claude.Sampling
+
+	subagentMaxTokensLimit int
+	subagentThinkingBudget int
 )
 
 // SetSubagentConfig stores config for task tool to use
@@ -21,11 +26,30 @@ func SetSubagentConfig(client *claude.Client, model, systemPrompt string) {
 	subagentSystemPrompt = systemPrompt
 }
 
+// SetSubagentSystemPrompt replaces the subagent system prompt, e.g. after
+// memory files change
+func SetSubagentSystemPrompt(systemPrompt string) {
+	subagentSystemPrompt = systemPrompt
+}
+
+// SetSubagentSampling stores sampling settings for subagent requests
+func SetSubagentSampling(s claude.Sampling) {
+	subagentSampling = s
+}
+
+// SetSubagentLimits stores max_tokens and the thinking budget for subagent
+// requests
+func SetSubagentLimits(maxTokens, thinkingBudget int) {
+	subagentMaxTokensLimit, subagentThinkingBudget = maxTokens, thinkingBudget
+}
+
 // ResetSubagentConfig clears config (for testing)
 func ResetSubagentConfig() {
 	subagentClient = nil
 	subagentModel = ""
 	subagentSystemPrompt = ""
+	subagentSampling = claude.Sampling{}
+	subagentMaxTokensLimit, subagentThinkingBudget = 0, 0
 }
 
 func init() {
@@ -49,8 +73,8 @@ type taskResult struct {
 }
 
 func (r taskResult) String() string { return r.output }
-func (r taskResult) Render() {
-	fmt.Printf("\n%s %s\n", infoBadge.Render("task"), dimStyle.Render(r.description))
+func (r taskResult) Render(w io.Writer) {
+	fmt.Fprintf(w, "\n%s %s\n", infoBadge.Render("task"), dimStyle.Render(r.description))
 }
 
 func task(input json.RawMessage) Result {
@@ -73,7 +97,14 @@ func task(input json.RawMessage) Result {
 		return newResult("Task", Error("subagent not configured"))
 	}
 
-	summary, err := RunSubagent(subagentClient, subagentModel, subagentSystemPrompt, args.Prompt)
+	summary, err := RunSubagent(SubagentConfig{
+		Client:         subagentClient,
+		Model:          subagentModel,
+		SystemPrompt:   subagentSystemPrompt,
+		Sampling:       subagentSampling,
+		MaxTokens:      subagentMaxTokensLimit,
+		ThinkingBudget: subagentThinkingBudget,
+	}, args.Prompt)
 	if err != nil {
 		return newResult("Task", Error(err.Error()))
 	}
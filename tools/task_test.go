@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"strings"
 	"testing"
+
+	"simpleagent/claude/claudetest"
 )
 
 func TestTaskTool_RequiresConfig(t *testing.T) {
@@ -109,3 +111,24 @@ func TestSetSubagentConfig(t *testing.T) {
 	// then - should not panic, config stored
 	// Actual functionality tested via integration tests
 }
+
+func TestTask_UsesSubagentLimits(t *testing.T) {
+	// given
+	srv := claudetest.NewServer(t, claudetest.Reply(claudetest.Text("done")))
+	ResetSubagentConfig()
+	t.Cleanup(ResetSubagentConfig)
+	SetSubagentConfig(srv.Client(), "m", "prompt")
+	SetSubagentLimits(8000, 2048)
+
+	// when
+	result := task(json.RawMessage(`{"prompt": "look around", "description": "look"}`))
+
+	// then
+	if result.String() != "done" {
+		t.Fatalf("expected the subagent reply, got %q", result.String())
+	}
+	req := srv.Requests()[0]
+	if req.MaxTokens != 8000 || req.Thinking == nil || req.Thinking.BudgetTokens != 2048 {
+		t.Errorf("expected max_tokens 8000 and a 2048 thinking budget, got %d %+v", req.MaxTokens, req.Thinking)
+	}
+}
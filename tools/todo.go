@@ -3,6 +3,7 @@ package tools
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"simpleagent/claude"
 )
@@ -218,8 +219,13 @@ When in doubt, use this tool. Being proactive with task management demonstrates
 						Properties: map[string]claude.Property{
 							"content":     {Type: "string", Description: "Task description (imperative form)"},
 							"active_form": {Type: "string", Description: "Task description (present continuous form)"},
-							"status":      {Type: "string", Description: "pending, in_progress, or completed"},
+							"status": {
+								Type:        "string",
+								Description: "Task status",
+								Enum:        []any{"pending", "in_progress", "completed"},
+							},
 						},
+						Required: []string{"content", "status"},
 					},
 				},
 			},
@@ -232,8 +238,8 @@ type todoResult struct {
 	output string
 }
 
-func (r todoResult) String() string { return r.output }
-func (r todoResult) Render()        { RenderTodos(*configTodos) }
+func (r todoResult) String() string     { return r.output }
+func (r todoResult) Render(w io.Writer) { RenderTodos(w, *configTodos) }
 
 func executeTodo(input json.RawMessage) Result {
 	var args struct {
@@ -246,10 +252,10 @@ func executeTodo(input json.RawMessage) Result {
 	return todoResult{output: fmt.Sprintf(`{"success":true,"count":%d}`, len(args.Todos))}
 }
 
-// RenderTodos prints todos to stdout
-func RenderTodos(t []Todo) {
-	fmt.Println()
-	fmt.Println(Status("todos"))
+// RenderTodos prints todos to w
+func RenderTodos(w io.Writer, t []Todo) {
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, Status("todos"))
 	for _, todo := range t {
 		text := todo.Content
 		if todo.Status == "in_progress" && todo.ActiveForm != "" {
@@ -263,6 +269,6 @@ func RenderTodos(t []Todo) {
 		case "in_progress":
 			styled = Highlight(text)
 		}
-		fmt.Printf("  %s %s\n", Checkbox(todo.Status), styled)
+		fmt.Fprintf(w, "  %s %s\n", Checkbox(todo.Status), styled)
 	}
 }
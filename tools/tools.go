@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 
 	"simpleagent/claude"
 )
@@ -21,6 +22,7 @@ type Config struct {
 	MCPClients      *MCPClients
 	PermissionsMode string
 	RuleMatcher     func(string) (string, []string)
+	MemoryLoader    func(string) (string, []string)
 	SkillLoader     func(string) (*SkillInfo, error)
 	Subagent        *SubagentConfig
 	Todos           *[]Todo // pointer so tool can mutate
@@ -31,6 +33,11 @@ type SubagentConfig struct {
 	Client       *claude.Client
 	Model        string
 	SystemPrompt string
+	Sampling     claude.Sampling
+	// MaxTokens and ThinkingBudget come from the subagent profile
+	// (0 = subagentMaxTokens, no thinking)
+	MaxTokens      int
+	ThinkingBudget int
 }
 
 // Init configures the tools package (full replacement, caller provides complete config)
@@ -41,19 +48,22 @@ func Init(cfg Config) {
 		permissionsMode = "prompt"
 	}
 	RuleMatcher = cfg.RuleMatcher
+	MemoryLoader = cfg.MemoryLoader
 	SkillLoader = cfg.SkillLoader
 	configTodos = cfg.Todos
 	if cfg.Subagent != nil {
 		subagentClient = cfg.Subagent.Client
 		subagentModel = cfg.Subagent.Model
 		subagentSystemPrompt = cfg.Subagent.SystemPrompt
+		subagentSampling = cfg.Subagent.Sampling
+		subagentMaxTokensLimit, subagentThinkingBudget = cfg.Subagent.MaxTokens, cfg.Subagent.ThinkingBudget
 	}
 }
 
 // Result is returned by Execute, with tool-specific rendering
 type Result interface {
 	String() string
-	Render()
+	Render(w io.Writer)
 }
 
 type toolResult struct {
@@ -61,8 +71,8 @@ type toolResult struct {
 	output string
 }
 
-func (r toolResult) String() string { return r.output }
-func (r toolResult) Render()        { fmt.Printf("\n%s\n", Tool(r.name)) }
+func (r toolResult) String() string     { return r.output }
+func (r toolResult) Render(w io.Writer) { fmt.Fprintf(w, "\n%s\n", Tool(r.name)) }
 
 // newResult creates a standard tool result
 func newResult(name, output string) Result {
@@ -72,10 +82,11 @@ func newResult(name, output string) Result {
 // rawResult prints output directly without tool badge (for bash, grep, etc.)
 type rawResult struct{ output string }
 
-func (r rawResult) String() string { return r.output }
-func (r rawResult) Render()        { fmt.Print(r.output) }
+func (r rawResult) String() string     { return r.output }
+func (r rawResult) Render(w io.Writer) { fmt.Fprint(w, r.output) }
 
 var registry = make(map[string]func(json.RawMessage) Result)
+var schemas = make(map[string]claude.InputSchema) // validated by Execute
 var allTools []claude.Tool
 var readOnlyTools = map[string]bool{
 	"ReadFile":        true,
@@ -127,17 +138,26 @@ func ReadOnly() []claude.Tool {
 	return tools
 }
 
-// Execute runs a tool by name (local first, then MCP fallback)
+// Execute validates input against the tool schema, then runs the tool by name
+// (local first, then MCP fallback)
 func Execute(name string, input json.RawMessage) Result {
 	// Check skill tool restrictions (applies to both local and MCP)
 	if allowedTools != nil && !allowedTools[name] {
 		return toolResult{name: name, output: "error: tool '" + name + "' not allowed in current skill"}
 	}
 	if fn, ok := registry[name]; ok {
+		if err := ValidateInput(name, schemas[name], input); err != nil {
+			return toolResult{name: name, output: err.Error()}
+		}
 		return fn(input)
 	}
 	// MCP fallback
 	if globalMCPClients != nil {
+		if schema, found := globalMCPClients.Schema(name); found {
+			if err := ValidateInput(name, schema, input); err != nil {
+				return toolResult{name: name, output: err.Error()}
+			}
+		}
 		if result, found := globalMCPClients.Execute(context.Background(), name, input); found {
 			return newResult(name, result)
 		}
@@ -148,4 +168,5 @@ func Execute(name string, input json.RawMessage) Result {
 func register(t claude.Tool, fn func(json.RawMessage) Result) {
 	allTools = append(allTools, t)
 	registry[t.Name] = fn
+	schemas[t.Name] = t.InputSchema
 }
@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"simpleagent/claude"
+)
+
+// ValidationError lists every schema violation found in a tool input
+type ValidationError struct {
+	Tool       string
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("error: invalid input for tool '%s' (%d problem%s):\n", e.Tool, len(e.Violations), pluralS(len(e.Violations))))
+	for _, v := range e.Violations {
+		b.WriteString("- " + v + "\n")
+	}
+	b.WriteString("Fix the input to match the tool's input_schema and call it again.")
+	return b.String()
+}
+
+func pluralS(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// ValidateInput checks input against a tool schema, returns nil if valid
+func ValidateInput(tool string, schema claude.InputSchema, input json.RawMessage) *ValidationError {
+	trimmed := bytes.TrimSpace(input)
+	if len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		trimmed = []byte("{}")
+	}
+
+	var value any
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	dec.UseNumber()
+	if err := dec.Decode(&value); err != nil {
+		return &ValidationError{Tool: tool, Violations: []string{fmt.Sprintf("input is not valid JSON: %v", err)}}
+	}
+
+	v := validator{root: schema}
+	v.check("input", schema, value)
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Tool: tool, Violations: v.violations}
+}
+
+type validator struct {
+	root       claude.Property
+	violations []string
+	depth      int
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.violations = append(v.violations, path+": "+fmt.Sprintf(format, args...))
+}
+
+// resolve follows a local $ref ("#/$defs/name" or "#/definitions/name");
+// refs that lead back to themselves are unresolvable
+func (v *validator) resolve(s claude.Property) (claude.Property, bool) {
+	seen := make(map[string]bool)
+	for s.Ref != "" {
+		if seen[s.Ref] {
+			return s, false
+		}
+		seen[s.Ref] = true
+		name, ok := strings.CutPrefix(s.Ref, "#/$defs/")
+		if !ok {
+			name, ok = strings.CutPrefix(s.Ref, "#/definitions/")
+		}
+		if s.Ref == "#" {
+			s = v.root
+			continue
+		}
+		target, found := v.root.Defs[name]
+		if !ok || !found {
+			return s, false // unresolvable refs are not enforced
+		}
+		s = target
+	}
+	return s, true
+}
+
+func (v *validator) check(path string, s claude.Property, value any) {
+	v.depth++
+	defer func() { v.depth-- }()
+	if v.depth > 64 {
+		return // recursive schema guard
+	}
+
+	s, ok := v.resolve(s)
+	if !ok {
+		return
+	}
+	if s.Boolean != nil {
+		if !*s.Boolean {
+			v.fail(path, "no value is allowed here")
+		}
+		return
+	}
+
+	if types := s.TypeNames(); len(types) > 0 && !matchesAnyType(types, value) {
+		v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return
+	}
+
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		v.fail(path, "must be one of %s (got %s)", formatEnum(s.Enum), compactJSON(value))
+	}
+	if s.Const != nil && !jsonEqual(s.Const, value) {
+		v.fail(path, "must be %s (got %s)", compactJSON(s.Const), compactJSON(value))
+	}
+
+	switch val := value.(type) {
+	case map[string]any:
+		v.checkObject(path, s, val)
+	case []any:
+		v.checkArray(path, s, val)
+	case string:
+		v.checkString(path, s, val)
+	case json.Number:
+		v.checkNumber(path, s, val)
+	}
+
+	v.checkComposition(path, s, value)
+}
+
+func (v *validator) checkObject(path string, s claude.Property, obj map[string]any) {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			v.fail(joinPath(path, name), "required field is missing")
+		}
+	}
+	extra, allowed := s.AdditionalSchema()
+	for _, name := range slices.Sorted(maps.Keys(obj)) {
+		val := obj[name]
+		if prop, ok := s.Properties[name]; ok {
+			v.check(joinPath(path, name), prop, val)
+			continue
+		}
+		if !allowed {
+			v.fail(joinPath(path, name), "unknown field")
+		} else if extra != nil {
+			v.check(joinPath(path, name), *extra, val)
+		}
+	}
+}
+
+func (v *validator) checkArray(path string, s claude.Property, arr []any) {
+	if s.MinItems != nil && len(arr) < *s.MinItems {
+		v.fail(path, "must have at least %d item%s (got %d)", *s.MinItems, pluralS(*s.MinItems), len(arr))
+	}
+	if s.MaxItems != nil && len(arr) > *s.MaxItems {
+		v.fail(path, "must have at most %d item%s (got %d)", *s.MaxItems, pluralS(*s.MaxItems), len(arr))
+	}
+	if s.UniqueItems {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if jsonEqual(arr[i], arr[j]) {
+					v.fail(path, "items %d and %d are duplicates", i, j)
+				}
+			}
+		}
+	}
+	for i, item := range arr {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case s.TupleItems != nil:
+			if i < len(s.TupleItems) {
+				v.check(itemPath, s.TupleItems[i], item)
+			}
+		case s.Items != nil:
+			v.check(itemPath, *s.Items, item)
+		}
+	}
+}
+
+func (v *validator) checkString(path string, s claude.Property, str string) {
+	n := len([]rune(str))
+	if s.MinLength != nil && n < *s.MinLength {
+		v.fail(path, "must be at least %d characters (got %d)", *s.MinLength, n)
+	}
+	if s.MaxLength != nil && n > *s.MaxLength {
+		v.fail(path, "must be at most %d characters (got %d)", *s.MaxLength, n)
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		switch {
+		case err != nil:
+			v.fail(path, "schema pattern %q is not valid RE2: %v", s.Pattern, err)
+		case !re.MatchString(str):
+			v.fail(path, "must match pattern %q", s.Pattern)
+		}
+	}
+}
+
+func (v *validator) checkNumber(path string, s claude.Property, num json.Number) {
+	f, err := num.Float64()
+	if err != nil {
+		return
+	}
+	if s.Minimum != nil && f < *s.Minimum {
+		v.fail(path, "must be >= %v (got %v)", *s.Minimum, num)
+	}
+	if s.Maximum != nil && f > *s.Maximum {
+		v.fail(path, "must be <= %v (got %v)", *s.Maximum, num)
+	}
+	if s.ExclusiveMinimum != nil && f <= *s.ExclusiveMinimum {
+		v.fail(path, "must be > %v (got %v)", *s.ExclusiveMinimum, num)
+	}
+	if s.ExclusiveMaximum != nil && f >= *s.ExclusiveMaximum {
+		v.fail(path, "must be < %v (got %v)", *s.ExclusiveMaximum, num)
+	}
+	if s.MultipleOf != nil && *s.MultipleOf != 0 {
+		if q := f / *s.MultipleOf; q != math.Trunc(q) {
+			v.fail(path, "must be a multiple of %v (got %v)", *s.MultipleOf, num)
+		}
+	}
+}
+
+func (v *validator) checkComposition(path string, s claude.Property, value any) {
+	for _, sub := range s.AllOf {
+		v.check(path, sub, value)
+	}
+	if len(s.AnyOf) > 0 && v.countMatches(path, s.AnyOf, value) == 0 {
+		v.fail(path, "does not match any of the allowed schemas")
+	}
+	if len(s.OneOf) > 0 {
+		if n := v.countMatches(path, s.OneOf, value); n != 1 {
+			v.fail(path, "must match exactly one of the allowed schemas (matched %d)", n)
+		}
+	}
+	if s.Not != nil {
+		sub := validator{root: v.root, depth: v.depth}
+		sub.check(path, *s.Not, value)
+		if len(sub.violations) == 0 {
+			v.fail(path, "must not match the disallowed schema")
+		}
+	}
+}
+
+// countMatches returns how many alternatives accept value
+func (v *validator) countMatches(path string, alternatives []claude.Property, value any) int {
+	n := 0
+	for _, alt := range alternatives {
+		sub := validator{root: v.root, depth: v.depth}
+		sub.check(path, alt, value)
+		if len(sub.violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func joinPath(path, field string) string {
+	if path == "input" {
+		return field
+	}
+	return path + "." + field
+}
+
+func matchesAnyType(types []string, value any) bool {
+	for _, t := range types {
+		if matchesType(t, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(t string, value any) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "null":
+		return value == nil
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		num, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := num.Float64()
+		return err == nil && f == math.Trunc(f)
+	}
+	return true // unknown type names are not enforced
+}
+
+func jsonTypeName(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case json.Number:
+		if matchesType("integer", val) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// jsonEqual compares values by their canonical JSON encoding
+func jsonEqual(a, b any) bool {
+	return compactJSON(normalize(a)) == compactJSON(normalize(b))
+}
+
+// normalize turns json.Number into float64 so 1 and 1.0 compare equal
+func normalize(value any) any {
+	switch val := value.(type) {
+	case json.Number:
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, x := range val {
+			out[k] = normalize(x)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, x := range val {
+			out[i] = normalize(x)
+		}
+		return out
+	}
+	if rv := reflect.ValueOf(value); rv.IsValid() && rv.CanInt() {
+		return float64(rv.Int())
+	}
+	return value
+}
+
+func inEnum(enum []any, value any) bool {
+	for _, e := range enum {
+		if jsonEqual(e, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []any) string {
+	parts := make([]string, len(enum))
+	for i, e := range enum {
+		parts[i] = compactJSON(e)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func compactJSON(value any) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"simpleagent/claude"
+)
+
+func TestValidateInput_Valid(t *testing.T) {
+	// given
+	input := json.RawMessage(`{"todos":[{"content":"a","active_form":"doing a","status":"pending"}]}`)
+
+	// when
+	err := ValidateInput("TodoWrite", schemas["TodoWrite"], input)
+
+	// then
+	if err != nil {
+		t.Errorf("expected valid, got: %v", err)
+	}
+}
+
+func TestValidateInput_ListsEveryViolation(t *testing.T) {
+	// given - wrong status enum and missing content
+	input := json.RawMessage(`{"todos":[{"active_form":"doing a","status":"done"}]}`)
+
+	// when
+	err := ValidateInput("TodoWrite", schemas["TodoWrite"], input)
+
+	// then
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	if len(err.Violations) != 2 {
+		t.Fatalf("expected 2 violations, got %d: %v", len(err.Violations), err.Violations)
+	}
+	msg := err.Error()
+	for _, want := range []string{"todos[0].content: required field is missing", `todos[0].status: must be one of "pending", "in_progress", "completed" (got "done")`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in:\n%s", want, msg)
+		}
+	}
+}
+
+func TestValidateInput_TodoWithoutActiveForm(t *testing.T) {
+	// given - active_form is optional at runtime
+	input := json.RawMessage(`{"todos":[{"content":"a","status":"pending"}]}`)
+
+	// when
+	err := ValidateInput("TodoWrite", schemas["TodoWrite"], input)
+
+	// then
+	if err != nil {
+		t.Errorf("expected valid, got: %v", err)
+	}
+}
+
+func TestValidateInput_WrongType(t *testing.T) {
+	// given
+	input := json.RawMessage(`{"path":"a.go","start_line":"3"}`)
+
+	// when
+	err := ValidateInput("ReadFile", schemas["ReadFile"], input)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "start_line: expected integer, got string") {
+		t.Errorf("expected type error, got: %v", err)
+	}
+}
+
+func TestValidateInput_InvalidJSON(t *testing.T) {
+	// given
+	input := json.RawMessage(`{invalid}`)
+
+	// when
+	err := ValidateInput("Task", schemas["Task"], input)
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("expected JSON error, got: %v", err)
+	}
+}
+
+func TestValidateInput_Composition(t *testing.T) {
+	// given - anyOf with $ref and additionalProperties false
+	schema := claude.InputSchema{
+		Type: "object",
+		Properties: map[string]claude.Property{
+			"id": {AnyOf: []claude.Property{{Ref: "#/$defs/num"}, {Type: "string", MinLength: claude.Int(3)}}},
+		},
+		AdditionalProperties: false,
+		Defs:                 map[string]claude.Property{"num": {Type: "integer", Minimum: claude.Float(1)}},
+	}
+
+	// when
+	ok := ValidateInput("x", schema, json.RawMessage(`{"id":5}`))
+	bad := ValidateInput("x", schema, json.RawMessage(`{"id":"ab","extra":1}`))
+
+	// then
+	if ok != nil {
+		t.Errorf("expected valid, got: %v", ok)
+	}
+	if bad == nil || len(bad.Violations) != 2 {
+		t.Fatalf("expected 2 violations, got: %v", bad)
+	}
+}
+
+func TestValidateInput_TupleItemsAndFalseSchema(t *testing.T) {
+	// given - a schema as an MCP server would send it
+	var schema claude.InputSchema
+	json.Unmarshal([]byte(`{"type":"object","properties":{"point":{"type":"array","items":[{"type":"number"},{"type":"string"}]},"legacy":false}}`), &schema)
+
+	// when
+	ok := ValidateInput("x", schema, json.RawMessage(`{"point":[1,"a",true]}`))
+	bad := ValidateInput("x", schema, json.RawMessage(`{"point":["a",1],"legacy":1}`))
+
+	// then
+	if ok != nil {
+		t.Errorf("expected valid, got: %v", ok)
+	}
+	want := "legacy: no value is allowed here,point[0]: expected number, got string,point[1]: expected string, got integer"
+	if bad == nil || strings.Join(bad.Violations, ",") != want {
+		t.Errorf("expected %q, got: %v", want, bad)
+	}
+}
+
+func TestValidateInput_InvalidPattern(t *testing.T) {
+	// given - a lookahead, which RE2 does not support
+	schema := claude.InputSchema{
+		Type:       "object",
+		Properties: map[string]claude.Property{"name": {Type: "string", Pattern: "^(?!tmp)"}},
+	}
+
+	// when
+	err := ValidateInput("x", schema, json.RawMessage(`{"name":"tmpfile"}`))
+
+	// then
+	if err == nil || !strings.Contains(err.Error(), `name: schema pattern "^(?!tmp)" is not valid RE2`) {
+		t.Errorf("expected the bad pattern reported, got: %v", err)
+	}
+}
+
+func TestValidateInput_RefCycles(t *testing.T) {
+	// given - refs that only lead back to themselves
+	schemas := []claude.InputSchema{
+		{Ref: "#"},
+		{
+			Type:       "object",
+			Properties: map[string]claude.Property{"x": {Ref: "#/$defs/a"}},
+			Defs:       map[string]claude.Property{"a": {Ref: "#/$defs/b"}, "b": {Ref: "#/$defs/a"}},
+		},
+	}
+
+	for _, schema := range schemas {
+		// when
+		err := ValidateInput("x", schema, json.RawMessage(`{"x":1}`))
+
+		// then - unresolvable, so not enforced
+		if err != nil {
+			t.Errorf("expected cyclic refs ignored, got: %v", err)
+		}
+	}
+}
+
+func TestValidateInput_ViolationOrder(t *testing.T) {
+	// given
+	schema := claude.InputSchema{Type: "object", AdditionalProperties: false}
+
+	// when
+	err := ValidateInput("x", schema, json.RawMessage(`{"d":1,"b":1,"c":1,"a":1}`))
+
+	// then
+	want := "a: unknown field,b: unknown field,c: unknown field,d: unknown field"
+	if err == nil || strings.Join(err.Violations, ",") != want {
+		t.Errorf("expected sorted violations, got: %v", err)
+	}
+}
+
+func TestExecute_RejectsInvalidInput(t *testing.T) {
+	// given - Glob type outside enum
+	input := json.RawMessage(`{"pattern":"*.go","type":"folder"}`)
+
+	// when
+	result := Execute("Glob", input)
+
+	// then
+	if !strings.Contains(result.String(), "invalid input for tool 'Glob'") {
+		t.Errorf("expected validation error, got: %s", result.String())
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+
+	"simpleagent/claude"
+)
+
+// DefaultMaxContinuations bounds automatic recovery from max_tokens per turn
+const DefaultMaxContinuations = 3
+
+// trimTruncated drops blocks a max_tokens cut left unusable: tool_use blocks
+// whose input is not a complete JSON object, and a trailing thinking block
+// that never received its signature. Returns the kept blocks and how many
+// tool calls were discarded.
+func trimTruncated(blocks []claude.ContentBlock) ([]claude.ContentBlock, int) {
+	var kept []claude.ContentBlock
+	dropped := 0
+	for i, b := range blocks {
+		switch {
+		case b.Type == "tool_use" && !completeInput(b.Input):
+			dropped++
+			continue
+		case b.Type == "thinking" && b.Signature == "" && i == len(blocks)-1:
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return kept, dropped
+}
+
+// completeInput reports whether tool input parsed as a JSON object
+func completeInput(input json.RawMessage) bool {
+	var obj map[string]any
+	return len(input) > 0 && json.Unmarshal(input, &obj) == nil
+}
+
+func hasToolUse(blocks []claude.ContentBlock) bool {
+	for _, b := range blocks {
+		if b.Type == "tool_use" {
+			return true
+		}
+	}
+	return false
+}
+
+// raisedMaxTokens doubles the turn's output limit, staying inside the
+// model's output limit and the context window room left by the request.
+// It returns the current limit when there is no room to raise it.
+func (a *Agent) raisedMaxTokens() int {
+	current := max(a.maxTokens, a.turnMaxTokens)
+	next := current * 2
+	if a.maxOutputTokens > 0 {
+		next = min(next, a.maxOutputTokens)
+	}
+	if a.contextWindow > 0 && a.contextTokens > 0 {
+		next = min(next, a.contextWindow-a.contextTokens)
+	}
+	return max(next, current)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"simpleagent/tools"
+)
+
+// fileStamp identifies one version of a watched file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// promptWatcher notices edits to the files the system prompt is built from:
+// memory files, rules and skills. It is polled between turns, the earliest
+// a rebuilt prompt can be used, so it needs no background notifications.
+type promptWatcher struct {
+	stamps map[string]fileStamp // absolute path -> last seen version
+}
+
+func newPromptWatcher() *promptWatcher {
+	w := &promptWatcher{}
+	w.rescan()
+	return w
+}
+
+// rescan takes the current versions as seen, e.g. after a reload
+func (w *promptWatcher) rescan() {
+	w.stamps = scanPromptFiles()
+}
+
+// promptFiles lists the loaded memory files, the memory files that would be
+// loaded if created, configured ones included, and the files in the rule
+// and skill dirs
+func promptFiles() []string {
+	memory := globalMemory
+	if memory == nil {
+		memory = newMemoryLoader()
+	}
+	paths := append(slices.Clone(memory.order), memory.discover()...)
+	for _, name := range ruleDirNames {
+		if dir := findDir(name); dir != "" {
+			entries, _ := os.ReadDir(dir)
+			for _, entry := range entries {
+				if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+					paths = append(paths, filepath.Join(dir, entry.Name()))
+				}
+			}
+		}
+	}
+	for _, dir := range skillDirs() {
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			if entry.IsDir() {
+				paths = append(paths, filepath.Join(dir, entry.Name(), "SKILL.md"))
+			}
+		}
+	}
+	return paths
+}
+
+// scanPromptFiles stamps the prompt files that exist
+func scanPromptFiles() map[string]fileStamp {
+	stamps := make(map[string]fileStamp)
+	for _, path := range promptFiles() {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(abs); err == nil && !info.IsDir() {
+			stamps[abs] = fileStamp{info.ModTime(), info.Size()}
+		}
+	}
+	return stamps
+}
+
+// fileChange is a watched file that was added, edited or removed
+type fileChange struct {
+	path string // absolute
+	note string // "new", "removed" or "" for edited
+}
+
+// changes returns the files added, edited or removed since the last scan,
+// sorted by path. Subdirectory memory loaded by a read since is already in
+// context, so it is watched from now on but not a change.
+func (w *promptWatcher) changes() []fileChange {
+	stamps := scanPromptFiles()
+	var changed []fileChange
+	for path, stamp := range stamps {
+		if old, ok := w.stamps[path]; !ok {
+			if !memoryLoaded(path) {
+				changed = append(changed, fileChange{path, "new"})
+			}
+		} else if !old.modTime.Equal(stamp.modTime) || old.size != stamp.size {
+			changed = append(changed, fileChange{path, ""})
+		}
+	}
+	for path := range w.stamps {
+		if _, ok := stamps[path]; !ok {
+			changed = append(changed, fileChange{path, "removed"})
+		}
+	}
+	w.stamps = stamps
+	slices.SortFunc(changed, func(a, b fileChange) int { return strings.Compare(a.path, b.path) })
+	return changed
+}
+
+// reloadChanged rebuilds the system prompt, rules and skills when any of
+// their files changed since the last check
+func (a *Agent) reloadChanged() error {
+	if a.watcher == nil {
+		return nil
+	}
+	changed := a.watcher.changes()
+	if len(changed) == 0 {
+		return nil
+	}
+	var names, stale []string
+	for _, c := range changed {
+		name := displayPath(c.path)
+		if c.note != "" {
+			name += " (" + c.note + ")"
+		}
+		names = append(names, name)
+		stale = append(stale, c.path)
+	}
+	fmt.Println(tools.Status("changed") + " " + tools.Dim(strings.Join(names, ", ")))
+	return a.reloadSystemPrompt(stale...)
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAgent_ReloadChanged(t *testing.T) {
+	// given
+	t.Setenv("HOME", t.TempDir()) // no personal skills
+	agent, root := useTempMemory(t, "")
+	agent.watcher = newPromptWatcher()
+	writeConfigFile(t, filepath.Join(root, "pkg", "CLAUDE.md"), "pkg memory")
+	GetNestedMemory("pkg/main.go")
+
+	// when - nothing but a lazy memory load happened
+	err := agent.reloadChanged()
+
+	// then
+	if err != nil || len(agent.watcher.changes()) != 0 {
+		t.Errorf("expected no changes, got %v", err)
+	}
+
+	// when - a rule and a skill are added and CLAUDE.md edited
+	writeConfigFile(t, filepath.Join(root, rulesDirName, "go.md"), "Use gofmt.")
+	writeConfigFile(t, filepath.Join(root, ".claude", "skills", "deploy", "SKILL.md"), "---\nname: deploy\ndescription: Ship it\n---\nRun make deploy.")
+	writeConfigFile(t, filepath.Join(root, "CLAUDE.md"), "edited memory")
+	out := captureStdout(t, func() { err = agent.reloadChanged() })
+
+	// then
+	want := ".claude/skills/deploy/SKILL.md (new), .rules/go.md (new), CLAUDE.md"
+	if err != nil || !strings.Contains(out, want) {
+		t.Errorf("expected a reload notice naming %q, got %v:\n%s", want, err, out)
+	}
+	for _, w := range []string{"Use gofmt.", "deploy: Ship it", "edited memory"} {
+		if !strings.Contains(agent.systemPrompt, w) {
+			t.Errorf("expected the system prompt to contain %q", w)
+		}
+	}
+	if _, ok := skillPaths["deploy"]; !ok {
+		t.Error("expected the new skill registered")
+	}
+
+	// when - the rule is removed
+	os.Remove(filepath.Join(root, rulesDirName, "go.md"))
+	changed := agent.watcher.changes()
+
+	// then
+	if len(changed) != 1 || changed[0].path != filepath.Join(root, rulesDirName, "go.md") || changed[0].note != "removed" {
+		t.Errorf("expected the rule removed, got %+v", changed)
+	}
+}
+
+func TestAgent_ReloadChanged_ConfiguredMemoryFile(t *testing.T) {
+	// given - a configured memory file that does not exist yet
+	root := useTempRepo(t, "", map[string]string{
+		filepath.Join(configDirName, configFileName): `{"memory_files": ["docs/notes.md"]}`,
+	})
+	old := userConfigDir
+	userConfigDir = t.TempDir()
+	t.Cleanup(func() { userConfigDir, globalMemory = old, nil })
+	agent := &Agent{}
+	captureStdout(t, func() { agent.reloadSystemPrompt() })
+	agent.watcher = newPromptWatcher()
+
+	// when
+	writeConfigFile(t, filepath.Join(root, "docs", "notes.md"), "configured notes")
+	var err error
+	out := captureStdout(t, func() { err = agent.reloadChanged() })
+
+	// then
+	if err != nil || !strings.Contains(out, "docs/notes.md (new)") || !strings.Contains(agent.systemPrompt, "configured notes") {
+		t.Errorf("expected the configured file picked up, got %v:\n%s", err, out)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Workspace records where a session was started
+type Workspace struct {
+	Cwd     string `json:"cwd,omitempty"`
+	GitRoot string `json:"git_root,omitempty"`
+	Branch  string `json:"branch,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// Project identifies the workspace for scoping: the git root, else the cwd
+func (w Workspace) Project() string {
+	if w.GitRoot != "" {
+		return w.GitRoot
+	}
+	return w.Cwd
+}
+
+// currentWorkspace describes the process working directory
+func currentWorkspace() Workspace {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return Workspace{}
+	}
+	return detectWorkspace(cwd)
+}
+
+// detectWorkspace reads git root, branch and commit for dir; outside a
+// repository only Cwd is set
+func detectWorkspace(dir string) Workspace {
+	w := Workspace{Cwd: dir}
+	w.GitRoot = gitOutput(dir, "rev-parse", "--show-toplevel")
+	if w.GitRoot == "" {
+		return w
+	}
+	w.Branch = gitOutput(dir, "rev-parse", "--abbrev-ref", "HEAD")
+	w.Commit = gitOutput(dir, "rev-parse", "HEAD") // empty before the first commit
+	return w
+}
+
+// gitOutput runs a git command in dir, returning "" on any failure
+func gitOutput(dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectWorkspace(t *testing.T) {
+	// given - this package's directory sits inside the repository
+	wd, _ := os.Getwd()
+	plain := t.TempDir()
+
+	// when
+	repo := detectWorkspace(wd)
+	outside := detectWorkspace(plain)
+
+	// then
+	if repo.GitRoot == "" || !filepath.IsAbs(repo.GitRoot) || len(repo.Commit) != 40 {
+		t.Errorf("expected git root and commit, got %+v", repo)
+	}
+	if outside != (Workspace{Cwd: plain}) || outside.Project() != plain {
+		t.Errorf("expected only cwd outside a repository, got %+v", outside)
+	}
+	if repo.Project() != repo.GitRoot {
+		t.Errorf("expected project to be the git root, got %q", repo.Project())
+	}
+}